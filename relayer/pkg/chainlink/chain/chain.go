@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"math/rand"
 	"strconv"
 
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
 	"github.com/pelletier/go-toml/v2"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/chains"
@@ -18,7 +20,7 @@ import (
 	"github.com/smartcontractkit/chainlink-common/pkg/utils"
 
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/config"
-	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/db"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/erc20"
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm"
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
 )
@@ -63,6 +65,8 @@ type chain struct {
 	id   string
 	cfg  *config.TOMLConfig
 	lggr logger.Logger
+	ks   loop.Keystore
+	pool *nodePool
 	txm  txm.StarkTXM
 }
 
@@ -83,6 +87,19 @@ func newChain(id string, cfg *config.TOMLConfig, loopKs loop.Keystore, lggr logg
 		id:   id,
 		cfg:  cfg,
 		lggr: logger.Named(lggr, "Chain"),
+		ks:   loopKs,
+	}
+
+	nodes, err := cfg.ListNodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+	ch.pool, err = newNodePool(lggr, nodes, cfg.NodePollInterval(), cfg.NodeSyncThreshold(), cfg.RequestTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node pool: %w", err)
+	}
+	if client, clientErr := ch.pool.Client(); clientErr == nil {
+		client.SetDefaultBlock(starknetrpc.BlockID{Tag: cfg.DefaultBlockTag()})
 	}
 
 	getClient := func() (*starknet.Client, error) {
@@ -93,8 +110,7 @@ func newChain(id string, cfg *config.TOMLConfig, loopKs loop.Keystore, lggr logg
 		return ch.getFeederClient(), nil
 	}
 
-	var err error
-	ch.txm, err = txm.New(lggr, loopKs, cfg, getClient, getFeederClient)
+	ch.txm, err = txm.New(lggr, id, loopKs, cfg, getClient, getFeederClient)
 	if err != nil {
 		return nil, err
 	}
@@ -126,49 +142,23 @@ func (c *chain) getFeederClient() *starknet.FeederClient {
 	return starknet.NewFeederClient(c.cfg.FeederURL.String())
 }
 
-// getClient returns a client, randomly selecting one from available and valid nodes
+// getClient returns the client of the node pool, which sends requests to the healthiest node
 func (c *chain) getClient() (*starknet.Client, error) {
-	var node db.Node
-	var client *starknet.Client
-	nodes, err := c.cfg.ListNodes()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get nodes: %w", err)
-	}
-	if len(nodes) == 0 {
-		return nil, errors.New("no nodes available")
-	}
-	// #nosec
-	index := rand.Perm(len(nodes)) // list of node indexes to try
-	timeout := c.cfg.RequestTimeout()
-	for _, i := range index {
-		node = nodes[i]
-		// create client and check
-		client, err = starknet.NewClient(node.ChainID, node.URL, node.APIKey, c.lggr, &timeout)
-		// if error, try another node
-		if err != nil {
-			c.lggr.Warnw("failed to create node", "name", node.Name, "starknet-url", node.URL, "err", err.Error())
-			continue
-		}
-		// if all checks passed, mark found and break loop
-		break
-	}
-	// if no valid node found, exit with error
-	if client == nil {
-		return nil, errors.New("no node valid nodes available")
-	}
-	c.lggr.Debugw("Created client", "name", node.Name, "starknet-url", node.URL)
-	return client, nil
+	return c.pool.Client()
 }
 
 func (c *chain) Start(ctx context.Context) error {
 	return c.StartOnce("Chain", func() error {
+		if err := c.pool.Start(ctx); err != nil {
+			return err
+		}
 		return c.txm.Start(ctx)
 	})
 }
 
 func (c *chain) Close() error {
 	return c.StopOnce("Chain", func() error {
-		return c.txm.Close()
+		return errors.Join(c.txm.Close(), c.pool.Close())
 	})
 }
 
@@ -178,6 +168,7 @@ func (c *chain) Ready() error {
 
 func (c *chain) HealthReport() map[string]error {
 	report := map[string]error{c.Name(): c.Healthy()}
+	services.CopyHealth(report, c.pool.HealthReport())
 	services.CopyHealth(report, c.txm.HealthReport())
 	return report
 }
@@ -226,15 +217,114 @@ func (c *chain) ListNodeStatuses(ctx context.Context, pageSize int32, pageToken
 	return chains.ListNodeStatuses(int(pageSize), pageToken, c.listNodeStatuses)
 }
 
+// Transact transfers amount of the fee token of the account contract at address from (STRK, or ETH for Cairo 0
+// accounts) to the address to. If balanceCheck is set, the balance has to cover amount and the max fee of the
+// transfer. It returns once the transfer is enqueued, its status can be followed through the TxManager.
 func (c *chain) Transact(ctx context.Context, from, to string, amount *big.Int, balanceCheck bool) error {
-	return errors.ErrUnsupported
+	fromAddress, err := starknetutils.HexToFelt(from)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	toAddress, err := starknetutils.HexToFelt(to)
+	if err != nil {
+		return fmt.Errorf("invalid to address %q: %w", to, err)
+	}
+	if amount == nil || amount.Sign() < 0 || amount.BitLen() > 256 {
+		return fmt.Errorf("invalid amount %v: must be a u256", amount)
+	}
+	feeToken := c.txm.FeeToken(fromAddress)
+	token, err := starknetutils.HexToFelt(feeToken)
+	if err != nil {
+		return fmt.Errorf("invalid fee token %q: %w", feeToken, err)
+	}
+
+	reader, err := c.getClient()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	publicKey, err := c.accountPublicKey(ctx, reader, fromAddress)
+	if err != nil {
+		return err
+	}
+
+	// a u256 amount is passed as 2 felts (lower 128 bits | higher 128 bits)
+	low := new(big.Int).And(amount, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1)))
+	high := new(big.Int).Rsh(amount, 128)
+	call := starknetrpc.FunctionCall{
+		ContractAddress:    token,
+		EntryPointSelector: starknetutils.GetSelectorFromNameFelt("transfer"),
+		Calldata:           []*felt.Felt{toAddress, starknetutils.BigIntToFelt(low), starknetutils.BigIntToFelt(high)},
+	}
+
+	if balanceCheck {
+		// the transferred token is the fee token of the account, so the balance has to cover the fee of the transfer too
+		maxFee, err := c.txm.EstimateMaxFee(ctx, fromAddress, publicKey, call)
+		if err != nil {
+			return fmt.Errorf("failed to estimate fee of transfer: %w", err)
+		}
+		erc20Client, err := erc20.NewClient(reader, c.lggr, token)
+		if err != nil {
+			return fmt.Errorf("failed to create erc20 client: %w", err)
+		}
+		balance, err := erc20Client.BalanceOf(ctx, fromAddress)
+		if err != nil {
+			return fmt.Errorf("failed to get balance of %s: %w", from, err)
+		}
+		if required := new(big.Int).Add(amount, maxFee); balance.Cmp(required) < 0 {
+			return fmt.Errorf("balance %s of %s is too low to transfer %s with a max fee of %s", balance, from, amount, maxFee)
+		}
+	}
+
+	id, err := c.txm.Enqueue(ctx, fromAddress, publicKey, call, "", txm.TxOpts{Priority: txm.PriorityHigh, Caller: "transfer"}) // admin transfers are not delayed by transmits
+	if err != nil {
+		return fmt.Errorf("failed to enqueue transfer: %w", err)
+	}
+	c.lggr.Infow("Enqueued transfer", "txID", id, "from", from, "to", to, "amount", amount, "token", token)
+	return nil
+}
+
+// accountPublicKey returns the public key of the account contract at accountAddress, which must be held by the keystore
+func (c *chain) accountPublicKey(ctx context.Context, reader starknet.Reader, accountAddress *felt.Felt) (*felt.Felt, error) {
+	var publicKey *felt.Felt
+	var errs error
+	// Cairo 1 accounts expose get_public_key, older Cairo 0 accounts getPublicKey
+	for _, selector := range []string{"get_public_key", "getPublicKey"} {
+		res, err := reader.CallContract(ctx, starknet.CallOps{
+			ContractAddress: accountAddress,
+			Selector:        starknetutils.GetSelectorFromNameFelt(selector),
+		})
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", selector, err))
+			continue
+		}
+		if len(res) != 1 {
+			errs = errors.Join(errs, fmt.Errorf("%s: unexpected result length %d", selector, len(res)))
+			continue
+		}
+		publicKey = res[0]
+		break
+	}
+	if publicKey == nil {
+		return nil, fmt.Errorf("failed to get public key of account %s: %w", accountAddress, errs)
+	}
+
+	accounts, err := c.ks.Accounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keystore accounts: %w", err)
+	}
+	for _, account := range accounts {
+		key, err := starknetutils.HexToFelt(account)
+		if err == nil && key.Equal(publicKey) {
+			return publicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("public key %s of account %s is not in the keystore", publicKey, accountAddress)
 }
 
 func (c *chain) SendTx(ctx context.Context, from, to string, amount *big.Int, balanceCheck bool) error {
 	return c.Transact(ctx, from, to, amount, balanceCheck)
 }
 
-// TODO BCF-2602 statuses are static for non-evm chain and should be dynamic
 func (c *chain) listNodeStatuses(start, end int) ([]types.NodeStatus, int, error) {
 	stats := make([]types.NodeStatus, 0)
 	total := len(c.cfg.Nodes)
@@ -246,7 +336,7 @@ func (c *chain) listNodeStatuses(start, end int) ([]types.NodeStatus, int, error
 	}
 	nodes := c.cfg.Nodes[start:end]
 	for _, node := range nodes {
-		stat, err := nodeStatus(node, c.ChainID())
+		stat, err := nodeStatus(node, c.ChainID(), c.pool.state(*node.Name))
 		if err != nil {
 			return stats, total, err
 		}
@@ -255,10 +345,11 @@ func (c *chain) listNodeStatuses(start, end int) ([]types.NodeStatus, int, error
 	return stats, total, nil
 }
 
-func nodeStatus(n *config.Node, id string) (types.NodeStatus, error) {
+func nodeStatus(n *config.Node, id string, state nodeState) (types.NodeStatus, error) {
 	var s types.NodeStatus
 	s.ChainID = id
 	s.Name = *n.Name
+	s.State = string(state)
 	b, err := toml.Marshal(n)
 	if err != nil {
 		return types.NodeStatus{}, err
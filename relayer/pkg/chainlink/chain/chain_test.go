@@ -0,0 +1,138 @@
+package starknet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commoncfg "github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/config"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet/starknettest"
+)
+
+// fakeKeystore holds the public keys in keys, it only supports key existence checks.
+type fakeKeystore struct {
+	keys []string
+}
+
+func (ks *fakeKeystore) Sign(ctx context.Context, account string, data []byte) ([]byte, error) {
+	for _, k := range ks.keys {
+		if k == account && data == nil {
+			return nil, nil
+		}
+	}
+	return nil, errors.New("unknown key")
+}
+
+func (ks *fakeKeystore) Accounts(ctx context.Context) ([]string, error) {
+	return ks.keys, nil
+}
+
+func TestChain_Transact(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	server := starknettest.NewServer(t, "SN_SEPOLIA")
+	publicKey := new(felt.Felt).SetUint64(0x123)
+	recipient := new(felt.Felt).SetUint64(0xb0b)
+
+	// deployAccount deploys an account that returns result from its public key getter, and funds it with its fee token
+	deployAccount := func(address uint64, unit starknetrpc.FeePaymentUnit, getter string, result ...*felt.Felt) *felt.Felt {
+		account := new(felt.Felt).SetUint64(address)
+		server.AddAccount(account)
+		server.Deploy(account, starknettest.Functions{
+			getter: func(*starknettest.Execution, []*felt.Felt) ([]*felt.Felt, error) { return result, nil },
+		})
+		server.FeeToken(unit).Mint(account, big.NewInt(1_000_000_000))
+		return account
+	}
+	cairo1 := deployAccount(1, starknetrpc.UnitStrk, "get_public_key", publicKey)
+	cairo0 := deployAccount(2, starknetrpc.UnitWei, "getPublicKey", publicKey)
+	unknownKey := deployAccount(3, starknetrpc.UnitStrk, "get_public_key", new(felt.Felt).SetUint64(0x456))
+	invalidKey := deployAccount(4, starknetrpc.UnitStrk, "get_public_key", publicKey, publicKey)
+
+	chainID, nodeName := "SN_SEPOLIA", "primary"
+	cairo0Address, cairo0Flavour := cairo0.String(), string(txm.FlavourCairo0)
+	cfg := &config.TOMLConfig{
+		ChainID:  &chainID,
+		Nodes:    config.Nodes{{Name: &nodeName, URL: commoncfg.MustParseURL(server.URL)}},
+		Accounts: config.Accounts{{Address: &cairo0Address, Flavour: &cairo0Flavour}},
+	}
+	cfg.SetDefaults()
+	c, err := newChain(chainID, cfg, &fakeKeystore{keys: []string{publicKey.String()}}, logger.Test(t))
+	require.NoError(t, err)
+
+	// transfers returns the calldata of the transfers of token enqueued by account, the txm is not started so they
+	// stay queued
+	transfers := func(t *testing.T, account, token *felt.Felt) (calldata [][]*felt.Felt) {
+		records, err := c.txm.ListTransactions(ctx, account, txm.TxEnqueued)
+		require.NoError(t, err)
+		for _, r := range records {
+			assert.Equal(t, token, r.Call.ContractAddress)
+			assert.Equal(t, starknetutils.GetSelectorFromNameFelt("transfer"), r.Call.EntryPointSelector)
+			assert.Equal(t, publicKey, r.PublicKey)
+			assert.Equal(t, txm.PriorityHigh, r.Priority)
+			calldata = append(calldata, r.Call.Calldata)
+		}
+		return
+	}
+	u256 := func(low, high uint64) []*felt.Felt {
+		return []*felt.Felt{recipient, new(felt.Felt).SetUint64(low), new(felt.Felt).SetUint64(high)}
+	}
+
+	t.Run("u256 amount", func(t *testing.T) {
+		require.NoError(t, c.Transact(ctx, cairo1.String(), recipient.String(), big.NewInt(7), true))
+		// not covered by the balance, but not checked either
+		amount := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(3), 128), big.NewInt(5))
+		require.NoError(t, c.Transact(ctx, cairo1.String(), recipient.String(), amount, false))
+		assert.Equal(t, [][]*felt.Felt{u256(7, 0), u256(5, 3)}, transfers(t, cairo1, starknettest.STRKFeeToken))
+
+		err := c.Transact(ctx, cairo1.String(), recipient.String(), new(big.Int).Lsh(big.NewInt(1), 256), false)
+		require.ErrorContains(t, err, "must be a u256")
+	})
+
+	t.Run("cairo 0 account pays in ETH", func(t *testing.T) {
+		call := starknetrpc.FunctionCall{
+			ContractAddress:    starknettest.ETHFeeToken,
+			EntryPointSelector: starknetutils.GetSelectorFromNameFelt("transfer"),
+			Calldata:           u256(1, 0),
+		}
+		maxFee, err := c.txm.EstimateMaxFee(ctx, cairo0, publicKey, call)
+		require.NoError(t, err)
+		require.Positive(t, maxFee.Sign())
+		available := new(big.Int).Sub(big.NewInt(1_000_000_000), maxFee)
+
+		// the balance has to cover the max fee of the transfer too
+		tooMuch := new(big.Int).Add(available, big.NewInt(1))
+		err = c.Transact(ctx, cairo0.String(), recipient.String(), tooMuch, true)
+		require.ErrorContains(t, err, "balance 1000000000 of "+cairo0.String()+" is too low to transfer "+tooMuch.String()+" with a max fee of "+maxFee.String())
+		assert.Empty(t, transfers(t, cairo0, starknettest.ETHFeeToken))
+
+		require.NoError(t, c.Transact(ctx, cairo0.String(), recipient.String(), available, true))
+		assert.Equal(t, [][]*felt.Felt{u256(available.Uint64(), 0)}, transfers(t, cairo0, starknettest.ETHFeeToken))
+	})
+
+	t.Run("key not in keystore", func(t *testing.T) {
+		err := c.Transact(ctx, unknownKey.String(), recipient.String(), big.NewInt(1), false)
+		require.EqualError(t, err, "public key 0x456 of account 0x3 is not in the keystore")
+		assert.Empty(t, transfers(t, unknownKey, starknettest.STRKFeeToken))
+	})
+
+	t.Run("invalid public key", func(t *testing.T) {
+		err := c.Transact(ctx, invalidKey.String(), recipient.String(), big.NewInt(1), false)
+		require.ErrorContains(t, err, "failed to get public key of account 0x4: get_public_key: unexpected result length 2")
+		require.ErrorContains(t, err, "getPublicKey: ")
+		assert.NotContains(t, err.Error(), "%!w")
+	})
+}
@@ -0,0 +1,439 @@
+package starknet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/db"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// nodeState is the health of a node of the pool, as reported by ListNodeStatuses.
+type nodeState string
+
+const (
+	// nodeStateUndialed nodes have not been probed yet
+	nodeStateUndialed    nodeState = "Undialed"
+	nodeStateAlive       nodeState = "Alive"
+	nodeStateOutOfSync   nodeState = "OutOfSync"
+	nodeStateUnreachable nodeState = "Unreachable"
+	// nodeStateInvalidChainID nodes serve another chain than the configured one and are not used
+	nodeStateInvalidChainID nodeState = "InvalidChainID"
+)
+
+// rank orders the states from the most to the least preferred.
+func (s nodeState) rank() int {
+	switch s {
+	case nodeStateAlive:
+		return 0
+	case nodeStateUndialed:
+		return 1
+	case nodeStateOutOfSync:
+		return 2
+	case nodeStateUnreachable:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type poolNode struct {
+	name   string
+	url    *url.URL
+	apiKey string
+	// chainID is the configured chain ID
+	chainID string
+	// client sends the probes to this node only
+	client *starknet.Client
+
+	// guarded by the pool lock
+	state       nodeState
+	blockNumber uint64
+	// verified is set once the node has reported the configured chain ID
+	verified bool
+	err      error
+}
+
+// nodePool probes the configured nodes every pollInterval and serves a client whose requests are sent to the
+// healthiest node, failing over to the next node on transport errors.
+type nodePool struct {
+	starter      utils.StartStopOnce
+	lggr         logger.Logger
+	pollInterval time.Duration
+	// nodes more than syncThreshold blocks behind the highest node are out of sync
+	syncThreshold uint64
+	timeout       time.Duration
+	done          sync.WaitGroup
+	stop          chan struct{}
+
+	lock   sync.RWMutex
+	nodes  []*poolNode
+	client *starknet.Client
+}
+
+func newNodePool(lggr logger.Logger, nodes []db.Node, pollInterval time.Duration, syncThreshold uint32, timeout time.Duration) (*nodePool, error) {
+	p := &nodePool{
+		lggr:          logger.Named(lggr, "NodePool"),
+		pollInterval:  pollInterval,
+		syncThreshold: uint64(syncThreshold),
+		timeout:       timeout,
+		stop:          make(chan struct{}),
+	}
+	for _, n := range nodes {
+		u, err := url.Parse(n.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url of node %s: %w", n.Name, err)
+		}
+		client, err := starknet.NewClient(n.ChainID, n.URL, n.APIKey, p.lggr, &timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client of node %s: %w", n.Name, err)
+		}
+		p.nodes = append(p.nodes, &poolNode{name: n.Name, url: u, apiKey: n.APIKey, chainID: n.ChainID, client: client, state: nodeStateUndialed})
+	}
+	if len(p.nodes) == 0 {
+		return p, nil
+	}
+
+	// the base url and api key are replaced by the transport for every attempt
+	httpClient := &http.Client{Transport: &poolTransport{pool: p, base: http.DefaultTransport}}
+	client, err := starknet.NewClient(nodes[0].ChainID, nodes[0].URL, "", p.lggr, &timeout, ethrpc.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool client: %w", err)
+	}
+	p.client = client
+	return p, nil
+}
+
+func (p *nodePool) Name() string {
+	return p.lggr.Name()
+}
+
+func (p *nodePool) Start(context.Context) error {
+	return p.starter.StartOnce("NodePool", func() error {
+		p.done.Add(1)
+		go p.probeLoop()
+		return nil
+	})
+}
+
+func (p *nodePool) Close() error {
+	return p.starter.StopOnce("NodePool", func() error {
+		close(p.stop)
+		p.done.Wait()
+		return nil
+	})
+}
+
+// HealthReport reports the pool as unhealthy when no probed node is alive, and the nodes that serve another chain.
+func (p *nodePool) HealthReport() map[string]error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	err := p.starter.Healthy()
+	probed := slices.ContainsFunc(p.nodes, func(n *poolNode) bool { return n.state != nodeStateUndialed })
+	alive := slices.ContainsFunc(p.nodes, func(n *poolNode) bool { return n.state == nodeStateAlive })
+	if err == nil && probed && !alive {
+		err = errors.New("no alive nodes")
+	}
+	report := map[string]error{p.Name(): err}
+	for _, n := range p.nodes {
+		if n.state == nodeStateInvalidChainID {
+			report[fmt.Sprintf("%s.Node.%s", p.Name(), n.name)] = n.err
+		}
+	}
+	return report
+}
+
+// Client returns the client of the pool, its requests are sent to the nodes in their order of preference.
+func (p *nodePool) Client() (*starknet.Client, error) {
+	if p.client == nil {
+		return nil, errors.New("no nodes available")
+	}
+	return p.client, nil
+}
+
+// state returns the state of the node with name.
+func (p *nodePool) state(name string) nodeState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	for _, n := range p.nodes {
+		if n.name == name {
+			return n.state
+		}
+	}
+	return ""
+}
+
+// ordered returns the usable nodes by state, and then from the highest to the lowest block, unreachable nodes last.
+func (p *nodePool) ordered() []*poolNode {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	nodes := slices.DeleteFunc(slices.Clone(p.nodes), func(n *poolNode) bool { return n.state == nodeStateInvalidChainID })
+	slices.SortStableFunc(nodes, func(a, b *poolNode) int {
+		if a.state.rank() != b.state.rank() {
+			return a.state.rank() - b.state.rank()
+		}
+		switch {
+		case a.blockNumber > b.blockNumber:
+			return -1
+		case a.blockNumber < b.blockNumber:
+			return 1
+		}
+		return 0
+	})
+	return nodes
+}
+
+func (p *nodePool) probeLoop() {
+	defer p.done.Done()
+
+	ctx, cancel := utils.ContextFromChan(p.stop)
+	defer cancel()
+
+	tick := time.NewTicker(p.pollInterval)
+	defer tick.Stop()
+	for {
+		p.probeAll(ctx)
+		select {
+		case <-p.stop:
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+type probeResult struct {
+	blockNumber uint64
+	syncing     bool
+	err         error
+}
+
+// probeAll probes the nodes concurrently and updates their states.
+func (p *nodePool) probeAll(ctx context.Context) {
+	results := make([]probeResult, len(p.nodes))
+	var wg sync.WaitGroup
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *poolNode) {
+			defer wg.Done()
+			results[i] = p.probe(ctx, n)
+		}(i, n)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	var highest uint64
+	for _, r := range results {
+		if r.err == nil && !r.syncing {
+			highest = max(highest, r.blockNumber)
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i, n := range p.nodes {
+		r := results[i]
+		if r.err == nil {
+			n.blockNumber = r.blockNumber
+		}
+		n.verified = r.err == nil
+		state := nodeStateAlive
+		switch {
+		case errors.Is(r.err, starknet.ErrChainIDMismatch):
+			state = nodeStateInvalidChainID
+		case r.err != nil:
+			state = nodeStateUnreachable
+		case r.syncing:
+			state = nodeStateOutOfSync
+			r.err = errors.New("node is syncing")
+		case r.blockNumber+p.syncThreshold < highest:
+			state = nodeStateOutOfSync
+			r.err = fmt.Errorf("node is at block %d, %d blocks behind the highest node", r.blockNumber, highest-r.blockNumber)
+		}
+		p.setState(n, state, r.err)
+	}
+}
+
+// probe requests the latest block number, chain ID and sync status of a node in one batch.
+func (p *nodePool) probe(ctx context.Context, n *poolNode) probeResult {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var blockNumber uint64
+	var chainID string
+	var syncing json.RawMessage
+	batch := []ethrpc.BatchElem{
+		{Method: "starknet_blockNumber", Result: &blockNumber},
+		{Method: "starknet_chainId", Result: &chainID},
+		{Method: "starknet_syncing", Result: &syncing},
+	}
+	if err := n.client.EthClient.BatchCallContext(ctx, batch); err != nil {
+		return probeResult{err: fmt.Errorf("failed to probe node: %w", err)}
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return probeResult{err: fmt.Errorf("%s failed: %w", elem.Method, elem.Error)}
+		}
+	}
+	if err := starknet.MatchChainID(n.chainID, chainID); err != nil {
+		return probeResult{err: err}
+	}
+	// a node that is not syncing returns false, else the sync progress
+	return probeResult{blockNumber: blockNumber, syncing: string(syncing) != "false"}
+}
+
+// verify checks the chain ID of a node before its first use, in case it is used before it is probed.
+func (p *nodePool) verify(ctx context.Context, n *poolNode) error {
+	p.lock.RLock()
+	verified := n.verified
+	p.lock.RUnlock()
+	if verified {
+		return nil
+	}
+
+	err := n.client.VerifyChainID(ctx)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	switch {
+	case ctx.Err() != nil:
+		// the caller gave up, which says nothing about the node
+	case errors.Is(err, starknet.ErrChainIDMismatch):
+		p.setState(n, nodeStateInvalidChainID, err)
+	case err != nil:
+		p.setState(n, nodeStateUnreachable, err)
+	default:
+		n.verified = true
+	}
+	return err
+}
+
+// markUnreachable is called when a request to a node fails, until the node recovers on the next probe.
+func (p *nodePool) markUnreachable(n *poolNode, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.setState(n, nodeStateUnreachable, err)
+}
+
+// setState updates the state of a node and logs its transitions. The pool lock must be held.
+func (p *nodePool) setState(n *poolNode, state nodeState, err error) {
+	previous := n.state
+	n.state, n.err = state, err
+	if state == previous {
+		return
+	}
+	switch state {
+	case nodeStateAlive:
+		p.lggr.Infow("Node is alive", "name", n.name, "previousState", previous, "blockNumber", n.blockNumber)
+		return
+	case nodeStateInvalidChainID:
+		p.lggr.Errorw("Node serves another chain, it is not used", "name", n.name, "previousState", previous, "error", err)
+		return
+	}
+	p.lggr.Warnw("Node is not alive", "name", n.name, "state", state, "previousState", previous, "error", err)
+}
+
+// poolTransport sends the requests of the pool client to the nodes in their order of preference, and tries the
+// next node when a node cannot be reached or fails with a server error. Txs are only sent to the next node if the
+// request never reached the failed node: a node that answers with an error may still have accepted the tx, and the
+// next node would reject it as a duplicate.
+type poolTransport struct {
+	pool *nodePool
+	base http.RoundTripper
+}
+
+func (t *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	write := isWriteRequest(body)
+	var errs error
+	for _, n := range t.pool.ordered() {
+		if err := t.pool.verify(req.Context(), n); err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			errs = errors.Join(errs, fmt.Errorf("node %s: %w", n.name, err))
+			continue
+		}
+
+		ctx := req.Context()
+		var sent atomic.Bool
+		if write {
+			ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+				WroteRequest: func(info httptrace.WroteRequestInfo) { sent.Store(info.Err == nil) },
+			})
+		}
+		r := req.Clone(ctx)
+		r.URL, r.Host = n.url, ""
+		r.Header.Del("x-apikey")
+		if n.apiKey != "" {
+			r.Header.Set("x-apikey", n.apiKey)
+		}
+		r.Body, r.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+
+		resp, err := t.base.RoundTrip(r)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+			resp.Body.Close()
+		}
+		if req.Context().Err() != nil {
+			// the caller gave up, which says nothing about the node
+			return nil, err
+		}
+		t.pool.markUnreachable(n, err)
+		if write && sent.Load() {
+			return nil, fmt.Errorf("node %s: %w", n.name, err)
+		}
+		t.pool.lggr.Warnw("Request to node failed, trying the next node", "name", n.name, "error", err)
+		errs = errors.Join(errs, fmt.Errorf("node %s: %w", n.name, err))
+	}
+	if errs == nil {
+		return nil, errors.New("no nodes serve the configured chain ID")
+	}
+	return nil, fmt.Errorf("all nodes failed: %w", errs)
+}
+
+// isWriteRequest returns true if the JSON-RPC request (or batch) in body submits a tx.
+func isWriteRequest(body []byte) bool {
+	type request struct {
+		Method string `json:"method"`
+	}
+	var batch []request
+	if err := json.Unmarshal(body, &batch); err != nil {
+		var single request
+		if err := json.Unmarshal(body, &single); err != nil {
+			// not JSON-RPC, don't risk sending it twice
+			return true
+		}
+		batch = []request{single}
+	}
+	return slices.ContainsFunc(batch, func(r request) bool { return strings.HasPrefix(r.Method, "starknet_add") })
+}
@@ -0,0 +1,215 @@
+package starknet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/db"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet/starknettest"
+)
+
+// closedURL returns the url of a server that is no longer listening.
+func closedURL() string {
+	s := httptest.NewServer(nil)
+	s.Close()
+	return s.URL
+}
+
+func TestNodePool(t *testing.T) {
+	t.Parallel()
+
+	synced := starknettest.NewServer(t, "SN_SEPOLIA")
+	behind := starknettest.NewServer(t, "SN_SEPOLIA")
+	var head uint64
+	for i := 0; i < 20; i++ {
+		head = synced.Mine()
+	}
+	behind.Mine()
+
+	nodes := []db.Node{
+		{Name: "down", ChainID: "SN_SEPOLIA", URL: closedURL()},
+		{Name: "behind", ChainID: "SN_SEPOLIA", URL: behind.URL},
+		{Name: "synced", ChainID: "SN_SEPOLIA", URL: synced.URL},
+	}
+
+	t.Run("failover", func(t *testing.T) {
+		t.Parallel()
+		ctx := tests.Context(t)
+
+		pool, err := newNodePool(logger.Test(t), nodes, time.Minute, 5, time.Second)
+		require.NoError(t, err)
+		client, err := pool.Client()
+		require.NoError(t, err)
+
+		// the nodes are undialed, so the request goes to the first node and fails over to the next one
+		_, err = client.LatestBlockHeight(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, nodeStateUnreachable, pool.state("down"))
+		assert.Equal(t, nodeStateUndialed, pool.state("behind"))
+		assert.Equal(t, []string{"behind", "synced", "down"}, names(pool.ordered()))
+	})
+
+	t.Run("probes", func(t *testing.T) {
+		t.Parallel()
+		ctx := tests.Context(t)
+
+		pool, err := newNodePool(logger.Test(t), nodes, 50*time.Millisecond, 5, time.Second)
+		require.NoError(t, err)
+		require.NoError(t, pool.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, pool.Close()) })
+
+		require.Eventually(t, func() bool {
+			return pool.state("synced") == nodeStateAlive
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, nodeStateUnreachable, pool.state("down"))
+		assert.Equal(t, nodeStateOutOfSync, pool.state("behind"))
+		assert.Equal(t, map[string]error{pool.Name(): nil}, pool.HealthReport())
+
+		// the most up-to-date node is preferred
+		client, err := pool.Client()
+		require.NoError(t, err)
+		height, err := client.LatestBlockHeight(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, head, height)
+
+		// a node that catches up is alive again
+		for i := 0; i < 20; i++ {
+			behind.Mine()
+		}
+		require.Eventually(t, func() bool {
+			return pool.state("behind") == nodeStateAlive
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("no alive nodes", func(t *testing.T) {
+		t.Parallel()
+		ctx := tests.Context(t)
+
+		pool, err := newNodePool(logger.Test(t), []db.Node{{Name: "down", ChainID: "SN_SEPOLIA", URL: closedURL()}}, time.Minute, 5, time.Second)
+		require.NoError(t, err)
+		require.NoError(t, pool.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, pool.Close()) })
+		require.Eventually(t, func() bool {
+			return pool.state("down") == nodeStateUnreachable
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.EqualError(t, pool.HealthReport()[pool.Name()], "no alive nodes")
+
+		client, err := pool.Client()
+		require.NoError(t, err)
+		_, err = client.LatestBlockHeight(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("chain ID mismatch", func(t *testing.T) {
+		t.Parallel()
+		ctx := tests.Context(t)
+
+		mainnet := starknettest.NewServer(t, "SN_MAIN")
+		pool, err := newNodePool(logger.Test(t), []db.Node{
+			{Name: "mainnet", ChainID: "SN_SEPOLIA", URL: mainnet.URL},
+			{Name: "synced", ChainID: "SN_SEPOLIA", URL: synced.URL},
+		}, 50*time.Millisecond, 5, time.Second)
+		require.NoError(t, err)
+		client, err := pool.Client()
+		require.NoError(t, err)
+
+		// the chain ID of a node is verified on first use
+		_, err = client.LatestBlockHeight(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, nodeStateInvalidChainID, pool.state("mainnet"))
+		assert.Zero(t, mainnet.Requests("starknet_blockNumber"))
+		assert.Equal(t, []string{"synced"}, names(pool.ordered()))
+
+		// and by the probes
+		require.NoError(t, pool.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, pool.Close()) })
+		require.Eventually(t, func() bool {
+			return pool.state("synced") == nodeStateAlive
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, nodeStateInvalidChainID, pool.state("mainnet"))
+		report := pool.HealthReport()
+		assert.NoError(t, report[pool.Name()])
+		assert.EqualError(t, report[pool.Name()+".Node.mainnet"], "chain ID mismatch: node serves SN_MAIN, expected SN_SEPOLIA")
+	})
+
+	t.Run("no failover of txs after a server error", func(t *testing.T) {
+		t.Parallel()
+		ctx := tests.Context(t)
+
+		// failing serves its chain ID, but fails all other requests after receiving them
+		var failed atomic.Int32
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			// the chain ID is verified with a batch request
+			var batch []struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+			}
+			if json.Unmarshal(body, &batch) == nil && len(batch) == 1 && batch[0].Method == "starknet_chainId" {
+				_, err = fmt.Fprintf(w, `[{"jsonrpc": "2.0", "id": %s, "result": "0x534e5f5345504f4c4941"}]`, batch[0].ID)
+				require.NoError(t, err)
+				return
+			}
+			failed.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(failing.Close)
+
+		newClient := func(t *testing.T) *http.Client {
+			pool, err := newNodePool(logger.Test(t), []db.Node{
+				{Name: "failing", ChainID: "SN_SEPOLIA", URL: failing.URL},
+				{Name: "synced", ChainID: "SN_SEPOLIA", URL: synced.URL},
+			}, time.Minute, 5, time.Second)
+			require.NoError(t, err)
+			return &http.Client{Transport: &poolTransport{pool: pool, base: http.DefaultTransport}}
+		}
+		post := func(client *http.Client, body string) (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://pool", strings.NewReader(body))
+			require.NoError(t, err)
+			return client.Do(req)
+		}
+
+		// the tx may have been accepted by the failing node, it is not sent again
+		_, err := post(newClient(t), `{"jsonrpc": "2.0", "id": 1, "method": "starknet_addInvokeTransaction", "params": []}`)
+		require.ErrorContains(t, err, "node failing: unexpected status 503 Service Unavailable")
+		assert.Equal(t, int32(1), failed.Load())
+		assert.Zero(t, synced.Requests("starknet_addInvokeTransaction"))
+
+		// reads fail over
+		resp, err := post(newClient(t), `{"jsonrpc": "2.0", "id": 1, "method": "starknet_blockNumber", "params": []}`)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(2), failed.Load())
+	})
+
+	t.Run("no nodes", func(t *testing.T) {
+		t.Parallel()
+
+		pool, err := newNodePool(logger.Test(t), nil, time.Minute, 5, time.Second)
+		require.NoError(t, err)
+		_, err = pool.Client()
+		require.EqualError(t, err, "no nodes available")
+	})
+}
+
+func names(nodes []*poolNode) (names []string) {
+	for _, n := range nodes {
+		names = append(names, n.name)
+	}
+	return
+}
@@ -3,10 +3,13 @@ package config
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"net/url"
 	"slices"
 	"time"
 
+	"github.com/NethermindEth/juno/core/felt"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
 	"github.com/pelletier/go-toml/v2"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/config"
@@ -14,14 +17,45 @@ import (
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/db"
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/ocr2"
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
 )
 
 var DefaultConfigSet = ConfigSet{
-	OCR2CachePollPeriod: 5 * time.Second,
-	OCR2CacheTTL:        time.Minute,
-	RequestTimeout:      10 * time.Second,
-	TxTimeout:           10 * time.Second,
-	ConfirmationPoll:    5 * time.Second,
+	OCR2CachePollPeriod:   5 * time.Second,
+	OCR2CacheTTL:          time.Minute,
+	RequestTimeout:        10 * time.Second,
+	NodePollInterval:      10 * time.Second,
+	NodeSyncThreshold:     10,
+	DefaultBlockTag:       starknet.BlockTagPending,
+	TxTimeout:             10 * time.Second,
+	ConfirmationPoll:      5 * time.Second,
+	ConfirmationBatchSize: 100,
+	FinalityLevel:         string(txm.FinalityAcceptedOnL2),
+	RebroadcastTimeout:    time.Minute,
+	FeeBumpPercent:        20,
+	MaxFeeBumps:           5,
+	MaxQueueLenPerAccount: 1000,
+	BroadcastWorkers:      4,
+	BatchMaxCalls:         1,
+	BatchMaxCalldataLen:   2000,
+	SimulateTxs:           false,
+	FeeToken:              "0x04718f5a0fc34cc1af16a1cdee98ffb20c31f5cd61d6ab07201858f4287c938d", // STRK
+	AccountClass:          string(txm.AccountClassOZ),
+	AutoDeployAccounts:    false,
+	BroadcastMaxAttempts:  3,
+	BroadcastRetryBackoff: 5 * time.Second,
+	TxTTL:                 time.Hour,
+	BalancePollInterval:   0, // opt-in, pauses underfunded accounts
+	DrainTimeout:          30 * time.Second,
+	FeeLedgerRetention:    30 * 24 * time.Hour,
+	FeeEstimator: fees.Config{
+		Mode:                 fees.ModePadded,
+		AmountPaddingPercent: 150,
+		PricePaddingPercent:  150,
+		BlockHistorySize:     10,
+		Percentile:           60,
+	},
 }
 
 type ConfigSet struct { //nolint:revive
@@ -30,10 +64,43 @@ type ConfigSet struct { //nolint:revive
 
 	// client config
 	RequestTimeout time.Duration
+	// node pool health checks, a node more than NodeSyncThreshold blocks behind the highest node is out of sync
+	NodePollInterval  time.Duration
+	NodeSyncThreshold uint32
+	// block read by contract calls and nonce reads that do not target a block, latest or pending
+	DefaultBlockTag string
 
 	// txm config
-	TxTimeout        time.Duration
-	ConfirmationPoll time.Duration
+	TxTimeout             time.Duration
+	ConfirmationPoll      time.Duration
+	ConfirmationBatchSize uint32
+	// see [txm.FinalityLevel]
+	FinalityLevel         string
+	RebroadcastTimeout    time.Duration
+	FeeBumpPercent        uint32
+	MaxFeeBumps           uint32
+	MaxQueueLenPerAccount uint32
+	BroadcastWorkers      uint32
+	// multicall batching of queued calls, disabled when BatchMaxCalls <= 1
+	BatchMaxCalls       uint32
+	BatchMaxCalldataLen uint32
+	SimulateTxs         bool
+	FeeEstimator        fees.Config
+	// token paying the fees of txm and transferred by [Chain.Transact], Cairo 0 accounts use ETH instead
+	FeeToken string
+	// see [txm.ErrInsufficientBalance], disabled when 0 (default)
+	BalancePollInterval time.Duration
+	// how long queued txs are broadcast on shutdown, disabled when 0
+	DrainTimeout time.Duration
+	// how long actual fees are kept for [txm.TxManager.FeeSpend]
+	FeeLedgerRetention time.Duration
+	// account deployment, see [txm.TxManager.DeployAccount]
+	AccountClass       string
+	AutoDeployAccounts bool
+	// retries and expiry, see [txm.TxDeadLetter]
+	BroadcastMaxAttempts  uint32
+	BroadcastRetryBackoff time.Duration
+	TxTTL                 time.Duration
 }
 
 type Config interface {
@@ -44,14 +111,179 @@ type Config interface {
 
 	// client config
 	RequestTimeout() time.Duration
+	NodePollInterval() time.Duration
+	NodeSyncThreshold() uint32
+	DefaultBlockTag() string
 }
 
 type Chain struct {
-	OCR2CachePollPeriod *config.Duration
-	OCR2CacheTTL        *config.Duration
-	RequestTimeout      *config.Duration
-	TxTimeout           *config.Duration
-	ConfirmationPoll    *config.Duration
+	OCR2CachePollPeriod   *config.Duration
+	OCR2CacheTTL          *config.Duration
+	RequestTimeout        *config.Duration
+	NodePollInterval      *config.Duration
+	NodeSyncThreshold     *uint32
+	DefaultBlockTag       *string
+	TxTimeout             *config.Duration
+	ConfirmationPoll      *config.Duration
+	ConfirmationBatchSize *uint32
+	FinalityLevel         *string
+	RebroadcastTimeout    *config.Duration
+	FeeBumpPercent        *uint32
+	MaxFeeBumps           *uint32
+	MaxQueueLenPerAccount *uint32
+	BroadcastWorkers      *uint32
+	BatchMaxCalls         *uint32
+	BatchMaxCalldataLen   *uint32
+	SimulateTxs           *bool
+	FeeToken              *string
+	AccountClass          *string
+	// optional, overrides the well-known class hash of AccountClass
+	AccountClassHash      *string
+	AutoDeployAccounts    *bool
+	BroadcastMaxAttempts  *uint32
+	BroadcastRetryBackoff *config.Duration
+	TxTTL                 *config.Duration
+	BalancePollInterval   *config.Duration
+	DrainTimeout          *config.Duration
+	FeeLedgerRetention    *config.Duration
+	// optional, see [txm.Config.ReplaceSelectors]
+	ReplaceSelectors *[]string
+	// optional, the account that invokes and pays for the txs of all accounts, see [txm.Config.SponsorAccount]
+	SponsorAccount   *string
+	SponsorPublicKey *string
+	// optional, txs are only kept in memory if unset
+	TxStoragePath *string
+	FeeEstimator  FeeEstimator
+}
+
+type FeeEstimator struct {
+	// one of Padded, Fixed, BlockPrice or Percentile
+	Mode                 *string
+	AmountPaddingPercent *uint32
+	PricePaddingPercent  *uint32
+	// only used by the Fixed mode
+	FixedMaxAmount       *uint64
+	FixedMaxPricePerUnit *big.Int
+	// optional, the L2 gas bounds of every tx. RPC v0.7 nodes neither estimate nor price L2 gas, so no mode derives
+	// them, and zero bounds are used unless they are configured.
+	L2GasMaxAmount       *uint64
+	L2GasMaxPricePerUnit *big.Int
+	// only used by the Percentile mode
+	BlockHistorySize *uint32
+	Percentile       *uint32
+	Tip              *uint64
+	// optional, maximum fee in FRI that a single tx may pay
+	MaxFee *big.Int
+	// optional, maximum fee in WEI that a single tx of a Cairo 0 account may pay
+	MaxFeeWei *big.Int
+}
+
+func (f *FeeEstimator) setDefaults() {
+	d := DefaultConfigSet.FeeEstimator
+	if f.Mode == nil {
+		mode := string(d.Mode)
+		f.Mode = &mode
+	}
+	if f.AmountPaddingPercent == nil {
+		amountPaddingPercent := d.AmountPaddingPercent
+		f.AmountPaddingPercent = &amountPaddingPercent
+	}
+	if f.PricePaddingPercent == nil {
+		pricePaddingPercent := d.PricePaddingPercent
+		f.PricePaddingPercent = &pricePaddingPercent
+	}
+	if f.BlockHistorySize == nil {
+		blockHistorySize := d.BlockHistorySize
+		f.BlockHistorySize = &blockHistorySize
+	}
+	if f.Percentile == nil {
+		percentile := d.Percentile
+		f.Percentile = &percentile
+	}
+	if f.Tip == nil {
+		tip := d.Tip
+		f.Tip = &tip
+	}
+}
+
+func (f *FeeEstimator) setFrom(o *FeeEstimator) {
+	if o.Mode != nil {
+		f.Mode = o.Mode
+	}
+	if o.AmountPaddingPercent != nil {
+		f.AmountPaddingPercent = o.AmountPaddingPercent
+	}
+	if o.PricePaddingPercent != nil {
+		f.PricePaddingPercent = o.PricePaddingPercent
+	}
+	if o.FixedMaxAmount != nil {
+		f.FixedMaxAmount = o.FixedMaxAmount
+	}
+	if o.FixedMaxPricePerUnit != nil {
+		f.FixedMaxPricePerUnit = o.FixedMaxPricePerUnit
+	}
+	if o.L2GasMaxAmount != nil {
+		f.L2GasMaxAmount = o.L2GasMaxAmount
+	}
+	if o.L2GasMaxPricePerUnit != nil {
+		f.L2GasMaxPricePerUnit = o.L2GasMaxPricePerUnit
+	}
+	if o.BlockHistorySize != nil {
+		f.BlockHistorySize = o.BlockHistorySize
+	}
+	if o.Percentile != nil {
+		f.Percentile = o.Percentile
+	}
+	if o.Tip != nil {
+		f.Tip = o.Tip
+	}
+	if o.MaxFee != nil {
+		f.MaxFee = o.MaxFee
+	}
+	if o.MaxFeeWei != nil {
+		f.MaxFeeWei = o.MaxFeeWei
+	}
+}
+
+func (f *FeeEstimator) config() fees.Config {
+	cfg := DefaultConfigSet.FeeEstimator
+	if f.Mode != nil {
+		cfg.Mode = fees.Mode(*f.Mode)
+	}
+	if f.AmountPaddingPercent != nil {
+		cfg.AmountPaddingPercent = *f.AmountPaddingPercent
+	}
+	if f.PricePaddingPercent != nil {
+		cfg.PricePaddingPercent = *f.PricePaddingPercent
+	}
+	if f.FixedMaxAmount != nil {
+		cfg.FixedMaxAmount = *f.FixedMaxAmount
+	}
+	if f.FixedMaxPricePerUnit != nil {
+		cfg.FixedMaxPricePerUnit = f.FixedMaxPricePerUnit
+	}
+	if f.L2GasMaxAmount != nil {
+		cfg.L2GasMaxAmount = *f.L2GasMaxAmount
+	}
+	if f.L2GasMaxPricePerUnit != nil {
+		cfg.L2GasMaxPricePerUnit = f.L2GasMaxPricePerUnit
+	}
+	if f.BlockHistorySize != nil {
+		cfg.BlockHistorySize = *f.BlockHistorySize
+	}
+	if f.Percentile != nil {
+		cfg.Percentile = *f.Percentile
+	}
+	if f.Tip != nil {
+		cfg.Tip = *f.Tip
+	}
+	if f.MaxFee != nil {
+		cfg.MaxFee = f.MaxFee
+	}
+	if f.MaxFeeWei != nil {
+		cfg.MaxFeeWei = f.MaxFeeWei
+	}
+	return cfg
 }
 
 func (c *Chain) SetDefaults() {
@@ -64,12 +296,94 @@ func (c *Chain) SetDefaults() {
 	if c.RequestTimeout == nil {
 		c.RequestTimeout = config.MustNewDuration(DefaultConfigSet.RequestTimeout)
 	}
+	if c.NodePollInterval == nil {
+		c.NodePollInterval = config.MustNewDuration(DefaultConfigSet.NodePollInterval)
+	}
+	if c.NodeSyncThreshold == nil {
+		nodeSyncThreshold := DefaultConfigSet.NodeSyncThreshold
+		c.NodeSyncThreshold = &nodeSyncThreshold
+	}
+	if c.DefaultBlockTag == nil {
+		defaultBlockTag := DefaultConfigSet.DefaultBlockTag
+		c.DefaultBlockTag = &defaultBlockTag
+	}
 	if c.TxTimeout == nil {
 		c.TxTimeout = config.MustNewDuration(DefaultConfigSet.TxTimeout)
 	}
 	if c.ConfirmationPoll == nil {
 		c.ConfirmationPoll = config.MustNewDuration(DefaultConfigSet.ConfirmationPoll)
 	}
+	if c.ConfirmationBatchSize == nil {
+		confirmationBatchSize := DefaultConfigSet.ConfirmationBatchSize
+		c.ConfirmationBatchSize = &confirmationBatchSize
+	}
+	if c.FinalityLevel == nil {
+		finalityLevel := DefaultConfigSet.FinalityLevel
+		c.FinalityLevel = &finalityLevel
+	}
+	if c.RebroadcastTimeout == nil {
+		c.RebroadcastTimeout = config.MustNewDuration(DefaultConfigSet.RebroadcastTimeout)
+	}
+	if c.FeeBumpPercent == nil {
+		feeBumpPercent := DefaultConfigSet.FeeBumpPercent
+		c.FeeBumpPercent = &feeBumpPercent
+	}
+	if c.MaxFeeBumps == nil {
+		maxFeeBumps := DefaultConfigSet.MaxFeeBumps
+		c.MaxFeeBumps = &maxFeeBumps
+	}
+	if c.MaxQueueLenPerAccount == nil {
+		maxQueueLenPerAccount := DefaultConfigSet.MaxQueueLenPerAccount
+		c.MaxQueueLenPerAccount = &maxQueueLenPerAccount
+	}
+	if c.BroadcastWorkers == nil {
+		broadcastWorkers := DefaultConfigSet.BroadcastWorkers
+		c.BroadcastWorkers = &broadcastWorkers
+	}
+	if c.BatchMaxCalls == nil {
+		batchMaxCalls := DefaultConfigSet.BatchMaxCalls
+		c.BatchMaxCalls = &batchMaxCalls
+	}
+	if c.BatchMaxCalldataLen == nil {
+		batchMaxCalldataLen := DefaultConfigSet.BatchMaxCalldataLen
+		c.BatchMaxCalldataLen = &batchMaxCalldataLen
+	}
+	if c.SimulateTxs == nil {
+		simulateTxs := DefaultConfigSet.SimulateTxs
+		c.SimulateTxs = &simulateTxs
+	}
+	if c.FeeToken == nil {
+		feeToken := DefaultConfigSet.FeeToken
+		c.FeeToken = &feeToken
+	}
+	if c.AccountClass == nil {
+		accountClass := DefaultConfigSet.AccountClass
+		c.AccountClass = &accountClass
+	}
+	if c.AutoDeployAccounts == nil {
+		autoDeployAccounts := DefaultConfigSet.AutoDeployAccounts
+		c.AutoDeployAccounts = &autoDeployAccounts
+	}
+	if c.BroadcastMaxAttempts == nil {
+		broadcastMaxAttempts := DefaultConfigSet.BroadcastMaxAttempts
+		c.BroadcastMaxAttempts = &broadcastMaxAttempts
+	}
+	if c.BroadcastRetryBackoff == nil {
+		c.BroadcastRetryBackoff = config.MustNewDuration(DefaultConfigSet.BroadcastRetryBackoff)
+	}
+	if c.TxTTL == nil {
+		c.TxTTL = config.MustNewDuration(DefaultConfigSet.TxTTL)
+	}
+	if c.BalancePollInterval == nil {
+		c.BalancePollInterval = config.MustNewDuration(DefaultConfigSet.BalancePollInterval)
+	}
+	if c.DrainTimeout == nil {
+		c.DrainTimeout = config.MustNewDuration(DefaultConfigSet.DrainTimeout)
+	}
+	if c.FeeLedgerRetention == nil {
+		c.FeeLedgerRetention = config.MustNewDuration(DefaultConfigSet.FeeLedgerRetention)
+	}
+	c.FeeEstimator.setDefaults()
 }
 
 type Node struct {
@@ -79,6 +393,15 @@ type Node struct {
 	APIKey *string
 }
 
+// Account configures how the invokes of a transmitter account are built and signed.
+type Account struct {
+	Address *string
+	// one of OpenZeppelin, Argent, Braavos or Cairo0, see [txm.AccountFlavour]
+	Flavour *string
+	// optional, the keystore key that co-signs the invokes of a guardian-protected Argent account
+	GuardianPublicKey *string
+}
+
 type TOMLConfigs []*TOMLConfig
 
 func (cs TOMLConfigs) ValidateConfig() (err error) {
@@ -142,6 +465,8 @@ type TOMLConfig struct {
 	Enabled *bool
 	Chain
 	Nodes Nodes
+	// optional, accounts without an entry are OpenZeppelin accounts
+	Accounts Accounts
 }
 
 func (c *TOMLConfig) IsEnabled() bool {
@@ -160,6 +485,7 @@ func (c *TOMLConfig) SetFrom(f *TOMLConfig) {
 	}
 	setFromChain(&c.Chain, &f.Chain)
 	c.Nodes.SetFrom(&f.Nodes)
+	c.Accounts.SetFrom(&f.Accounts)
 }
 
 func setFromChain(c, f *Chain) {
@@ -172,12 +498,94 @@ func setFromChain(c, f *Chain) {
 	if f.RequestTimeout != nil {
 		c.RequestTimeout = f.RequestTimeout
 	}
+	if f.NodePollInterval != nil {
+		c.NodePollInterval = f.NodePollInterval
+	}
+	if f.NodeSyncThreshold != nil {
+		c.NodeSyncThreshold = f.NodeSyncThreshold
+	}
+	if f.DefaultBlockTag != nil {
+		c.DefaultBlockTag = f.DefaultBlockTag
+	}
 	if f.TxTimeout != nil {
 		c.TxTimeout = f.TxTimeout
 	}
 	if f.ConfirmationPoll != nil {
 		c.ConfirmationPoll = f.ConfirmationPoll
 	}
+	if f.ConfirmationBatchSize != nil {
+		c.ConfirmationBatchSize = f.ConfirmationBatchSize
+	}
+	if f.FinalityLevel != nil {
+		c.FinalityLevel = f.FinalityLevel
+	}
+	if f.RebroadcastTimeout != nil {
+		c.RebroadcastTimeout = f.RebroadcastTimeout
+	}
+	if f.FeeBumpPercent != nil {
+		c.FeeBumpPercent = f.FeeBumpPercent
+	}
+	if f.MaxFeeBumps != nil {
+		c.MaxFeeBumps = f.MaxFeeBumps
+	}
+	if f.MaxQueueLenPerAccount != nil {
+		c.MaxQueueLenPerAccount = f.MaxQueueLenPerAccount
+	}
+	if f.BroadcastWorkers != nil {
+		c.BroadcastWorkers = f.BroadcastWorkers
+	}
+	if f.BatchMaxCalls != nil {
+		c.BatchMaxCalls = f.BatchMaxCalls
+	}
+	if f.BatchMaxCalldataLen != nil {
+		c.BatchMaxCalldataLen = f.BatchMaxCalldataLen
+	}
+	if f.SimulateTxs != nil {
+		c.SimulateTxs = f.SimulateTxs
+	}
+	if f.FeeToken != nil {
+		c.FeeToken = f.FeeToken
+	}
+	if f.AccountClass != nil {
+		c.AccountClass = f.AccountClass
+	}
+	if f.AccountClassHash != nil {
+		c.AccountClassHash = f.AccountClassHash
+	}
+	if f.ReplaceSelectors != nil {
+		c.ReplaceSelectors = f.ReplaceSelectors
+	}
+	if f.SponsorAccount != nil {
+		c.SponsorAccount = f.SponsorAccount
+	}
+	if f.SponsorPublicKey != nil {
+		c.SponsorPublicKey = f.SponsorPublicKey
+	}
+	if f.AutoDeployAccounts != nil {
+		c.AutoDeployAccounts = f.AutoDeployAccounts
+	}
+	if f.BroadcastMaxAttempts != nil {
+		c.BroadcastMaxAttempts = f.BroadcastMaxAttempts
+	}
+	if f.BroadcastRetryBackoff != nil {
+		c.BroadcastRetryBackoff = f.BroadcastRetryBackoff
+	}
+	if f.TxTTL != nil {
+		c.TxTTL = f.TxTTL
+	}
+	if f.BalancePollInterval != nil {
+		c.BalancePollInterval = f.BalancePollInterval
+	}
+	if f.DrainTimeout != nil {
+		c.DrainTimeout = f.DrainTimeout
+	}
+	if f.FeeLedgerRetention != nil {
+		c.FeeLedgerRetention = f.FeeLedgerRetention
+	}
+	if f.TxStoragePath != nil {
+		c.TxStoragePath = f.TxStoragePath
+	}
+	c.FeeEstimator.setFrom(&f.FeeEstimator)
 }
 
 func (c *TOMLConfig) ValidateConfig() (err error) {
@@ -191,6 +599,76 @@ func (c *TOMLConfig) ValidateConfig() (err error) {
 		err = errors.Join(err, config.ErrMissing{Name: "Nodes", Msg: "must have at least one node"})
 	}
 
+	if c.Chain.NodePollInterval != nil && c.Chain.NodePollInterval.Duration() <= 0 {
+		err = errors.Join(err, config.ErrInvalid{Name: "NodePollInterval", Value: c.Chain.NodePollInterval.Duration(), Msg: "must be positive"})
+	}
+
+	if c.Chain.DefaultBlockTag != nil {
+		switch *c.Chain.DefaultBlockTag {
+		case starknet.BlockTagLatest, starknet.BlockTagPending:
+		default:
+			err = errors.Join(err, config.ErrInvalid{Name: "DefaultBlockTag", Value: *c.Chain.DefaultBlockTag, Msg: "must be latest or pending"})
+		}
+	}
+
+	if c.Chain.FeeEstimator.Mode != nil {
+		if _, feeErr := fees.NewEstimator(c.Chain.FeeEstimator.config()); feeErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "FeeEstimator", Value: *c.Chain.FeeEstimator.Mode, Msg: feeErr.Error()})
+		}
+	}
+
+	if c.Chain.FeeToken != nil {
+		if _, feeTokenErr := starknetutils.HexToFelt(*c.Chain.FeeToken); feeTokenErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "FeeToken", Value: *c.Chain.FeeToken, Msg: feeTokenErr.Error()})
+		}
+	}
+
+	if c.Chain.AccountClass != nil {
+		switch txm.AccountClass(*c.Chain.AccountClass) {
+		case txm.AccountClassOZ, txm.AccountClassArgent:
+		default:
+			err = errors.Join(err, config.ErrInvalid{Name: "AccountClass", Value: *c.Chain.AccountClass, Msg: "must be OpenZeppelin or Argent"})
+		}
+	}
+
+	if c.Chain.FinalityLevel != nil {
+		switch txm.FinalityLevel(*c.Chain.FinalityLevel) {
+		case txm.FinalityAcceptedOnL2, txm.FinalityAcceptedOnL1:
+		default:
+			err = errors.Join(err, config.ErrInvalid{Name: "FinalityLevel", Value: *c.Chain.FinalityLevel, Msg: "must be ACCEPTED_ON_L2 or ACCEPTED_ON_L1"})
+		}
+	}
+
+	if c.Chain.AccountClassHash != nil {
+		if _, classHashErr := starknetutils.HexToFelt(*c.Chain.AccountClassHash); classHashErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "AccountClassHash", Value: *c.Chain.AccountClassHash, Msg: classHashErr.Error()})
+		}
+	}
+
+	if c.Chain.ReplaceSelectors != nil {
+		for _, name := range *c.Chain.ReplaceSelectors {
+			if name == "" {
+				err = errors.Join(err, config.ErrEmpty{Name: "ReplaceSelectors", Msg: "selector names must not be empty"})
+			}
+		}
+	}
+
+	if (c.Chain.SponsorAccount == nil) != (c.Chain.SponsorPublicKey == nil) {
+		err = errors.Join(err, config.ErrMissing{Name: "SponsorPublicKey", Msg: "SponsorAccount and SponsorPublicKey must be set together"})
+	}
+	if c.Chain.SponsorAccount != nil {
+		if _, sponsorErr := starknetutils.HexToFelt(*c.Chain.SponsorAccount); sponsorErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "SponsorAccount", Value: *c.Chain.SponsorAccount, Msg: sponsorErr.Error()})
+		}
+	}
+	if c.Chain.SponsorPublicKey != nil {
+		if _, sponsorErr := starknetutils.HexToFelt(*c.Chain.SponsorPublicKey); sponsorErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "SponsorPublicKey", Value: *c.Chain.SponsorPublicKey, Msg: sponsorErr.Error()})
+		}
+	}
+
+	err = errors.Join(err, c.Accounts.validate())
+
 	return
 }
 
@@ -227,6 +705,75 @@ func setFromNode(n, f *Node) {
 	}
 }
 
+type Accounts []*Account
+
+func (as *Accounts) SetFrom(fs *Accounts) {
+	for _, f := range *fs {
+		if f.Address == nil {
+			*as = append(*as, f)
+		} else if i := slices.IndexFunc(*as, func(a *Account) bool {
+			return a.Address != nil && *a.Address == *f.Address
+		}); i == -1 {
+			*as = append(*as, f)
+		} else {
+			setFromAccount((*as)[i], f)
+		}
+	}
+}
+
+func setFromAccount(a, f *Account) {
+	if f.Flavour != nil {
+		a.Flavour = f.Flavour
+	}
+	if f.GuardianPublicKey != nil {
+		a.GuardianPublicKey = f.GuardianPublicKey
+	}
+}
+
+// find returns the account with the given address, or nil.
+func (as Accounts) find(accountAddress *felt.Felt) *Account {
+	for _, a := range as {
+		if a.Address == nil {
+			continue
+		}
+		// validated by ValidateConfig
+		address, err := starknetutils.HexToFelt(*a.Address)
+		if err == nil && address.Equal(accountAddress) {
+			return a
+		}
+	}
+	return nil
+}
+
+func (as Accounts) validate() (err error) {
+	addresses := config.UniqueStrings{}
+	for i, a := range as {
+		if a.Address == nil {
+			err = errors.Join(err, config.ErrMissing{Name: fmt.Sprintf("Accounts.%d.Address", i), Msg: "required for all accounts"})
+		} else if address, addressErr := starknetutils.HexToFelt(*a.Address); addressErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: fmt.Sprintf("Accounts.%d.Address", i), Value: *a.Address, Msg: addressErr.Error()})
+		} else if addresses.IsDupeFmt(address) {
+			err = errors.Join(err, config.NewErrDuplicate(fmt.Sprintf("Accounts.%d.Address", i), *a.Address))
+		}
+
+		flavour := txm.FlavourOZ
+		if a.Flavour != nil {
+			flavour = txm.AccountFlavour(*a.Flavour)
+			if !flavour.IsValid() {
+				err = errors.Join(err, config.ErrInvalid{Name: fmt.Sprintf("Accounts.%d.Flavour", i), Value: *a.Flavour, Msg: "must be OpenZeppelin, Argent, Braavos or Cairo0"})
+			}
+		}
+		if a.GuardianPublicKey != nil {
+			if _, keyErr := starknetutils.HexToFelt(*a.GuardianPublicKey); keyErr != nil {
+				err = errors.Join(err, config.ErrInvalid{Name: fmt.Sprintf("Accounts.%d.GuardianPublicKey", i), Value: *a.GuardianPublicKey, Msg: keyErr.Error()})
+			} else if !flavour.HasGuardian() {
+				err = errors.Join(err, config.ErrInvalid{Name: fmt.Sprintf("Accounts.%d.GuardianPublicKey", i), Value: *a.GuardianPublicKey, Msg: "only Argent and Cairo0 accounts have a guardian"})
+			}
+		}
+	}
+	return
+}
+
 func legacyNode(n *Node, id string) db.Node {
 	var apiKey string
 	if n.APIKey == nil {
@@ -252,6 +799,146 @@ func (c *TOMLConfig) ConfirmationPoll() time.Duration {
 	return c.Chain.ConfirmationPoll.Duration()
 }
 
+func (c *TOMLConfig) ConfirmationBatchSize() uint32 {
+	return *c.Chain.ConfirmationBatchSize
+}
+
+func (c *TOMLConfig) FinalityLevel() string {
+	return *c.Chain.FinalityLevel
+}
+
+func (c *TOMLConfig) RebroadcastTimeout() time.Duration {
+	return c.Chain.RebroadcastTimeout.Duration()
+}
+
+func (c *TOMLConfig) FeeBumpPercent() uint32 {
+	return *c.Chain.FeeBumpPercent
+}
+
+func (c *TOMLConfig) MaxFeeBumps() uint32 {
+	return *c.Chain.MaxFeeBumps
+}
+
+func (c *TOMLConfig) MaxQueueLenPerAccount() uint32 {
+	return *c.Chain.MaxQueueLenPerAccount
+}
+
+func (c *TOMLConfig) BroadcastWorkers() uint32 {
+	return *c.Chain.BroadcastWorkers
+}
+
+func (c *TOMLConfig) BatchMaxCalls() uint32 {
+	return *c.Chain.BatchMaxCalls
+}
+
+func (c *TOMLConfig) BatchMaxCalldataLen() uint32 {
+	return *c.Chain.BatchMaxCalldataLen
+}
+
+func (c *TOMLConfig) SimulateTxs() bool {
+	return *c.Chain.SimulateTxs
+}
+
+// FeeToken returns the address of the ERC20 token that is transferred by Transact and pays the fees of txs.
+func (c *TOMLConfig) FeeToken() string {
+	return *c.Chain.FeeToken
+}
+
+func (c *TOMLConfig) FeeEstimator() fees.Config {
+	return c.Chain.FeeEstimator.config()
+}
+
+func (c *TOMLConfig) AccountClass() string {
+	return *c.Chain.AccountClass
+}
+
+func (c *TOMLConfig) AccountClassHash() *felt.Felt {
+	if c.Chain.AccountClassHash == nil {
+		return nil
+	}
+	// validated by ValidateConfig
+	classHash, _ := starknetutils.HexToFelt(*c.Chain.AccountClassHash)
+	return classHash
+}
+
+func (c *TOMLConfig) ReplaceSelectors() []string {
+	if c.Chain.ReplaceSelectors == nil {
+		return nil
+	}
+	return *c.Chain.ReplaceSelectors
+}
+
+func (c *TOMLConfig) SponsorAccount() *felt.Felt {
+	if c.Chain.SponsorAccount == nil {
+		return nil
+	}
+	// validated by ValidateConfig
+	sponsor, _ := starknetutils.HexToFelt(*c.Chain.SponsorAccount)
+	return sponsor
+}
+
+func (c *TOMLConfig) SponsorPublicKey() *felt.Felt {
+	if c.Chain.SponsorPublicKey == nil {
+		return nil
+	}
+	// validated by ValidateConfig
+	publicKey, _ := starknetutils.HexToFelt(*c.Chain.SponsorPublicKey)
+	return publicKey
+}
+
+func (c *TOMLConfig) AccountFlavour(accountAddress *felt.Felt) string {
+	a := c.Accounts.find(accountAddress)
+	if a == nil || a.Flavour == nil {
+		return ""
+	}
+	return *a.Flavour
+}
+
+func (c *TOMLConfig) AccountGuardian(accountAddress *felt.Felt) *felt.Felt {
+	a := c.Accounts.find(accountAddress)
+	if a == nil || a.GuardianPublicKey == nil {
+		return nil
+	}
+	// validated by ValidateConfig
+	guardian, _ := starknetutils.HexToFelt(*a.GuardianPublicKey)
+	return guardian
+}
+
+func (c *TOMLConfig) AutoDeployAccounts() bool {
+	return *c.Chain.AutoDeployAccounts
+}
+
+func (c *TOMLConfig) BroadcastMaxAttempts() uint32 {
+	return *c.Chain.BroadcastMaxAttempts
+}
+
+func (c *TOMLConfig) BroadcastRetryBackoff() time.Duration {
+	return c.Chain.BroadcastRetryBackoff.Duration()
+}
+
+func (c *TOMLConfig) TxTTL() time.Duration {
+	return c.Chain.TxTTL.Duration()
+}
+
+func (c *TOMLConfig) BalancePollInterval() time.Duration {
+	return c.Chain.BalancePollInterval.Duration()
+}
+
+func (c *TOMLConfig) DrainTimeout() time.Duration {
+	return c.Chain.DrainTimeout.Duration()
+}
+
+func (c *TOMLConfig) FeeLedgerRetention() time.Duration {
+	return c.Chain.FeeLedgerRetention.Duration()
+}
+
+func (c *TOMLConfig) TxStoragePath() string {
+	if c.Chain.TxStoragePath == nil {
+		return ""
+	}
+	return *c.Chain.TxStoragePath
+}
+
 func (c *TOMLConfig) OCR2CachePollPeriod() time.Duration {
 	return c.Chain.OCR2CachePollPeriod.Duration()
 }
@@ -264,6 +951,18 @@ func (c *TOMLConfig) RequestTimeout() time.Duration {
 	return c.Chain.RequestTimeout.Duration()
 }
 
+func (c *TOMLConfig) NodePollInterval() time.Duration {
+	return c.Chain.NodePollInterval.Duration()
+}
+
+func (c *TOMLConfig) NodeSyncThreshold() uint32 {
+	return *c.Chain.NodeSyncThreshold
+}
+
+func (c *TOMLConfig) DefaultBlockTag() string {
+	return *c.Chain.DefaultBlockTag
+}
+
 func (c *TOMLConfig) ListNodes() ([]db.Node, error) {
 	var allNodes []db.Node
 	for _, n := range c.Nodes {
@@ -95,15 +95,21 @@ func (c *contractTransmitter) Transmit(
 		return err
 	}
 
-	err = c.txm.Enqueue(ctx, c.accountAddress, c.senderAddress, starknetrpc.FunctionCall{
+	// the report context is used as the tx ID, so that a report can be traced to its tx and is never transmitted twice
+	_, err = c.txm.Enqueue(ctx, c.accountAddress, c.senderAddress, starknetrpc.FunctionCall{
 		ContractAddress:    c.contractAddress,
 		EntryPointSelector: starknetutils.GetSelectorFromNameFelt("transmit"),
 		Calldata:           calldata,
-	})
+	}, TransmitTxID(reportCtx), txm.TxOpts{Priority: txm.PriorityNormal, Caller: c.contractAddress.String()})
 
 	return err
 }
 
+// TransmitTxID returns the txm tx ID of the transmission of the report with the given context.
+func TransmitTxID(reportCtx types.ReportContext) string {
+	return fmt.Sprintf("transmit-%s-%d-%d", reportCtx.ConfigDigest.Hex(), reportCtx.Epoch, reportCtx.Round)
+}
+
 func (c *contractTransmitter) LatestConfigDigestAndEpoch(
 	ctx context.Context,
 ) (
@@ -0,0 +1,138 @@
+package ocr2
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	adapters "github.com/smartcontractkit/chainlink-common/pkg/loop/adapters/starknet"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2/reportingplugin/median"
+	"github.com/smartcontractkit/libocr/offchainreporting2/types"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/ocr2/medianreport"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet/starknettest"
+)
+
+// fakeKeystore signs every hash of publicKey with the same signature, which the test server does not verify.
+type fakeKeystore struct {
+	publicKey string
+}
+
+func (ks *fakeKeystore) Sign(ctx context.Context, account string, data []byte) ([]byte, error) {
+	if account != ks.publicKey {
+		return nil, errors.New("unknown key")
+	}
+	if data == nil {
+		// key existence check
+		return nil, nil
+	}
+	sig, err := adapters.SignatureFromBigInts(big.NewInt(7), big.NewInt(11))
+	if err != nil {
+		return nil, err
+	}
+	return sig.Bytes()
+}
+
+func (ks *fakeKeystore) Accounts(ctx context.Context) ([]string, error) {
+	return []string{ks.publicKey}, nil
+}
+
+// TestContractTransmitter_Transmit transmits a report through the txm to an aggregator of the test server, and
+// reads the transmission back with the ocr2 client.
+func TestContractTransmitter_Transmit(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	lggr := logger.Test(t)
+	server := starknettest.NewServer(t, "SN_SEPOLIA")
+
+	accountAddress := new(felt.Felt).SetUint64(0xacc)
+	publicKey := new(felt.Felt).SetUint64(0x123)
+	server.AddAccount(accountAddress)
+	server.FeeToken(starknetrpc.UnitStrk).Mint(accountAddress, new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+
+	aggregatorAddress := new(felt.Felt).SetUint64(0xa66)
+	aggregator := server.DeployAggregator(aggregatorAddress)
+	digest := types.ConfigDigest{0x00, 0x04, 0xaa, 0xbb}
+	require.NoError(t, aggregator.SetConfig(starknettest.AggregatorConfig{
+		ConfigDigest:  digest,
+		Signers:       [][]byte{{1}},
+		Transmitters:  []*felt.Felt{accountAddress},
+		F:             1,
+		OnchainConfig: []*felt.Felt{new(felt.Felt).SetUint64(1), new(felt.Felt), new(felt.Felt).SetUint64(1_000_000)},
+	}))
+
+	timeout := 5 * time.Second
+	client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", lggr, &timeout)
+	require.NoError(t, err)
+
+	cfg := txm.NewTestConfig(t, nil)
+
+	getClient := func() (*starknet.Client, error) { return client, nil }
+	getFeederClient := func() (*starknet.FeederClient, error) { return nil, errors.New("no feeder client") }
+	txManager, err := txm.New(lggr, "SN_SEPOLIA", &fakeKeystore{publicKey: publicKey.String()}, cfg, getClient, getFeederClient)
+	require.NoError(t, err)
+	require.NoError(t, txManager.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txManager.Close()) })
+
+	now := uint32(time.Now().Unix())
+	var observations []median.ParsedAttributedObservation
+	for i, v := range []int64{10, 30, 20} {
+		observations = append(observations, median.ParsedAttributedObservation{
+			Timestamp:        now,
+			Value:            big.NewInt(v),
+			JuelsPerFeeCoin:  big.NewInt(2),
+			GasPriceSubunits: big.NewInt(3),
+			Observer:         commontypes.OracleID(i),
+		})
+	}
+	report, err := medianreport.ReportCodec{}.BuildReport(ctx, observations)
+	require.NoError(t, err)
+	reportCtx := types.ReportContext{ReportTimestamp: types.ReportTimestamp{ConfigDigest: digest, Epoch: 1, Round: 2}}
+	sigs := []types.AttributedOnchainSignature{{Signature: make([]byte, 96), Signer: 0}}
+
+	transmitter := NewContractTransmitter(nil, aggregatorAddress.String(), publicKey.String(), accountAddress.String(), txManager)
+	require.NoError(t, transmitter.Transmit(ctx, reportCtx, report, sigs))
+
+	require.Eventually(t, func() bool {
+		r, err := txManager.GetTransaction(ctx, TransmitTxID(reportCtx))
+		return err == nil && r.State == txm.TxConfirmed
+	}, 10*time.Second, 50*time.Millisecond)
+
+	ocr2Client, err := NewClient(client, lggr)
+	require.NoError(t, err)
+
+	details, err := ocr2Client.LatestTransmissionDetails(ctx, aggregatorAddress)
+	require.NoError(t, err)
+	assert.Equal(t, digest, details.Digest)
+	assert.Equal(t, uint32(1), details.Epoch)
+	assert.Equal(t, uint8(2), details.Round)
+	assert.Equal(t, big.NewInt(20), details.LatestAnswer)
+	assert.Equal(t, int64(now), details.LatestTimestamp.Unix())
+
+	round := aggregator.LatestRound()
+	events, err := ocr2Client.NewTransmissionsFromEventsAt(ctx, aggregatorAddress, round.BlockNumber)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint32(1), events[0].RoundId)
+	assert.Equal(t, big.NewInt(20), events[0].LatestAnswer)
+	assert.Equal(t, accountAddress, events[0].Transmitter)
+	assert.Equal(t, []*big.Int{big.NewInt(10), big.NewInt(20), big.NewInt(30)}, events[0].Observations)
+	assert.Equal(t, digest, events[0].ConfigDigest)
+
+	// the report is not transmitted again
+	require.NoError(t, transmitter.Transmit(ctx, reportCtx, report, sigs))
+	assert.Equal(t, 1, server.Requests("starknet_addInvokeTransaction"))
+}
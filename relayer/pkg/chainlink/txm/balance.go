@@ -0,0 +1,236 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/erc20"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// ErrInsufficientBalance is returned when the fee token balance of an account cannot cover the max fee of its next
+// invoke. The account is paused: its txs stay queued, without using up broadcast attempts, until a balance check
+// finds that it has been funded.
+var ErrInsufficientBalance = errors.New("insufficient fee token balance")
+
+// pausedAccount is an account whose broadcasts are paused by the balance guard.
+type pausedAccount struct {
+	accountAddress *felt.Felt
+	// balance is nil if the node rejected the invoke before the balance was known
+	balance *big.Int
+	maxFee  *big.Int
+	since   time.Time
+}
+
+func (p pausedAccount) err() error {
+	if p.balance == nil {
+		return fmt.Errorf("%w: max fee %s of account %s rejected by node", ErrInsufficientBalance, p.maxFee, p.accountAddress)
+	}
+	return fmt.Errorf("%w: balance %s of account %s is below max fee %s", ErrInsufficientBalance, p.balance, p.accountAddress, p.maxFee)
+}
+
+// balanceGuard tracks the accounts that are paused for an insufficient balance.
+type balanceGuard struct {
+	lock   sync.RWMutex
+	paused map[string]pausedAccount
+}
+
+func newBalanceGuard() *balanceGuard {
+	return &balanceGuard{paused: map[string]pausedAccount{}}
+}
+
+func (g *balanceGuard) pause(p pausedAccount) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.paused[p.accountAddress.String()] = p
+}
+
+func (g *balanceGuard) isPaused(accountAddress *felt.Felt) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	_, ok := g.paused[accountAddress.String()]
+	return ok
+}
+
+// setBalance updates the last known balance of a paused account.
+func (g *balanceGuard) setBalance(accountAddress *felt.Felt, balance *big.Int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if p, ok := g.paused[accountAddress.String()]; ok {
+		p.balance = balance
+		g.paused[accountAddress.String()] = p
+	}
+}
+
+// resume returns false if the account was not paused.
+func (g *balanceGuard) resume(accountAddress *felt.Felt) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	_, ok := g.paused[accountAddress.String()]
+	delete(g.paused, accountAddress.String())
+	return ok
+}
+
+func (g *balanceGuard) list() []pausedAccount {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	paused := make([]pausedAccount, 0, len(g.paused))
+	for _, p := range g.paused {
+		paused = append(paused, p)
+	}
+	return paused
+}
+
+// payer returns the account that pays the fees of accountAddress, the SponsorAccount if one is configured.
+func (txm *starktxm) payer(accountAddress *felt.Felt) *felt.Felt {
+	if sponsor := txm.cfg.SponsorAccount(); sponsor != nil {
+		return sponsor
+	}
+	return accountAddress
+}
+
+// EstimateMaxFee returns the max fee of an invoke of call by accountAddress, computed like the fees of a broadcast.
+func (txm *starktxm) EstimateMaxFee(ctx context.Context, accountAddress *felt.Felt, publicKey *felt.Felt, call starknetrpc.FunctionCall) (*big.Int, error) {
+	if txm.cfg.SponsorAccount() != nil {
+		return new(big.Int), nil
+	}
+	client, err := txm.client.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %+w", err)
+	}
+	account, err := txm.newAccount(client, accountAddress, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := newInvokeTx(account, txm.accountFlavour(accountAddress), []starknetrpc.FunctionCall{call})
+	if err != nil {
+		return nil, err
+	}
+	estimate, _, err := txm.estimateFee(ctx, client, accountAddress, tx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %+w", errEstimateFailed, err)
+	}
+	params, err := txm.invokeFees(ctx, client, tx, estimate, 0)
+	if err != nil {
+		return nil, err
+	}
+	return params.MaxFee(), nil
+}
+
+// balanceOf returns the fee token balance of the payer of accountAddress.
+func (txm *starktxm) balanceOf(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt) (*big.Int, error) {
+	accountAddress = txm.payer(accountAddress)
+	return txm.feeTokenBalance(ctx, client, txm.FeeToken(accountAddress), txm.feeUnit(accountAddress), accountAddress)
+}
+
+// feeTokenBalance returns the balance of accountAddress in feeToken, whose amounts are in unit.
+func (txm *starktxm) feeTokenBalance(ctx context.Context, client *starknet.Client, feeToken string, unit starknetrpc.FeePaymentUnit, accountAddress *felt.Felt) (*big.Int, error) {
+	token, err := starknetutils.HexToFelt(feeToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee token %q: %w", feeToken, err)
+	}
+	erc20Client, err := erc20.NewClient(client, txm.lggr, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create erc20 client: %w", err)
+	}
+	balance, err := erc20Client.BalanceOf(ctx, accountAddress)
+	if err != nil {
+		return nil, err
+	}
+	balanceFloat, _ := new(big.Float).SetInt(balance).Float64()
+	promAccountBalance.WithLabelValues(txm.chainID, accountAddress.String(), string(unit)).Set(balanceFloat)
+	return balance, nil
+}
+
+// checkBalance pauses accountAddress and returns ErrInsufficientBalance if the balance of its payer is below maxFee. A failed
+// balance lookup does not block the broadcast, the node rejects an underfunded invoke anyways.
+func (txm *starktxm) checkBalance(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, maxFee *big.Int) error {
+	if txm.cfg.BalancePollInterval() == 0 {
+		return nil
+	}
+	balance, err := txm.balanceOf(ctx, client, accountAddress)
+	if err != nil {
+		txm.lggr.Warnw("failed to check account balance", "accountAddress", accountAddress, "error", err)
+		return nil
+	}
+	if balance.Cmp(maxFee) >= 0 {
+		return nil
+	}
+	return txm.pauseAccount(pausedAccount{accountAddress: accountAddress, balance: balance, maxFee: maxFee, since: time.Now()})
+}
+
+// pauseAccount stops scheduling the txs of an account, and returns the ErrInsufficientBalance describing why.
+func (txm *starktxm) pauseAccount(p pausedAccount) error {
+	txm.balances.pause(p)
+	txm.queues.pause(p.accountAddress)
+	promAccountPaused.WithLabelValues(txm.chainID, p.accountAddress.String()).Set(1)
+	err := p.err()
+	txm.lggr.Errorw("pausing broadcasts of account", "accountAddress", p.accountAddress, "error", err)
+	return err
+}
+
+// isInsufficientBalance returns true if the node rejected an invoke because the balance of the account is below
+// its max fee.
+func isInsufficientBalance(err error) bool {
+	var rpcErr *starknetrpc.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == starknetrpc.ErrInsufficientAccountBalance.Code
+}
+
+// balanceLoop periodically checks the balances of paused accounts, and resumes the accounts that can pay the max
+// fee of their last attempt.
+func (txm *starktxm) balanceLoop() {
+	defer txm.done.Done()
+
+	ctx, cancel := utils.ContextFromChan(txm.stop)
+	defer cancel()
+
+	tick := time.NewTicker(txm.cfg.BalancePollInterval())
+	defer tick.Stop()
+	for {
+		select {
+		case <-txm.stop:
+			return
+		case <-tick.C:
+		}
+		paused := txm.balances.list()
+		if len(paused) == 0 {
+			continue
+		}
+		client, err := txm.client.Get()
+		if err != nil {
+			txm.lggr.Errorw("failed to fetch client: skipping balance checks", "error", err)
+			continue
+		}
+		for _, p := range paused {
+			balance, err := txm.balanceOf(ctx, client, p.accountAddress)
+			if err != nil {
+				txm.lggr.Warnw("failed to check balance of paused account", "accountAddress", p.accountAddress, "error", err)
+				continue
+			}
+			if balance.Cmp(p.maxFee) < 0 {
+				txm.balances.setBalance(p.accountAddress, balance)
+				continue
+			}
+			txm.resumeAccount(p.accountAddress, balance)
+		}
+	}
+}
+
+func (txm *starktxm) resumeAccount(accountAddress *felt.Felt, balance *big.Int) {
+	if !txm.balances.resume(accountAddress) {
+		return
+	}
+	txm.queues.resume(accountAddress)
+	promAccountPaused.WithLabelValues(txm.chainID, accountAddress.String()).Set(0)
+	txm.lggr.Infow("resuming broadcasts of funded account", "accountAddress", accountAddress, "balance", balance)
+}
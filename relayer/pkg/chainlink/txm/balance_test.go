@@ -0,0 +1,110 @@
+package txm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+func TestTxm_BalanceGuard(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	account := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+
+	var balance atomic.Int64
+	balance.Store(100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.Unmarshal(body, &req))
+		var result string
+		switch req.Method {
+		case "starknet_chainId":
+			result = `"result": "0x534e5f5345504f4c4941"`
+		case "starknet_call":
+			// u256 balance as (low, high)
+			result = fmt.Sprintf(`"result": ["0x%x", "0x0"]`, balance.Load())
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, %s}`, req.ID, result)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	txm := newTestTxm(t, publicKey)
+	cfg := txm.cfg.(*mocks.Config)
+	cfg.On("FeeToken").Return("0x4718f5a0fc34cc1af16a1cdee98ffb20c31f5cd61d6ab07201858f4287c938d")
+	cfg.On("BalancePollInterval").Return(10 * time.Millisecond)
+	txm.client = utils.NewLazyLoad(func() (*starknet.Client, error) {
+		return starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+	})
+	client, err := txm.client.Get()
+	require.NoError(t, err)
+
+	require.NoError(t, txm.checkBalance(ctx, client, account, big.NewInt(100)))
+	assert.Len(t, txm.HealthReport(), 1)
+	assert.InDelta(t, 100, testutil.ToFloat64(promAccountBalance.WithLabelValues(txm.chainID, account.String(), string(starknetrpc.UnitStrk))), 0)
+
+	// an underfunded account is paused, its txs stay queued
+	id, err := txm.Enqueue(ctx, account, publicKey, starknetrpc.FunctionCall{
+		ContractAddress:    new(felt.Felt).SetUint64(3),
+		EntryPointSelector: new(felt.Felt).SetUint64(4),
+	}, "", TxOpts{})
+	require.NoError(t, err)
+	aq, batch, ok := txm.queues.next(1, 0)
+	require.True(t, ok)
+	err = txm.checkBalance(ctx, client, account, big.NewInt(101))
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Equal(t, broadcastErrInsufficientFunds, broadcastErrorClass(err))
+	txm.queues.requeue(aq, batch)
+	txm.queues.done(aq)
+	_, _, ok = txm.queues.next(1, 0)
+	assert.False(t, ok)
+
+	report := txm.HealthReport()
+	require.Len(t, report, 2)
+	accountErr := report[fmt.Sprintf("%s.Account.%s", txm.Name(), account)]
+	require.ErrorIs(t, accountErr, ErrInsufficientBalance)
+	assert.Contains(t, accountErr.Error(), "balance 100")
+	assert.InDelta(t, 1, testutil.ToFloat64(promAccountPaused.WithLabelValues(txm.chainID, account.String())), 0)
+
+	// the account resumes once funded
+	txm.done.Add(1)
+	go txm.balanceLoop()
+	t.Cleanup(func() {
+		close(txm.stop)
+		txm.done.Wait()
+	})
+	balance.Store(101)
+	require.Eventually(t, func() bool { return len(txm.HealthReport()) == 1 }, tests.WaitTimeout(t), 10*time.Millisecond)
+	assert.InDelta(t, 0, testutil.ToFloat64(promAccountPaused.WithLabelValues(txm.chainID, account.String())), 0)
+	_, batch, ok = txm.queues.next(1, 0)
+	require.True(t, ok)
+	assert.Equal(t, []string{id}, txIDs(batch))
+}
@@ -0,0 +1,52 @@
+package txm
+
+import (
+	"slices"
+
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+)
+
+// takeBatch splits the txs to broadcast next off the front of an account queue: the first tx, followed by as many
+// of the next txs signed by the same key as fit into maxCalls calls and maxCalldataLen felts of multicall calldata
+// in the layout of flavour (0 means no limit). Calls are never reordered: the batch ends at the first call that
+// does not fit. Batching is disabled if maxCalls <= 1.
+func takeBatch(txs []Tx, flavour AccountFlavour, maxCalls, maxCalldataLen int) (batch []Tx, rest []Tx) {
+	n := 1
+	calldataLen := multicallHeaderLen(flavour) + multicallLen(flavour, txs[0].call)
+	for ; n < len(txs) && n < maxCalls; n++ {
+		next := txs[n]
+		if !next.publicKey.Equal(txs[0].publicKey) {
+			break
+		}
+		nextLen := calldataLen + multicallLen(flavour, next.call)
+		if maxCalldataLen > 0 && nextLen > maxCalldataLen {
+			break
+		}
+		calldataLen = nextLen
+	}
+	return slices.Clone(txs[:n]), txs[n:]
+}
+
+// multicallHeaderLen is the number of calldata felts of a multicall besides its calls, see FmtCalldata
+func multicallHeaderLen(flavour AccountFlavour) int {
+	if flavour.cairoVersion() == 0 {
+		return 2 // call array length, calldata length
+	}
+	return 1 // array length
+}
+
+// multicallLen is the number of calldata felts that a call takes up in a multicall of an account of flavour
+func multicallLen(flavour AccountFlavour, call starknetrpc.FunctionCall) int {
+	if flavour.cairoVersion() == 0 {
+		return 4 + len(call.Calldata) // to, selector, data offset, data length, and calldata in the shared array
+	}
+	return 3 + len(call.Calldata) // to, selector, calldata length, calldata
+}
+
+func txIDs(txs []Tx) []string {
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.id
+	}
+	return ids
+}
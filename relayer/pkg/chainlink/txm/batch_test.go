@@ -0,0 +1,121 @@
+package txm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetaccount "github.com/NethermindEth/starknet.go/account"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTx(id string, account *felt.Felt, publicKey *felt.Felt, calldataLen int) Tx {
+	return Tx{
+		id:             id,
+		publicKey:      publicKey,
+		accountAddress: account,
+		call: starknetrpc.FunctionCall{
+			ContractAddress:    new(felt.Felt).SetUint64(4),
+			EntryPointSelector: new(felt.Felt).SetUint64(5),
+			Calldata:           make([]*felt.Felt, calldataLen),
+		},
+	}
+}
+
+func TestTakeBatch(t *testing.T) {
+	t.Parallel()
+
+	account := new(felt.Felt).SetUint64(1)
+	keyA := new(felt.Felt).SetUint64(2)
+	keyB := new(felt.Felt).SetUint64(3)
+
+	var txs []Tx
+	for i := 0; i < 4; i++ {
+		txs = append(txs, newTestTx(fmt.Sprintf("%d", i), account, keyA, 1))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		batch, rest := takeBatch(txs, FlavourOZ, 1, 0)
+		assert.Equal(t, []string{"0"}, txIDs(batch))
+		assert.Equal(t, []string{"1", "2", "3"}, txIDs(rest))
+	})
+
+	t.Run("max calls", func(t *testing.T) {
+		t.Parallel()
+
+		batch, rest := takeBatch(txs, FlavourOZ, 3, 0)
+		assert.Equal(t, []string{"0", "1", "2"}, txIDs(batch))
+		assert.Equal(t, []string{"3"}, txIDs(rest))
+
+		batch, rest = takeBatch(rest, FlavourOZ, 3, 0)
+		assert.Equal(t, []string{"3"}, txIDs(batch))
+		assert.Empty(t, rest)
+	})
+
+	t.Run("public key", func(t *testing.T) {
+		t.Parallel()
+
+		mixed := []Tx{txs[0], newTestTx("b", account, keyB, 1), txs[1]}
+		batch, rest := takeBatch(mixed, FlavourOZ, 10, 0)
+		assert.Equal(t, []string{"0"}, txIDs(batch))
+		assert.Equal(t, []string{"b", "1"}, txIDs(rest))
+	})
+
+	t.Run("calldata length", func(t *testing.T) {
+		t.Parallel()
+
+		// 1 + (3+2) + (3+2) = 11 felts fit, the large call does not and ends the batch
+		queue := []Tx{
+			newTestTx("a", account, keyA, 2),
+			newTestTx("b", account, keyA, 2),
+			newTestTx("large", account, keyA, 10),
+			newTestTx("c", account, keyA, 1),
+		}
+		batch, rest := takeBatch(queue, FlavourOZ, 10, 12)
+		assert.Equal(t, []string{"a", "b"}, txIDs(batch))
+		assert.Equal(t, []string{"large", "c"}, txIDs(rest))
+
+		// a single call larger than the limit is still broadcast
+		batch, rest = takeBatch(rest, FlavourOZ, 10, 12)
+		assert.Equal(t, []string{"large"}, txIDs(batch))
+		assert.Equal(t, []string{"c"}, txIDs(rest))
+	})
+
+	t.Run("cairo 0 calldata length", func(t *testing.T) {
+		t.Parallel()
+
+		// 2 + (4+2) + (4+2) = 14 felts, one felt more per call than the Cairo 1 layout
+		queue := []Tx{newTestTx("a", account, keyA, 2), newTestTx("b", account, keyA, 2)}
+		batch, rest := takeBatch(queue, FlavourCairo0, 10, 13)
+		assert.Equal(t, []string{"a"}, txIDs(batch))
+		assert.Equal(t, []string{"b"}, txIDs(rest))
+
+		batch, rest = takeBatch(queue, FlavourCairo0, 10, 14)
+		assert.Equal(t, []string{"a", "b"}, txIDs(batch))
+		assert.Empty(t, rest)
+	})
+}
+
+func TestMulticallLen(t *testing.T) {
+	t.Parallel()
+
+	calls := []starknetrpc.FunctionCall{
+		newTestTx("a", nil, nil, 0).call,
+		newTestTx("b", nil, nil, 3).call,
+		newTestTx("c", nil, nil, 1).call,
+	}
+	for _, flavour := range []AccountFlavour{FlavourOZ, FlavourCairo0} {
+		account := &starknetaccount.Account{CairoVersion: flavour.cairoVersion()}
+		calldata, err := account.FmtCalldata(calls)
+		require.NoError(t, err)
+		calldataLen := multicallHeaderLen(flavour)
+		for _, call := range calls {
+			calldataLen += multicallLen(flavour, call)
+		}
+		assert.Len(t, calldata, calldataLen, flavour)
+	}
+}
@@ -1,11 +1,76 @@
 package txm
 
-import "time"
+import (
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+)
 
 //go:generate mockery --name Config --output ./mocks/ --case=underscore --filename config.go
 
 // txm config
 type Config interface {
 	ConfirmationPoll() time.Duration
+	// ConfirmationBatchSize is the maximum number of tx status requests sent in a single JSON-RPC batch, 0 disables
+	// batching
+	ConfirmationBatchSize() uint32
+	// FinalityLevel is the FinalityLevel at which txs are confirmed
+	FinalityLevel() string
 	TxTimeout() time.Duration
+	// RebroadcastTimeout is how long a tx may stay unconfirmed before it is rebroadcast with a higher fee, 0 disables rebroadcasting
+	RebroadcastTimeout() time.Duration
+	// FeeBumpPercent is the percentage by which the max gas price and tip are raised on each rebroadcast
+	FeeBumpPercent() uint32
+	// MaxFeeBumps is the number of times a stuck tx is rebroadcast before giving up
+	MaxFeeBumps() uint32
+	// MaxQueueLenPerAccount is the maximum number of txs queued for broadcast per account
+	MaxQueueLenPerAccount() uint32
+	// BroadcastWorkers is the number of accounts whose txs are broadcast concurrently
+	BroadcastWorkers() uint32
+	// BatchMaxCalls is the maximum number of queued calls of an account combined into a single multicall invoke,
+	// batching is disabled if <= 1
+	BatchMaxCalls() uint32
+	// BatchMaxCalldataLen limits the total calldata length (in felts) of a multicall invoke, 0 means no limit
+	BatchMaxCalldataLen() uint32
+	FeeEstimator() fees.Config
+	// SimulateTxs enables simulating every signed invoke before broadcast, invokes that would revert are not broadcast
+	SimulateTxs() bool
+	// AccountClass is the AccountClass deployed for keystore keys, see DeployAccount
+	AccountClass() string
+	// AccountClassHash overrides the well-known class hash of AccountClass if not nil
+	AccountClassHash() *felt.Felt
+	// AccountFlavour is the AccountFlavour of accountAddress, empty for the default OpenZeppelin flavour
+	AccountFlavour(accountAddress *felt.Felt) string
+	// AccountGuardian is the keystore key that co-signs the invokes of a guardian-protected accountAddress, nil if
+	// the account has no guardian
+	AccountGuardian(accountAddress *felt.Felt) *felt.Felt
+	// AutoDeployAccounts enables deploying an undeployed account before its first invoke
+	AutoDeployAccounts() bool
+	// BroadcastMaxAttempts is the number of times a tx is broadcast before it is moved to the dead letters, failed
+	// broadcasts are retried after BroadcastRetryBackoff, which doubles with every attempt
+	BroadcastMaxAttempts() uint32
+	BroadcastRetryBackoff() time.Duration
+	// TxTTL is how long after enqueue a queued or unconfirmed tx is abandoned to the dead letters, 0 disables expiry
+	TxTTL() time.Duration
+	// FeeToken is the address of the ERC20 token that pays tx fees (STRK)
+	FeeToken() string
+	// BalancePollInterval is how often the fee token balances of accounts paused for an insufficient balance are
+	// checked, 0 disables the balance guard
+	BalancePollInterval() time.Duration
+	// DrainTimeout is how long Close waits for queued txs to be broadcast, 0 stops immediately
+	DrainTimeout() time.Duration
+	// FeeLedgerRetention is how long the actual fees of accepted txs are kept for FeeSpend
+	FeeLedgerRetention() time.Duration
+	// ReplaceSelectors are the entrypoints whose queued calls are replaced by a newer call of the same account to
+	// the same contract, e.g. "transmit" only keeps the latest report of a feed. Replaced txs are dead-lettered.
+	ReplaceSelectors() []string
+	// SponsorAccount is the account that pays the fees of all accounts if not nil. Their calls are signed as SNIP-9
+	// outside executions, which the sponsor invokes with its SponsorPublicKey.
+	SponsorAccount() *felt.Felt
+	SponsorPublicKey() *felt.Felt
+	// TxStoragePath is the file used to persist txs (and the fee ledger, with a .fees suffix) across restarts, txs
+	// are only kept in memory if empty
+	TxStoragePath() string
 }
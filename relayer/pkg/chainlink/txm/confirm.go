@@ -0,0 +1,136 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// txStatusResult is the outcome of a status request of a single tx hash.
+type txStatusResult struct {
+	status *starknetrpc.TxnStatusResp
+	err    error
+}
+
+// txReceiptResult is the outcome of a receipt request of a single tx hash.
+type txReceiptResult struct {
+	receipt *starknetrpc.TransactionReceiptWithBlockInfo
+	err     error
+}
+
+// fetchTxStatuses fetches the statuses of hashes, see fetchByHash.
+func (txm *starktxm) fetchTxStatuses(ctx context.Context, client *starknet.Client, hashes []string) map[string]txStatusResult {
+	results := make(map[string]txStatusResult, len(hashes))
+	fetch := func(ctx context.Context, f *felt.Felt) (any, error) {
+		return client.Provider.GetTransactionStatus(ctx, f)
+	}
+	txm.fetchByHash(ctx, client, hashes, starknet.BatchBuilder.RequestTxStatusByHash, fetch, func(hash string, result any, err error) {
+		if err != nil {
+			results[hash] = txStatusResult{err: err}
+			return
+		}
+		status, ok := result.(*starknetrpc.TxnStatusResp)
+		if !ok {
+			results[hash] = txStatusResult{err: fmt.Errorf("unexpected status type %T", result)}
+			return
+		}
+		results[hash] = txStatusResult{status: status}
+	})
+	return results
+}
+
+// fetchTxReceipts fetches the receipts of hashes, see fetchByHash.
+func (txm *starktxm) fetchTxReceipts(ctx context.Context, client *starknet.Client, hashes []string) map[string]txReceiptResult {
+	results := make(map[string]txReceiptResult, len(hashes))
+	fetch := func(ctx context.Context, f *felt.Felt) (any, error) {
+		return client.TransactionReceipt(ctx, f)
+	}
+	txm.fetchByHash(ctx, client, hashes, starknet.BatchBuilder.RequestTxReceiptByHash, fetch, func(hash string, result any, err error) {
+		if err != nil {
+			results[hash] = txReceiptResult{err: err}
+			return
+		}
+		var receipt *starknetrpc.TransactionReceiptWithBlockInfo
+		switch r := result.(type) {
+		case *starknet.ReceiptResult:
+			receipt = r.Receipt
+		case *starknetrpc.TransactionReceiptWithBlockInfo:
+			receipt = r
+		}
+		if receipt == nil {
+			results[hash] = txReceiptResult{err: fmt.Errorf("unexpected receipt %T", result)}
+			return
+		}
+		results[hash] = txReceiptResult{receipt: receipt}
+	})
+	return results
+}
+
+// fetchByHash sends a request per hash in JSON-RPC batches of up to ConfirmationBatchSize requests, so that a confirm
+// loop tick takes a single round-trip for most nodes, and passes the outcome of every request to handle. If the
+// batch size is 0 the requests are sent one by one with fetch instead.
+func (txm *starktxm) fetchByHash(ctx context.Context, client *starknet.Client, hashes []string,
+	request func(starknet.BatchBuilder, *felt.Felt) starknet.BatchBuilder,
+	fetch func(context.Context, *felt.Felt) (any, error),
+	handle func(hash string, result any, err error)) {
+	var valid []string
+	var felts []*felt.Felt
+	seen := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		f, err := starknetutils.HexToFelt(hash)
+		if err != nil {
+			handle(hash, nil, fmt.Errorf("invalid tx hash: %w", err))
+			continue
+		}
+		valid = append(valid, hash)
+		felts = append(felts, f)
+	}
+
+	batchSize := int(txm.cfg.ConfirmationBatchSize())
+	if batchSize == 0 {
+		for i, f := range felts {
+			result, err := fetch(ctx, f)
+			handle(valid[i], result, err)
+		}
+		return
+	}
+
+	for start := 0; start < len(felts); start += batchSize {
+		end := min(start+batchSize, len(felts))
+		builder := starknet.NewBatchBuilder()
+		for _, f := range felts[start:end] {
+			request(builder, f)
+		}
+		elems, err := client.Batch(ctx, builder)
+		for i, hash := range valid[start:end] {
+			switch {
+			case err != nil:
+				handle(hash, nil, err)
+			case elems[i].Error != nil:
+				handle(hash, nil, elems[i].Error)
+			default:
+				handle(hash, elems[i].Result, nil)
+			}
+		}
+	}
+}
+
+// isTxHashNotFound returns true if the node does not know the tx, both for single and batched requests.
+func isTxHashNotFound(err error) bool {
+	var rpcErr *starknetrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == starknetrpc.ErrHashNotFound.Code
+	}
+	var codeErr interface{ ErrorCode() int }
+	return errors.As(err, &codeErr) && codeErr.ErrorCode() == starknetrpc.ErrHashNotFound.Code
+}
@@ -0,0 +1,205 @@
+package txm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet/starknettest"
+)
+
+func TestTxm_FetchTxStatuses(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []string        `json:"params"`
+	}
+	// 0x1 is accepted, 0x2 is unknown to the node
+	respond := func(req request) string {
+		if req.Params[0] == "0x2" {
+			return fmt.Sprintf(`{"jsonrpc": "2.0", "id": %s, "error": {"code": 29, "message": "Transaction hash not found"}}`, req.ID)
+		}
+		return fmt.Sprintf(`{"jsonrpc": "2.0", "id": %s, "result": {"finality_status": "ACCEPTED_ON_L2", "execution_status": "SUCCEEDED"}}`, req.ID)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		batchSize  uint32
+		roundTrips int64
+	}{
+		{name: "unbatched", batchSize: 0, roundTrips: 3},
+		{name: "single batch", batchSize: 10, roundTrips: 1},
+		{name: "split batches", batchSize: 2, roundTrips: 2},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var roundTrips atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				roundTrips.Add(1)
+
+				var batch []request
+				if json.Unmarshal(body, &batch) != nil {
+					var req request
+					require.NoError(t, json.Unmarshal(body, &req))
+					_, err = io.WriteString(w, respond(req))
+					require.NoError(t, err)
+					return
+				}
+				responses := make([]string, len(batch))
+				for i, req := range batch {
+					assert.Equal(t, "starknet_getTransactionStatus", req.Method)
+					responses[i] = respond(req)
+				}
+				_, err = io.WriteString(w, "["+strings.Join(responses, ",")+"]")
+				require.NoError(t, err)
+			}))
+			t.Cleanup(server.Close)
+
+			txm := newTestTxm(t)
+			txm.cfg.(*mocks.Config).On("ConfirmationBatchSize").Return(tc.batchSize)
+			client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+			require.NoError(t, err)
+
+			statuses := txm.fetchTxStatuses(tests.Context(t), client, []string{"0x1", "0x2", "0x1", "not a hash", "0x3"})
+			assert.Equal(t, tc.roundTrips, roundTrips.Load())
+			require.Len(t, statuses, 4)
+			for _, hash := range []string{"0x1", "0x3"} {
+				require.NoError(t, statuses[hash].err)
+				assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L2, statuses[hash].status.FinalityStatus)
+			}
+			assert.ErrorContains(t, statuses["0x2"].err, "Transaction hash not found")
+			assert.ErrorContains(t, statuses["not a hash"].err, "invalid tx hash")
+		})
+	}
+}
+
+func TestTxm_Rebroadcast(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	account := new(felt.Felt).SetUint64(0xacc002)
+	publicKey := new(felt.Felt).SetUint64(2)
+	server := starknettest.NewServer(t, "SN_SEPOLIA")
+	server.AddAccount(account)
+	server.FeeToken(starknetrpc.UnitStrk).Mint(account, big.NewInt(1_000_000_000))
+	// the attempts stay pending until mined
+	server.SetAutoMine(false)
+
+	txm := newTestTxm(t, publicKey)
+	cfg := txm.cfg.(*mocks.Config)
+	cfg.On("TxTimeout").Return(time.Second)
+	cfg.On("SimulateTxs").Return(false)
+	cfg.On("BalancePollInterval").Return(time.Duration(0))
+	cfg.On("TxTTL").Return(time.Duration(0))
+	cfg.On("ConfirmationBatchSize").Return(uint32(0))
+	cfg.On("FinalityLevel").Return(string(FinalityAcceptedOnL2))
+	cfg.On("RebroadcastTimeout").Return(50 * time.Millisecond)
+	cfg.On("FeeBumpPercent").Return(uint32(20))
+	cfg.On("MaxFeeBumps").Return(uint32(2))
+	client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+	require.NoError(t, err)
+	txm.client = utils.NewLazyLoad(func() (*starknet.Client, error) { return client, nil })
+
+	id, err := txm.Enqueue(ctx, account, publicKey, starknetrpc.FunctionCall{
+		ContractAddress:    starknettest.STRKFeeToken,
+		EntryPointSelector: starknetutils.GetSelectorFromNameFelt("transfer"),
+		Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(3), new(felt.Felt).SetUint64(1), new(felt.Felt)},
+	}, "", TxOpts{})
+	require.NoError(t, err)
+	aq, batch, ok := txm.queues.next(1, 0)
+	require.True(t, ok)
+	_, err = txm.broadcast(ctx, batch)
+	require.NoError(t, err)
+	txm.queues.done(aq)
+
+	// check runs the confirmation of the unconfirmed tx of the account (see confirmLoop), and returns the tx
+	// afterwards, nil once confirmed
+	check := func() *UnconfirmedTx {
+		unconfirmed := txm.accountStore.GetTxStore(account).GetUnconfirmed()
+		require.Len(t, unconfirmed, 1)
+		txm.checkUnconfirmed(ctx, client, account, unconfirmed[0], txm.fetchTxStatuses(ctx, client, unconfirmed[0].Attempts))
+		if unconfirmed = txm.accountStore.GetTxStore(account).GetUnconfirmed(); len(unconfirmed) == 0 {
+			return nil
+		}
+		return unconfirmed[0]
+	}
+	invoke := func(hash string) (invoke starknetrpc.InvokeTxnV3) {
+		raw, ok := server.Transaction(starknetutils.TestHexToFelt(t, hash))
+		require.True(t, ok)
+		b, err := json.Marshal(raw)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b, &invoke))
+		return
+	}
+	price := func(u starknetrpc.U128) *big.Int {
+		v, ok := new(big.Int).SetString(string(u), 0)
+		require.True(t, ok)
+		return v
+	}
+
+	// not rebroadcast before RebroadcastTimeout
+	unconfirmed := check()
+	require.Len(t, unconfirmed.Attempts, 1)
+	assert.Equal(t, 1, server.Requests("starknet_addInvokeTransaction"))
+
+	time.Sleep(50 * time.Millisecond)
+	unconfirmed = check()
+	require.Len(t, unconfirmed.Attempts, 2)
+	first, second := invoke(unconfirmed.Attempts[0]), invoke(unconfirmed.Attempts[1])
+	assert.Equal(t, first.Nonce, second.Nonce)
+	l1Price := price(first.ResourceBounds.L1Gas.MaxPricePerUnit)
+	assert.Equal(t, new(big.Int).Div(new(big.Int).Mul(l1Price, big.NewInt(120)), big.NewInt(100)), price(second.ResourceBounds.L1Gas.MaxPricePerUnit))
+	assert.Equal(t, price(first.ResourceBounds.L2Gas.MaxPricePerUnit), price(second.ResourceBounds.L2Gas.MaxPricePerUnit))
+	assert.Equal(t, starknetrpc.U64("0x0"), first.Tip)
+	assert.Equal(t, starknetrpc.U64("0x1"), second.Tip)
+
+	// the bumps stop after MaxFeeBumps
+	for i := 0; i < 3; i++ {
+		time.Sleep(50 * time.Millisecond)
+		unconfirmed = check()
+	}
+	require.Len(t, unconfirmed.Attempts, 3)
+	assert.Equal(t, 3, server.Requests("starknet_addInvokeTransaction"))
+	third := invoke(unconfirmed.Attempts[2])
+	assert.Equal(t, first.Nonce, third.Nonce)
+	assert.Equal(t, new(big.Int).Div(new(big.Int).Mul(l1Price, big.NewInt(144)), big.NewInt(100)), price(third.ResourceBounds.L1Gas.MaxPricePerUnit))
+	record, err := txm.storage.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, unconfirmed.Attempts, record.Attempts)
+	assert.Equal(t, unconfirmed.Attempts[2], record.Hash)
+
+	// the first attempt is mined, which rejects the bumped attempts
+	server.Mine()
+	assert.Nil(t, check())
+	txm.done.Wait()
+	record, err = txm.storage.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxConfirmed, record.State)
+	assert.Equal(t, unconfirmed.Attempts[0], record.Hash)
+	assert.Equal(t, new(felt.Felt).SetUint64(1), server.Nonce(account))
+}
@@ -0,0 +1,364 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/juno/core/crypto"
+	"github.com/NethermindEth/juno/core/felt"
+	starknetaccount "github.com/NethermindEth/starknet.go/account"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// AccountClass is the account contract implementation that is deployed for keystore keys.
+type AccountClass string
+
+const (
+	// AccountClassOZ is the OpenZeppelin account (Cairo 1, v0.8.1), its constructor takes the public key
+	AccountClassOZ AccountClass = "OpenZeppelin"
+	// AccountClassArgent is the Argent account (Cairo 1, v0.3.1), its constructor takes the owner key and a guardian
+	AccountClassArgent AccountClass = "Argent"
+)
+
+var accountClassHashes = map[AccountClass]string{
+	AccountClassOZ:     "0x61dac032f228abef9c6626f995015233097ae253a7f72d68552db02f2971b8f",
+	AccountClassArgent: "0x29927c8af6bccf3f6fda035981e765a7bdbf18a2dc0d630494f8758aa908e2b",
+}
+
+var (
+	ErrAccountDeployed    = errors.New("account is already deployed")
+	ErrAccountNotDeployed = errors.New("account is not deployed")
+	// errDeployPending is returned by the broadcasts of an account whose auto deployment is not accepted yet
+	errDeployPending = errors.New("account deployment is pending")
+)
+
+var contractAddressPrefix = new(felt.Felt).SetBytes([]byte("STARKNET_CONTRACT_ADDRESS"))
+
+const (
+	// deployMisses is the number of consecutive polls for which the node may not know a broadcast DEPLOY_ACCOUNT
+	// before it is considered dropped
+	deployMisses = 5
+	// deployTimeout bounds the wait for a DEPLOY_ACCOUNT to be accepted if TxTTL is disabled
+	deployTimeout = 10 * time.Minute
+)
+
+// accountDeployment holds the DEPLOY_ACCOUNT parameters of a public key. The public key is used as salt, so that
+// every key has a single counterfactual address per class.
+type accountDeployment struct {
+	address             *felt.Felt
+	classHash           *felt.Felt
+	salt                *felt.Felt
+	constructorCalldata []*felt.Felt
+}
+
+func newAccountDeployment(class AccountClass, classHash *felt.Felt, publicKey *felt.Felt) (accountDeployment, error) {
+	var calldata []*felt.Felt
+	switch class {
+	case AccountClassOZ:
+		calldata = []*felt.Felt{publicKey}
+	case AccountClassArgent:
+		// no guardian
+		calldata = []*felt.Felt{publicKey, &felt.Zero}
+	default:
+		return accountDeployment{}, fmt.Errorf("unsupported account class %q", class)
+	}
+	if classHash == nil {
+		var err error
+		classHash, err = new(felt.Felt).SetString(accountClassHashes[class])
+		if err != nil {
+			return accountDeployment{}, err
+		}
+	}
+
+	// see https://docs.starknet.io/architecture-and-concepts/smart-contracts/contract-address/, a DEPLOY_ACCOUNT
+	// has no deployer so the caller address is 0
+	address := crypto.PedersenArray(
+		contractAddressPrefix,
+		&felt.Zero,
+		publicKey,
+		classHash,
+		crypto.PedersenArray(calldata...),
+	)
+	return accountDeployment{
+		address:             address,
+		classHash:           classHash,
+		salt:                publicKey,
+		constructorCalldata: calldata,
+	}, nil
+}
+
+func (txm *starktxm) accountDeployment(publicKey *felt.Felt) (accountDeployment, error) {
+	return newAccountDeployment(AccountClass(txm.cfg.AccountClass()), txm.cfg.AccountClassHash(), publicKey)
+}
+
+// AccountAddress returns the counterfactual address of the account of the configured class for publicKey.
+func (txm *starktxm) AccountAddress(publicKey *felt.Felt) (*felt.Felt, error) {
+	deployment, err := txm.accountDeployment(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return deployment.address, nil
+}
+
+// DeployAccount deploys the account of the configured class for publicKey and waits until the deployment is
+// accepted. The counterfactual address (see AccountAddress) has to be funded with STRK beforehand, otherwise
+// ErrInsufficientBalance is returned.
+func (txm *starktxm) DeployAccount(ctx context.Context, publicKey *felt.Felt) (string, error) {
+	if _, err := txm.ks.Loopp().Sign(ctx, publicKey.String(), nil); err != nil {
+		return "", fmt.Errorf("deploy account: failed to sign: %+w", err)
+	}
+	deployment, err := txm.accountDeployment(publicKey)
+	if err != nil {
+		return "", err
+	}
+	client, err := txm.client.Get()
+	if err != nil {
+		txm.client.Reset()
+		return "", fmt.Errorf("deploy account: failed to fetch client: %+w", err)
+	}
+	deployed, err := isDeployed(ctx, client, deployment.address)
+	if err != nil {
+		return "", err
+	}
+	if deployed {
+		return "", fmt.Errorf("%w: %s", ErrAccountDeployed, deployment.address)
+	}
+	account, hash, err := txm.deployAccount(ctx, client, deployment, publicKey)
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), txm.waitForAcceptance(ctx, account, hash)
+}
+
+// isDeployed looks up the class of the contract at address, an undeployed account has none.
+func isDeployed(ctx context.Context, client *starknet.Client, address *felt.Felt) (bool, error) {
+	_, err := client.Provider.ClassHashAt(ctx, starknetrpc.WithBlockTag(starknet.BlockTagPending), address)
+	if err == nil {
+		return true, nil
+	}
+	if isContractNotFound(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to get class hash of %s: %+w", address, err)
+}
+
+func isContractNotFound(err error) bool {
+	var rpcErr *starknetrpc.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == starknetrpc.ErrContractNotFound.Code
+}
+
+// autoDeployAccount deploys the account at accountAddress during the first broadcast of the account if it is
+// the counterfactual address of publicKey and AutoDeployAccounts is set. ErrInsufficientBalance is returned until
+// the account is funded. The broadcast worker does not wait for the deployment: errDeployPending is returned until
+// it is accepted, and the txs of the account are retried meanwhile.
+func (txm *starktxm) autoDeployAccount(ctx context.Context, client *starknet.Client, accountAddress, publicKey *felt.Felt) error {
+	if !txm.cfg.AutoDeployAccounts() {
+		return fmt.Errorf("%w: %s", ErrAccountNotDeployed, accountAddress)
+	}
+	if hash := txm.deploys.get(accountAddress); hash != nil {
+		return txm.checkDeploy(ctx, client, accountAddress, hash)
+	}
+	deployment, err := txm.accountDeployment(publicKey)
+	if err != nil {
+		return err
+	}
+	if !deployment.address.Equal(accountAddress) {
+		return fmt.Errorf("%w: %s is not the %s account of public key %s", ErrAccountNotDeployed, accountAddress, txm.cfg.AccountClass(), publicKey)
+	}
+	txm.lggr.Infow("deploying account", "accountAddress", accountAddress, "class", txm.cfg.AccountClass())
+	_, hash, err := txm.deployAccount(ctx, client, deployment, publicKey)
+	if err != nil {
+		return err
+	}
+	txm.deploys.add(accountAddress, hash)
+	return fmt.Errorf("%w: %s", errDeployPending, hash)
+}
+
+// checkDeploy returns nil once the auto deployment of accountAddress with the given hash is accepted, and
+// errDeployPending while it is in flight. A deployment that failed, or that the node has not known for deployMisses
+// checks, is forgotten so that the next broadcast deploys the account again.
+func (txm *starktxm) checkDeploy(ctx context.Context, client *starknet.Client, accountAddress, hash *felt.Felt) error {
+	status, err := client.Provider.GetTransactionStatus(ctx, hash)
+	if err != nil {
+		if isTxHashNotFound(err) && txm.deploys.miss(accountAddress) >= deployMisses {
+			txm.deploys.remove(accountAddress)
+			return fmt.Errorf("deployment %s dropped: not found for %d checks", hash, deployMisses)
+		}
+		return fmt.Errorf("%w: failed to fetch status of %s: %+w", errDeployPending, hash, err)
+	}
+	switch {
+	case status.FinalityStatus == starknetrpc.TxnStatus_Rejected:
+		txm.deploys.remove(accountAddress)
+		return fmt.Errorf("deployment %s rejected", hash)
+	case status.ExecutionStatus == starknetrpc.TxnExecutionStatusREVERTED:
+		txm.deploys.remove(accountAddress)
+		return fmt.Errorf("deployment %s reverted", hash)
+	case status.FinalityStatus == starknetrpc.TxnStatus_Accepted_On_L2 || status.FinalityStatus == starknetrpc.TxnStatus_Accepted_On_L1:
+		txm.deploys.remove(accountAddress)
+		txm.lggr.Infow("account deployed", "accountAddress", accountAddress, "txhash", hash)
+		return nil
+	}
+	return fmt.Errorf("%w: %s is %s", errDeployPending, hash, status.FinalityStatus)
+}
+
+// pendingDeploys tracks the hashes of the auto deployments that are not accepted yet.
+type pendingDeploys struct {
+	lock   sync.Mutex
+	hashes map[string]*felt.Felt
+	misses map[string]int
+}
+
+func newPendingDeploys() *pendingDeploys {
+	return &pendingDeploys{hashes: map[string]*felt.Felt{}, misses: map[string]int{}}
+}
+
+func (d *pendingDeploys) add(accountAddress, hash *felt.Felt) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.hashes[accountAddress.String()] = hash
+	d.misses[accountAddress.String()] = 0
+}
+
+// get returns nil if accountAddress has no pending deployment.
+func (d *pendingDeploys) get(accountAddress *felt.Felt) *felt.Felt {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.hashes[accountAddress.String()]
+}
+
+// miss records a check that did not find the deployment, and returns the number of consecutive misses.
+func (d *pendingDeploys) miss(accountAddress *felt.Felt) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.misses[accountAddress.String()]++
+	return d.misses[accountAddress.String()]
+}
+
+func (d *pendingDeploys) remove(accountAddress *felt.Felt) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.hashes, accountAddress.String())
+	delete(d.misses, accountAddress.String())
+}
+
+// deployAccount broadcasts a DEPLOY_ACCOUNT V3, once the counterfactual address can pay for it, and returns its hash.
+func (txm *starktxm) deployAccount(ctx context.Context, client *starknet.Client, deployment accountDeployment, publicKey *felt.Felt) (*starknetaccount.Account, *felt.Felt, error) {
+	account, err := txm.newAccount(client, deployment.address, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx := starknetrpc.DeployAccountTxnV3{
+		Type:                starknetrpc.TransactionType_DeployAccount,
+		Version:             starknetrpc.TransactionV3,
+		Signature:           []*felt.Felt{},
+		Nonce:               &felt.Zero,
+		ContractAddressSalt: deployment.salt,
+		ConstructorCalldata: deployment.constructorCalldata,
+		ClassHash:           deployment.classHash,
+		ResourceBounds: starknetrpc.ResourceBoundsMapping{
+			L1Gas: starknetrpc.ResourceBounds{MaxAmount: "0x0", MaxPricePerUnit: "0x0"},
+			L2Gas: starknetrpc.ResourceBounds{MaxAmount: "0x0", MaxPricePerUnit: "0x0"},
+		},
+		Tip:           "0x0",
+		PayMasterData: []*felt.Felt{},
+		NonceDataMode: starknetrpc.DAModeL1,
+		FeeMode:       starknetrpc.DAModeL1,
+	}
+
+	simFlags := []starknetrpc.SimulationFlag{starknetrpc.SKIP_VALIDATE}
+	estimates, err := client.Provider.EstimateFee(ctx, []starknetrpc.BroadcastTxn{starknetrpc.BroadcastDeployAccountTxnV3{DeployAccountTxnV3: tx}}, simFlags, starknetrpc.WithBlockTag(starknet.BlockTagPending))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to estimate deploy account fee: %+w", errEstimateFailed, err)
+	}
+	var friEstimate *starknetrpc.FeeEstimate
+	for i := range estimates {
+		if estimates[i].FeeUnit == starknetrpc.UnitStrk {
+			friEstimate = &estimates[i]
+		}
+	}
+	if friEstimate == nil {
+		return nil, nil, fmt.Errorf("%w: no FRI estimate for deploy account", errEstimateFailed)
+	}
+	params, err := txm.estimateFees(ctx, client, friEstimate, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx.ResourceBounds = params.ResourceBoundsMapping()
+	tx.Tip = starknetrpc.U64(fmt.Sprintf("0x%x", params.Tip))
+
+	// the deployment is paid by the account itself
+	balance, err := txm.feeTokenBalance(ctx, client, txm.cfg.FeeToken(), starknetrpc.UnitStrk, deployment.address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check balance of %s: %w", deployment.address, err)
+	}
+	if balance.Cmp(params.MaxFee()) < 0 {
+		return nil, nil, pausedAccount{accountAddress: deployment.address, balance: balance, maxFee: params.MaxFee()}.err()
+	}
+
+	hash, err := account.TransactionHashDeployAccount(tx, deployment.address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to hash deploy account: %+w", err)
+	}
+	tx.Signature, err = account.Sign(ctx, hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign deploy account: %+w", err)
+	}
+
+	execCtx, execCancel := context.WithTimeout(ctx, txm.cfg.TxTimeout())
+	defer execCancel()
+	res, err := account.AddDeployAccountTransaction(execCtx, starknetrpc.BroadcastDeployAccountTxnV3{DeployAccountTxnV3: tx})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to deploy account: %+w", err)
+	}
+	if res == nil {
+		return nil, nil, errors.New("deploy account response and error are nil")
+	}
+	txm.lggr.Infow("deploy account broadcast", "txhash", res.TransactionHash, "accountAddress", deployment.address, "MaxFee", params.MaxFee())
+	return account, res.TransactionHash, nil
+}
+
+// waitForAcceptance polls the status of a tx until it is accepted, see DeployAccount. It gives up after TxTTL
+// (deployTimeout if disabled), or once the node has not known the tx for deployMisses polls.
+func (txm *starktxm) waitForAcceptance(ctx context.Context, account *starknetaccount.Account, hash *felt.Felt) error {
+	timeout := txm.cfg.TxTTL()
+	if timeout == 0 {
+		timeout = deployTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tick := time.NewTicker(txm.cfg.ConfirmationPoll())
+	defer tick.Stop()
+	var misses int
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for tx %s: %w", hash, ctx.Err())
+		case <-tick.C:
+		}
+		status, err := account.GetTransactionStatus(ctx, hash)
+		if err != nil {
+			txm.lggr.Debugw("failed to fetch transaction status", "hash", hash, "error", err)
+			if isTxHashNotFound(err) {
+				if misses++; misses >= deployMisses {
+					return fmt.Errorf("tx %s dropped: not found for %d polls", hash, misses)
+				}
+			}
+			continue
+		}
+		misses = 0
+		switch {
+		case status.FinalityStatus == starknetrpc.TxnStatus_Rejected:
+			return fmt.Errorf("tx %s rejected", hash)
+		case status.ExecutionStatus == starknetrpc.TxnExecutionStatusREVERTED:
+			return fmt.Errorf("tx %s reverted", hash)
+		case status.FinalityStatus == starknetrpc.TxnStatus_Accepted_On_L2 || status.FinalityStatus == starknetrpc.TxnStatus_Accepted_On_L1:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package txm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetaccount "github.com/NethermindEth/starknet.go/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet/starknettest"
+)
+
+func TestNewAccountDeployment(t *testing.T) {
+	t.Parallel()
+
+	publicKey := new(felt.Felt).SetUint64(0x1234)
+	for _, class := range []AccountClass{AccountClassOZ, AccountClassArgent} {
+		deployment, err := newAccountDeployment(class, nil, publicKey)
+		require.NoError(t, err)
+		assert.Equal(t, accountClassHashes[class], deployment.classHash.String())
+		assert.Equal(t, publicKey, deployment.salt)
+		assert.Equal(t, publicKey, deployment.constructorCalldata[0])
+
+		// cross-check against the starknet.go implementation
+		expected, err := (&starknetaccount.Account{}).PrecomputeAddress(&felt.Zero, publicKey, deployment.classHash, deployment.constructorCalldata)
+		require.NoError(t, err)
+		assert.Equal(t, expected, deployment.address, class)
+	}
+
+	classHash := new(felt.Felt).SetUint64(42)
+	deployment, err := newAccountDeployment(AccountClassOZ, classHash, publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, classHash, deployment.classHash)
+
+	_, err = newAccountDeployment("Braavos", nil, publicKey)
+	require.ErrorContains(t, err, "unsupported account class")
+}
+
+func TestTxm_DeployAccount(t *testing.T) {
+	t.Parallel()
+
+	publicKey := new(felt.Felt).SetUint64(0x1234)
+
+	for _, tc := range []struct {
+		name     string
+		deployed bool
+		balance  string // u256 low felt of the fee token balance of the account
+		dropped  bool   // the node never knows the deployment
+		err      string
+	}{
+		{name: "undeployed", balance: "0xffffffff"},
+		{name: "deployed", deployed: true},
+		{name: "not funded", balance: "0x1", err: ErrInsufficientBalance.Error()},
+		{name: "dropped", balance: "0xffffffff", dropped: true, err: "not found for 5 polls"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deployTx map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				var req struct {
+					ID     json.RawMessage   `json:"id"`
+					Method string            `json:"method"`
+					Params []json.RawMessage `json:"params"`
+				}
+				require.NoError(t, json.Unmarshal(body, &req))
+				var result string
+				switch req.Method {
+				case "starknet_getClassHashAt":
+					result = `"error": {"code": 20, "message": "Contract not found"}`
+					if tc.deployed {
+						result = `"result": "0x1"`
+					}
+				case "starknet_chainId":
+					result = `"result": "0x534e5f5345504f4c4941"`
+				case "starknet_estimateFee":
+					result = `"result": [{"gas_consumed": "0x100", "gas_price": "0x10", "data_gas_consumed": "0x0", "data_gas_price": "0x1", "overall_fee": "0x1000", "unit": "FRI"}]`
+				case "starknet_call":
+					result = fmt.Sprintf(`"result": ["%s", "0x0"]`, tc.balance)
+				case "starknet_addDeployAccountTransaction":
+					require.NoError(t, json.Unmarshal(req.Params[0], &deployTx))
+					result = `"result": {"transaction_hash": "0xabc", "contract_address": "0x1"}`
+				case "starknet_getTransactionStatus":
+					result = `"result": {"finality_status": "ACCEPTED_ON_L2", "execution_status": "SUCCEEDED"}`
+					if tc.dropped {
+						result = `"error": {"code": 29, "message": "Transaction hash not found"}`
+					}
+				default:
+					t.Errorf("unexpected method %s", req.Method)
+				}
+				_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, %s}`, req.ID, result)
+				require.NoError(t, err)
+			}))
+			t.Cleanup(server.Close)
+
+			txm := newTestTxm(t, publicKey)
+			cfg := txm.cfg.(*mocks.Config)
+			cfg.On("AccountClass").Return(string(AccountClassOZ))
+			cfg.On("AccountClassHash").Return(nil)
+			cfg.On("TxTimeout").Return(time.Second).Maybe()
+			cfg.On("ConfirmationPoll").Return(10 * time.Millisecond).Maybe()
+			cfg.On("TxTTL").Return(time.Minute).Maybe()
+			cfg.On("FeeToken").Return(starknettest.STRKFeeToken.String()).Maybe()
+			txm.client = utils.NewLazyLoad(func() (*starknet.Client, error) {
+				return starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+			})
+
+			address, err := txm.AccountAddress(publicKey)
+			require.NoError(t, err)
+
+			hash, err := txm.DeployAccount(tests.Context(t), publicKey)
+			if tc.deployed {
+				require.ErrorIs(t, err, ErrAccountDeployed)
+				return
+			}
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				if errors.Is(err, ErrInsufficientBalance) {
+					assert.Nil(t, deployTx, "underfunded deployment broadcast")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "0xabc", hash)
+			assert.Equal(t, "DEPLOY_ACCOUNT", deployTx["type"])
+			assert.Equal(t, "0x3", deployTx["version"])
+			assert.Equal(t, accountClassHashes[AccountClassOZ], deployTx["class_hash"])
+			assert.Equal(t, publicKey.String(), deployTx["contract_address_salt"])
+			assert.Equal(t, []any{"0x7", "0xb"}, deployTx["signature"])
+			assert.NotNil(t, address)
+		})
+	}
+}
+
+func TestTxm_AutoDeployAccount(t *testing.T) {
+	t.Parallel()
+
+	publicKey := new(felt.Felt).SetUint64(0x1234)
+
+	for _, tc := range []struct {
+		name     string
+		statuses []string // results of the status checks after the broadcast
+		err      string   // error of the last check
+	}{
+		{name: "accepted", statuses: []string{
+			`"result": {"finality_status": "RECEIVED"}`,
+			`"result": {"finality_status": "ACCEPTED_ON_L2", "execution_status": "SUCCEEDED"}`,
+		}},
+		{name: "reverted", statuses: []string{
+			`"result": {"finality_status": "ACCEPTED_ON_L2", "execution_status": "REVERTED"}`,
+		}, err: "deployment 0xabc reverted"},
+		{name: "dropped", statuses: []string{
+			`"error": {"code": 29, "message": "Transaction hash not found"}`,
+			`"error": {"code": 29, "message": "Transaction hash not found"}`,
+			`"error": {"code": 29, "message": "Transaction hash not found"}`,
+			`"error": {"code": 29, "message": "Transaction hash not found"}`,
+			`"error": {"code": 29, "message": "Transaction hash not found"}`,
+		}, err: "deployment 0xabc dropped: not found for 5 checks"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deploys, checks int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				var req struct {
+					ID     json.RawMessage `json:"id"`
+					Method string          `json:"method"`
+				}
+				require.NoError(t, json.Unmarshal(body, &req))
+				var result string
+				switch req.Method {
+				case "starknet_chainId":
+					result = `"result": "0x534e5f5345504f4c4941"`
+				case "starknet_estimateFee":
+					result = `"result": [{"gas_consumed": "0x100", "gas_price": "0x10", "data_gas_consumed": "0x0", "data_gas_price": "0x1", "overall_fee": "0x1000", "unit": "FRI"}]`
+				case "starknet_call":
+					result = `"result": ["0xffffffff", "0x0"]`
+				case "starknet_addDeployAccountTransaction":
+					deploys++
+					result = `"result": {"transaction_hash": "0xabc", "contract_address": "0x1"}`
+				case "starknet_getTransactionStatus":
+					result = tc.statuses[checks]
+					checks++
+				default:
+					t.Errorf("unexpected method %s", req.Method)
+				}
+				_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, %s}`, req.ID, result)
+				require.NoError(t, err)
+			}))
+			t.Cleanup(server.Close)
+
+			txm := newTestTxm(t, publicKey)
+			cfg := txm.cfg.(*mocks.Config)
+			cfg.On("AutoDeployAccounts").Return(true)
+			cfg.On("AccountClass").Return(string(AccountClassOZ))
+			cfg.On("AccountClassHash").Return(nil)
+			cfg.On("TxTimeout").Return(time.Second).Maybe()
+			cfg.On("FeeToken").Return(starknettest.STRKFeeToken.String()).Maybe()
+			client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+			require.NoError(t, err)
+			address, err := txm.AccountAddress(publicKey)
+			require.NoError(t, err)
+			ctx := tests.Context(t)
+
+			// the broadcast worker does not wait for the deployment
+			err = txm.autoDeployAccount(ctx, client, address, publicKey)
+			require.ErrorIs(t, err, errDeployPending)
+			assert.Equal(t, 1, deploys)
+			assert.Equal(t, 0, checks)
+
+			for range tc.statuses[1:] {
+				require.ErrorIs(t, txm.autoDeployAccount(ctx, client, address, publicKey), errDeployPending)
+			}
+			err = txm.autoDeployAccount(ctx, client, address, publicKey)
+			assert.Equal(t, len(tc.statuses), checks)
+			if tc.err == "" {
+				require.NoError(t, err)
+				assert.Nil(t, txm.deploys.get(address))
+				return
+			}
+			require.EqualError(t, err, tc.err)
+
+			// a failed deployment is broadcast again
+			require.ErrorIs(t, txm.autoDeployAccount(ctx, client, address, publicKey), errDeployPending)
+			assert.Equal(t, 2, deploys)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// drainPollInterval is how often Close checks whether the queues have been drained
+const drainPollInterval = 100 * time.Millisecond
+
+// ErrShuttingDown is returned by Enqueue once Close has been called.
+var ErrShuttingDown = errors.New("txm is shutting down")
+
+// drain waits until the queues of all accounts that are not paused are empty, or DrainTimeout has passed.
+func (txm *starktxm) drain() {
+	timeout := txm.cfg.DrainTimeout()
+	if timeout == 0 {
+		return
+	}
+	txm.lggr.Infow("draining tx queues", "queued", txm.queues.len(), "timeout", timeout)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(drainPollInterval)
+	defer tick.Stop()
+	for !txm.queues.idle() || txm.pendingRetries.Load() > 0 {
+		select {
+		case <-deadline.C:
+			txm.lggr.Warnw("tx queues not drained before timeout", "queued", txm.queues.len(), "retrying", txm.pendingRetries.Load())
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// reportUndrained logs the txs that are left queued at shutdown.
+func (txm *starktxm) reportUndrained() {
+	records, err := txm.ListTransactions(context.Background(), nil, TxEnqueued)
+	if err != nil {
+		txm.lggr.Errorw("failed to list queued txs", "error", err)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+	ids := make([]string, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	if txm.cfg.TxStoragePath() == "" {
+		txm.lggr.Errorw("dropping queued txs at shutdown, set TxStoragePath to persist them", "ids", ids)
+		return
+	}
+	txm.lggr.Warnw("queued txs are broadcast after restart", "ids", ids)
+}
@@ -0,0 +1,90 @@
+package txm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+)
+
+func TestTxm_Drain(t *testing.T) {
+	t.Parallel()
+
+	account := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+	call := starknetrpc.FunctionCall{
+		ContractAddress:    new(felt.Felt).SetUint64(3),
+		EntryPointSelector: new(felt.Felt).SetUint64(4),
+	}
+	newStartedTxm := func(t *testing.T, drainTimeout time.Duration) *starktxm {
+		txm := newTestTxm(t, publicKey)
+		txm.cfg.(*mocks.Config).On("DrainTimeout").Return(drainTimeout)
+		// no workers, the tests take batches from the queues themselves
+		require.NoError(t, txm.starter.StartOnce("Txm", func() error { return nil }))
+		return txm
+	}
+
+	t.Run("drained", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := tests.Context(t)
+		txm := newStartedTxm(t, time.Minute)
+		_, err := txm.Enqueue(ctx, account, publicKey, call, "", TxOpts{})
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			aq, _, ok := txm.queues.next(1, 0)
+			assert.True(t, ok)
+			txm.queues.done(aq)
+		}()
+		start := time.Now()
+		require.NoError(t, txm.Close())
+		assert.Less(t, time.Since(start), time.Minute)
+		assert.Equal(t, 0, txm.queues.len())
+
+		_, err = txm.Enqueue(ctx, account, publicKey, call, "", TxOpts{})
+		require.ErrorIs(t, err, ErrShuttingDown)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := tests.Context(t)
+		txm := newStartedTxm(t, 50*time.Millisecond)
+		id, err := txm.Enqueue(ctx, account, publicKey, call, "", TxOpts{})
+		require.NoError(t, err)
+
+		start := time.Now()
+		require.NoError(t, txm.Close())
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		// left queued, to be restored by the next start
+		record, err := txm.storage.Get(id)
+		require.NoError(t, err)
+		assert.Equal(t, TxEnqueued, record.State)
+	})
+
+	t.Run("paused account", func(t *testing.T) {
+		t.Parallel()
+
+		txm := newStartedTxm(t, time.Minute)
+		_, err := txm.Enqueue(context.Background(), account, publicKey, call, "", TxOpts{})
+		require.NoError(t, err)
+
+		// the txs of a paused account cannot be drained
+		txm.queues.pause(account)
+		start := time.Now()
+		require.NoError(t, txm.Close())
+		assert.Less(t, time.Since(start), time.Minute)
+		assert.Equal(t, 1, txm.queues.len())
+	})
+}
@@ -0,0 +1,344 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+type Mode string
+
+const (
+	// ModePadded pads the gas amounts and prices returned by starknet_estimateFee
+	ModePadded Mode = "Padded"
+	// ModeFixed always uses the configured L1 gas bounds
+	ModeFixed Mode = "Fixed"
+	// ModeBlockPrice pads the estimated gas amounts, and prices them at the gas price of the latest block
+	ModeBlockPrice Mode = "BlockPrice"
+	// ModePercentile pads the estimated gas amounts, and prices them at a percentile of the gas prices
+	// of recent blocks
+	ModePercentile Mode = "Percentile"
+)
+
+// Config configures how the resource bounds of a tx are computed. The L1 gas and L1 data gas bounds are derived
+// from the node's estimate by Mode. L2 gas is neither estimated nor priced by RPC v0.7 nodes, so every mode uses the
+// configured L2GasMaxAmount and L2GasMaxPricePerUnit, which stay zero until the sequencer charges L2 gas.
+type Config struct {
+	Mode Mode
+	// AmountPaddingPercent scales the estimated gas amounts, 150 means 150% of the estimate
+	AmountPaddingPercent uint32
+	// PricePaddingPercent scales the gas prices, 150 means 150% of the estimate
+	PricePaddingPercent uint32
+	// FixedMaxAmount and FixedMaxPricePerUnit are the L1 gas bounds used by ModeFixed
+	FixedMaxAmount       uint64
+	FixedMaxPricePerUnit *big.Int
+	// L2GasMaxAmount and L2GasMaxPricePerUnit are the L2 gas bounds of every tx
+	L2GasMaxAmount       uint64
+	L2GasMaxPricePerUnit *big.Int
+	// BlockHistorySize is the number of recent blocks considered by ModePercentile
+	BlockHistorySize uint32
+	// Percentile of the recent block gas prices used by ModePercentile
+	Percentile uint32
+	// Tip is the initial tip of every tx
+	Tip uint64
+	// MaxFee is the maximum fee (in FRI) a single tx is allowed to pay, no limit if nil
+	MaxFee *big.Int
+	// MaxFeeWei is the maximum fee (in WEI) a single tx of a legacy account is allowed to pay, no limit if nil
+	MaxFeeWei *big.Int
+}
+
+// ResourceBound is the maximum amount and maximum price per unit of a single resource
+type ResourceBound struct {
+	MaxAmount       uint64
+	MaxPricePerUnit *big.Int
+}
+
+func (b ResourceBound) maxFee() *big.Int {
+	if b.MaxPricePerUnit == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(b.MaxAmount), b.MaxPricePerUnit)
+}
+
+// Params are the resource bounds and tip of a V3 tx
+type Params struct {
+	L1Gas     ResourceBound
+	L1DataGas ResourceBound
+	L2Gas     ResourceBound
+	Tip       uint64
+}
+
+// MaxFee is the most that a tx with these fees can be charged.
+func (f Params) MaxFee() *big.Int {
+	total := new(big.Int).Add(f.L1Gas.maxFee(), f.L1DataGas.maxFee())
+	total.Add(total, f.L2Gas.maxFee())
+	// the tip is paid per unit of L2 gas
+	tip := new(big.Int).Mul(new(big.Int).SetUint64(f.Tip), new(big.Int).SetUint64(f.L2Gas.MaxAmount))
+	return total.Add(total, tip)
+}
+
+// Bump raises every max price per unit and the tip by percent.
+func (f Params) Bump(percent uint32) Params {
+	bump := func(b ResourceBound) ResourceBound {
+		if b.MaxPricePerUnit != nil {
+			b.MaxPricePerUnit = bumpPercentage(b.MaxPricePerUnit, percent)
+		}
+		return b
+	}
+	f.L1Gas = bump(f.L1Gas)
+	f.L1DataGas = bump(f.L1DataGas)
+	f.L2Gas = bump(f.L2Gas)
+	// a zero tip can't be bumped by a percentage, start at the smallest unit
+	f.Tip = bumpPercentage(new(big.Int).SetUint64(f.Tip), percent).Uint64()
+	if f.Tip == 0 {
+		f.Tip = 1
+	}
+	return f
+}
+
+// ResourceBoundsMapping converts the fees into the resource bounds of an invoke V3.
+// The tx version in use (RPC v0.7) has no separate L1 data gas bound: data gas is charged against the L1 gas
+// bound, so the L1 data gas budget is converted into L1 gas units at the L1 gas price.
+func (f Params) ResourceBoundsMapping() starknetrpc.ResourceBoundsMapping {
+	l1Amount := new(big.Int).SetUint64(f.L1Gas.MaxAmount)
+	l1Price := f.L1Gas.MaxPricePerUnit
+	if l1Price == nil {
+		l1Price = new(big.Int)
+	}
+	if l1Price.Sign() > 0 {
+		dataGasUnits := new(big.Int).Add(f.L1DataGas.maxFee(), new(big.Int).Sub(l1Price, big.NewInt(1)))
+		l1Amount.Add(l1Amount, dataGasUnits.Div(dataGasUnits, l1Price))
+	}
+
+	return starknetrpc.ResourceBoundsMapping{
+		L1Gas: starknetrpc.ResourceBounds{
+			MaxAmount:       starknetrpc.U64(fmt.Sprintf("0x%x", l1Amount)),
+			MaxPricePerUnit: starknetrpc.U128(fmt.Sprintf("0x%x", l1Price)),
+		},
+		L2Gas: toResourceBounds(f.L2Gas),
+	}
+}
+
+func toResourceBounds(b ResourceBound) starknetrpc.ResourceBounds {
+	price := b.MaxPricePerUnit
+	if price == nil {
+		price = new(big.Int)
+	}
+	return starknetrpc.ResourceBounds{
+		MaxAmount:       starknetrpc.U64(fmt.Sprintf("0x%x", b.MaxAmount)),
+		MaxPricePerUnit: starknetrpc.U128(fmt.Sprintf("0x%x", price)),
+	}
+}
+
+func bumpPercentage(value *big.Int, percent uint32) *big.Int {
+	bumped := new(big.Int).Mul(value, big.NewInt(int64(100+percent)))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func padPercentage(value *big.Int, percent uint32) *big.Int {
+	padded := new(big.Int).Mul(value, big.NewInt(int64(percent)))
+	return padded.Div(padded, big.NewInt(100))
+}
+
+var ErrExceedsMax = errors.New("fee exceeds configured maximum")
+
+// Estimator computes the fee params of a tx from the node's fee estimate for it.
+type Estimator interface {
+	Estimate(ctx context.Context, client *starknet.Client, estimate *starknetrpc.FeeEstimate) (Params, error)
+}
+
+// NewEstimator returns the Estimator selected by cfg.Mode, defaulting to ModePadded.
+func NewEstimator(cfg Config) (Estimator, error) {
+	switch cfg.Mode {
+	case ModePadded, "":
+		return &paddedEstimator{cfg: cfg}, nil
+	case ModeFixed:
+		if cfg.FixedMaxAmount == 0 || cfg.FixedMaxPricePerUnit == nil || cfg.FixedMaxPricePerUnit.Sign() <= 0 {
+			return nil, errors.New("fixed estimator requires FixedMaxAmount and FixedMaxPricePerUnit")
+		}
+		return &fixedEstimator{cfg: cfg}, nil
+	case ModeBlockPrice:
+		return &blockPriceEstimator{cfg: cfg}, nil
+	case ModePercentile:
+		if cfg.BlockHistorySize == 0 {
+			return nil, errors.New("percentile estimator requires BlockHistorySize")
+		}
+		if cfg.Percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile: %d", cfg.Percentile)
+		}
+		return &percentileEstimator{cfg: cfg, prices: map[uint64]blockGasPrices{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown fee estimator mode: %s", cfg.Mode)
+	}
+}
+
+// paddedParams pads the amounts and prices of the node's estimate.
+func paddedParams(cfg Config, estimate *starknetrpc.FeeEstimate) Params {
+	return Params{
+		L1Gas: ResourceBound{
+			MaxAmount:       padPercentage(feltToBig(estimate.GasConsumed), cfg.AmountPaddingPercent).Uint64(),
+			MaxPricePerUnit: padPercentage(feltToBig(estimate.GasPrice), cfg.PricePaddingPercent),
+		},
+		L1DataGas: ResourceBound{
+			MaxAmount:       padPercentage(feltToBig(estimate.DataGasConsumed), cfg.AmountPaddingPercent).Uint64(),
+			MaxPricePerUnit: padPercentage(feltToBig(estimate.DataGasPrice), cfg.PricePaddingPercent),
+		},
+		L2Gas: l2GasBound(cfg),
+		Tip:   cfg.Tip,
+	}
+}
+
+// l2GasBound returns the configured L2 gas bound, see Config.
+func l2GasBound(cfg Config) ResourceBound {
+	price := new(big.Int)
+	if cfg.L2GasMaxPricePerUnit != nil {
+		price.Set(cfg.L2GasMaxPricePerUnit)
+	}
+	return ResourceBound{MaxAmount: cfg.L2GasMaxAmount, MaxPricePerUnit: price}
+}
+
+func feltToBig(f *felt.Felt) *big.Int {
+	if f == nil {
+		return new(big.Int)
+	}
+	return f.BigInt(new(big.Int))
+}
+
+type paddedEstimator struct {
+	cfg Config
+}
+
+func (e *paddedEstimator) Estimate(_ context.Context, _ *starknet.Client, estimate *starknetrpc.FeeEstimate) (Params, error) {
+	return paddedParams(e.cfg, estimate), nil
+}
+
+type fixedEstimator struct {
+	cfg Config
+}
+
+func (e *fixedEstimator) Estimate(_ context.Context, _ *starknet.Client, _ *starknetrpc.FeeEstimate) (Params, error) {
+	return Params{
+		L1Gas: ResourceBound{
+			MaxAmount:       e.cfg.FixedMaxAmount,
+			MaxPricePerUnit: new(big.Int).Set(e.cfg.FixedMaxPricePerUnit),
+		},
+		L1DataGas: ResourceBound{MaxPricePerUnit: new(big.Int)},
+		L2Gas:     l2GasBound(e.cfg),
+		Tip:       e.cfg.Tip,
+	}, nil
+}
+
+type blockGasPrices struct {
+	l1Gas     *big.Int
+	l1DataGas *big.Int
+}
+
+func headerGasPrices(l1GasPrice, l1DataGasPrice starknetrpc.ResourcePrice) blockGasPrices {
+	return blockGasPrices{
+		l1Gas:     feltToBig(l1GasPrice.PriceInFRI),
+		l1DataGas: feltToBig(l1DataGasPrice.PriceInFRI),
+	}
+}
+
+// withPrices pads the estimated amounts and replaces the prices with padded block prices. Prices missing
+// from the block header fall back to the estimate.
+func withPrices(cfg Config, estimate *starknetrpc.FeeEstimate, prices blockGasPrices) Params {
+	params := paddedParams(cfg, estimate)
+	if prices.l1Gas.Sign() > 0 {
+		params.L1Gas.MaxPricePerUnit = padPercentage(prices.l1Gas, cfg.PricePaddingPercent)
+	}
+	if prices.l1DataGas.Sign() > 0 {
+		params.L1DataGas.MaxPricePerUnit = padPercentage(prices.l1DataGas, cfg.PricePaddingPercent)
+	}
+	return params
+}
+
+type blockPriceEstimator struct {
+	cfg Config
+}
+
+func (e *blockPriceEstimator) Estimate(ctx context.Context, client *starknet.Client, estimate *starknetrpc.FeeEstimate) (Params, error) {
+	block, err := client.Provider.BlockWithTxHashes(ctx, starknetrpc.WithBlockTag(starknet.BlockTagLatest))
+	if err != nil {
+		return Params{}, fmt.Errorf("failed to fetch latest block: %w", err)
+	}
+	header, ok := block.(*starknetrpc.BlockTxHashes)
+	if !ok {
+		return Params{}, fmt.Errorf("unexpected block type: %T", block)
+	}
+	return withPrices(e.cfg, estimate, headerGasPrices(header.L1GasPrice, header.L1DataGasPrice)), nil
+}
+
+type percentileEstimator struct {
+	cfg Config
+
+	lock   sync.Mutex
+	prices map[uint64]blockGasPrices // cache of gas prices by block number
+}
+
+func (e *percentileEstimator) Estimate(ctx context.Context, client *starknet.Client, estimate *starknetrpc.FeeEstimate) (Params, error) {
+	latest, err := client.Provider.BlockNumber(ctx)
+	if err != nil {
+		return Params{}, fmt.Errorf("failed to fetch latest block number: %w", err)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var l1GasPrices, l1DataGasPrices []*big.Int
+	for i := uint64(0); i < uint64(e.cfg.BlockHistorySize) && i <= latest; i++ {
+		number := latest - i
+		prices, ok := e.prices[number]
+		if !ok {
+			block, err := client.Provider.BlockWithTxHashes(ctx, starknetrpc.BlockID{Number: &number})
+			if err != nil {
+				return Params{}, fmt.Errorf("failed to fetch block %d: %w", number, err)
+			}
+			header, ok := block.(*starknetrpc.BlockTxHashes)
+			if !ok {
+				return Params{}, fmt.Errorf("unexpected block type: %T", block)
+			}
+			prices = headerGasPrices(header.L1GasPrice, header.L1DataGasPrice)
+			e.prices[number] = prices
+		}
+		l1GasPrices = append(l1GasPrices, prices.l1Gas)
+		l1DataGasPrices = append(l1DataGasPrices, prices.l1DataGas)
+	}
+
+	// evict blocks that fell out of the history window
+	for number := range e.prices {
+		if number+uint64(e.cfg.BlockHistorySize) <= latest {
+			delete(e.prices, number)
+		}
+	}
+
+	return withPrices(e.cfg, estimate, blockGasPrices{
+		l1Gas:     percentile(l1GasPrices, e.cfg.Percentile),
+		l1DataGas: percentile(l1DataGasPrices, e.cfg.Percentile),
+	}), nil
+}
+
+// percentile returns the nearest-rank percentile of values, or zero if there are none.
+func percentile(values []*big.Int, p uint32) *big.Int {
+	if len(values) == 0 {
+		return new(big.Int)
+	}
+	sorted := make([]*big.Int, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+	rank := (int(p)*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	if rank < 1 {
+		rank = 1
+	}
+	return new(big.Int).Set(sorted[rank-1])
+}
@@ -0,0 +1,124 @@
+package fees
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEstimator(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewEstimator(Config{})
+	require.NoError(t, err)
+
+	_, err = NewEstimator(Config{Mode: ModeFixed})
+	require.ErrorContains(t, err, "fixed estimator requires")
+	_, err = NewEstimator(Config{Mode: ModeFixed, FixedMaxAmount: 10, FixedMaxPricePerUnit: big.NewInt(1)})
+	require.NoError(t, err)
+
+	_, err = NewEstimator(Config{Mode: ModePercentile})
+	require.ErrorContains(t, err, "percentile estimator requires")
+	_, err = NewEstimator(Config{Mode: ModePercentile, BlockHistorySize: 5, Percentile: 101})
+	require.ErrorContains(t, err, "invalid percentile")
+
+	_, err = NewEstimator(Config{Mode: "Unknown"})
+	require.ErrorContains(t, err, "unknown fee estimator mode")
+}
+
+func TestPaddedEstimator(t *testing.T) {
+	t.Parallel()
+
+	e, err := NewEstimator(Config{Mode: ModePadded, AmountPaddingPercent: 150, PricePaddingPercent: 200, Tip: 3})
+	require.NoError(t, err)
+
+	params, err := e.Estimate(context.Background(), nil, &starknetrpc.FeeEstimate{
+		GasConsumed:     new(felt.Felt).SetUint64(100),
+		GasPrice:        new(felt.Felt).SetUint64(10),
+		DataGasConsumed: new(felt.Felt).SetUint64(20),
+		DataGasPrice:    new(felt.Felt).SetUint64(5),
+		OverallFee:      new(felt.Felt).SetUint64(1100),
+		FeeUnit:         starknetrpc.UnitStrk,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(150), params.L1Gas.MaxAmount)
+	assert.Equal(t, big.NewInt(20), params.L1Gas.MaxPricePerUnit)
+	assert.Equal(t, uint64(30), params.L1DataGas.MaxAmount)
+	assert.Equal(t, big.NewInt(10), params.L1DataGas.MaxPricePerUnit)
+	assert.Equal(t, uint64(3), params.Tip)
+	// 150*20 + 30*10
+	assert.Equal(t, big.NewInt(3300), params.MaxFee())
+
+	// data gas is folded into the L1 gas bound: 150 + 300/20
+	bounds := params.ResourceBoundsMapping()
+	assert.Equal(t, starknetrpc.U64("0xa5"), bounds.L1Gas.MaxAmount)
+	assert.Equal(t, starknetrpc.U128("0x14"), bounds.L1Gas.MaxPricePerUnit)
+	assert.Equal(t, starknetrpc.U64("0x0"), bounds.L2Gas.MaxAmount)
+	assert.Equal(t, starknetrpc.U128("0x0"), bounds.L2Gas.MaxPricePerUnit)
+}
+
+func TestL2GasBound(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{FixedMaxAmount: 10, FixedMaxPricePerUnit: big.NewInt(1), L2GasMaxAmount: 1000, L2GasMaxPricePerUnit: big.NewInt(3), Tip: 2}
+	for _, mode := range []Mode{ModePadded, ModeFixed} {
+		cfg.Mode = mode
+		e, err := NewEstimator(cfg)
+		require.NoError(t, err)
+		params, err := e.Estimate(context.Background(), nil, &starknetrpc.FeeEstimate{})
+		require.NoError(t, err, mode)
+
+		bounds := params.ResourceBoundsMapping()
+		assert.Equal(t, starknetrpc.U64("0x3e8"), bounds.L2Gas.MaxAmount, mode)
+		assert.Equal(t, starknetrpc.U128("0x3"), bounds.L2Gas.MaxPricePerUnit, mode)
+		// the L2 gas and the tip per unit of L2 gas count towards the max fee
+		assert.Equal(t, 0, params.MaxFee().Cmp(new(big.Int).Add(params.L1Gas.maxFee(), big.NewInt(1000*3+1000*2))), mode)
+
+		// bumps raise the configured price
+		assert.Equal(t, big.NewInt(4), params.Bump(50).L2Gas.MaxPricePerUnit, mode)
+	}
+	assert.Equal(t, big.NewInt(3), cfg.L2GasMaxPricePerUnit)
+}
+
+func TestParams_Bump(t *testing.T) {
+	t.Parallel()
+
+	params := Params{
+		L1Gas:     ResourceBound{MaxAmount: 100, MaxPricePerUnit: big.NewInt(100)},
+		L1DataGas: ResourceBound{MaxAmount: 10, MaxPricePerUnit: big.NewInt(50)},
+		L2Gas:     ResourceBound{MaxPricePerUnit: new(big.Int)},
+	}
+
+	bumped := params.Bump(20)
+	assert.Equal(t, uint64(100), bumped.L1Gas.MaxAmount)
+	assert.Equal(t, big.NewInt(120), bumped.L1Gas.MaxPricePerUnit)
+	assert.Equal(t, big.NewInt(60), bumped.L1DataGas.MaxPricePerUnit)
+	// zero tip starts at the smallest unit
+	assert.Equal(t, uint64(1), bumped.Tip)
+	// original is unchanged
+	assert.Equal(t, big.NewInt(100), params.L1Gas.MaxPricePerUnit)
+
+	bumped = bumped.Bump(100)
+	assert.Equal(t, big.NewInt(240), bumped.L1Gas.MaxPricePerUnit)
+	assert.Equal(t, uint64(2), bumped.Tip)
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	values := []*big.Int{big.NewInt(5), big.NewInt(1), big.NewInt(4), big.NewInt(2), big.NewInt(3)}
+	assert.Equal(t, big.NewInt(1), percentile(values, 0))
+	assert.Equal(t, big.NewInt(3), percentile(values, 50))
+	assert.Equal(t, big.NewInt(3), percentile(values, 60))
+	assert.Equal(t, big.NewInt(4), percentile(values, 61))
+	assert.Equal(t, big.NewInt(5), percentile(values, 100))
+	assert.Equal(t, new(big.Int), percentile(nil, 50))
+	// input is not reordered
+	assert.Equal(t, big.NewInt(5), values[0])
+}
@@ -0,0 +1,202 @@
+package txm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// FinalityLevel is the finality status at which a tx is confirmed.
+type FinalityLevel string
+
+const (
+	// FinalityAcceptedOnL2 confirms txs as soon as they are accepted by the sequencer
+	FinalityAcceptedOnL2 FinalityLevel = "ACCEPTED_ON_L2"
+	// FinalityAcceptedOnL1 keeps tracking accepted txs until their block is proven on L1. A tx that disappears from
+	// L2 in the meantime is queued again.
+	FinalityAcceptedOnL1 FinalityLevel = "ACCEPTED_ON_L1"
+)
+
+// reorgMisses is the number of consecutive polls for which the node has to report an accepted tx as unknown or
+// rejected before it is considered reorged out, a single miss may come from a lagging node.
+const reorgMisses = 3
+
+// acceptedTx is an invoke that was accepted on L2 and waits for L1 finality.
+type acceptedTx struct {
+	accountAddress *felt.Felt
+	hash           string
+	ids            []string
+	// blockHash is nil while the tx is in the pending block
+	blockHash   *felt.Felt
+	blockNumber uint64
+	misses      int
+}
+
+// acceptedTxs tracks the txs in state TxAccepted by hash.
+type acceptedTxs struct {
+	lock sync.Mutex
+	txs  map[string]*acceptedTx
+}
+
+func newAcceptedTxs() *acceptedTxs {
+	return &acceptedTxs{txs: map[string]*acceptedTx{}}
+}
+
+func (a *acceptedTxs) add(tx *acceptedTx) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.txs[tx.hash] = tx
+}
+
+func (a *acceptedTxs) remove(hash string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.txs, hash)
+}
+
+func (a *acceptedTxs) hashes() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	hashes := make([]string, 0, len(a.txs))
+	for hash := range a.txs {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+func (a *acceptedTxs) get(hash string) (*acceptedTx, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	tx, ok := a.txs[hash]
+	return tx, ok
+}
+
+func (txm *starktxm) l1Finality() bool {
+	return FinalityLevel(txm.cfg.FinalityLevel()) == FinalityAcceptedOnL1
+}
+
+// checkAccepted confirms the accepted txs that reached L1, records the blocks of txs that left the pending block and
+// queues the txs that disappeared again. statuses has to hold the statuses of every accepted tx.
+func (txm *starktxm) checkAccepted(ctx context.Context, client *starknet.Client, statuses map[string]txStatusResult) {
+	var unknownBlocks []string
+	for _, hash := range txm.accepted.hashes() {
+		tx, ok := txm.accepted.get(hash)
+		if !ok {
+			continue
+		}
+		result, ok := statuses[hash]
+		if !ok {
+			continue
+		}
+		switch {
+		case result.err != nil && !isTxHashNotFound(result.err):
+			txm.lggr.Debugw("failed to fetch status of accepted tx", "hash", hash, "error", result.err)
+			continue
+		case result.err == nil && result.status.FinalityStatus == starknetrpc.TxnStatus_Accepted_On_L1:
+			txm.accepted.remove(hash)
+			txm.lggr.Infow("tx accepted on L1", "hash", hash, "ids", tx.ids, "blockNumber", tx.blockNumber)
+			txm.updateRecords(tx.ids, func(r *TxRecord) {
+				r.State = TxConfirmed
+			})
+			txm.done.Add(1)
+			go txm.recordFinalFee(ctx, client, tx)
+			continue
+		case result.err == nil && result.status.FinalityStatus != starknetrpc.TxnStatus_Rejected:
+			tx.misses = 0
+			if tx.blockHash == nil {
+				unknownBlocks = append(unknownBlocks, hash)
+			}
+			continue
+		}
+
+		// unknown to the node or rejected after it had been accepted
+		tx.misses++
+		txm.lggr.Warnw("accepted tx not found on L2", "hash", hash, "misses", tx.misses, "blockNumber", tx.blockNumber, "blockHash", tx.blockHash)
+		if tx.misses >= reorgMisses {
+			txm.requeueReorged(ctx, client, tx)
+		}
+	}
+
+	if len(unknownBlocks) == 0 {
+		return
+	}
+	for hash, result := range txm.fetchTxReceipts(ctx, client, unknownBlocks) {
+		if result.err != nil || result.receipt.BlockHash == nil {
+			// still pending
+			continue
+		}
+		tx, ok := txm.accepted.get(hash)
+		if !ok {
+			continue
+		}
+		tx.blockHash, tx.blockNumber = result.receipt.BlockHash, uint64(result.receipt.BlockNumber)
+		txm.updateRecords(tx.ids, func(r *TxRecord) {
+			r.BlockHash = tx.blockHash
+			r.BlockNumber = tx.blockNumber
+		})
+	}
+}
+
+// recordFinalFee records the actual fee of a tx that reached L1 in the fee ledger. The fee is not recorded at L2
+// acceptance, a reorged tx would be charged again for its next attempt.
+func (txm *starktxm) recordFinalFee(ctx context.Context, client *starknet.Client, tx *acceptedTx) {
+	defer txm.done.Done()
+
+	f, err := starknetutils.HexToFelt(tx.hash)
+	if err != nil {
+		txm.lggr.Errorw("invalid felt value", "hash", tx.hash)
+		return
+	}
+	receipt, err := client.TransactionReceipt(ctx, f)
+	if err != nil {
+		txm.lggr.Warnw("failed to fetch receipt of final tx", "hash", tx.hash, "error", err)
+		return
+	}
+	if fee, ok := receiptFeePayment(receipt); ok {
+		txm.recordFees(tx.accountAddress, tx.ids, tx.hash, fee)
+	}
+}
+
+// requeueReorged queues the calls of an accepted tx that disappeared from L2 again, and resyncs the nonce of its
+// account which the tx no longer uses.
+func (txm *starktxm) requeueReorged(ctx context.Context, client *starknet.Client, tx *acceptedTx) {
+	txm.accepted.remove(tx.hash)
+	txm.lggr.Errorw("accepted tx disappeared from L2, queueing it again", "hash", tx.hash, "ids", tx.ids, "blockNumber", tx.blockNumber, "blockHash", tx.blockHash)
+	promTxReorgs.WithLabelValues(txm.chainID, tx.accountAddress.String()).Inc()
+
+	reason := fmt.Sprintf("reorged out of L2 block %d", tx.blockNumber)
+	now := time.Now()
+	for _, id := range tx.ids {
+		var requeued Tx
+		txm.updateRecord(id, func(r *TxRecord) {
+			r.History = append(r.History, TxAttempt{Time: now, Hash: tx.hash, Error: reason})
+			r.Error = reason
+			r.State = TxEnqueued
+			r.Nonce, r.Hash, r.Attempts = nil, "", nil
+			r.BlockHash, r.BlockNumber = nil, 0
+			r.Sponsor = nil
+			requeued = txFromRecord(*r)
+		})
+		if requeued.id == "" {
+			continue
+		}
+		if err := txm.push(requeued); err != nil {
+			txm.deadLetter(requeued.accountAddress, []string{id}, deadLetterQueueFull, err.Error())
+		}
+	}
+
+	if txm.accountStore.GetTxStore(tx.accountAddress) == nil {
+		// the nonce is read from the node on the next broadcast
+		return
+	}
+	if err := txm.resyncNonce(ctx, client, tx.accountAddress); err != nil {
+		txm.lggr.Errorw("resync failed for reorged tx", "error", err)
+	}
+}
@@ -0,0 +1,134 @@
+package txm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+func TestTxm_CheckAccepted(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	// unique per test, the reorg metric is global
+	account := new(felt.Felt).SetUint64(0xacc016)
+	publicKey := new(felt.Felt).SetUint64(2)
+
+	// finality status per tx hash, unknown hashes are not found
+	var lock sync.Mutex
+	statuses := map[string]string{"0x1": "ACCEPTED_ON_L2"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []string        `json:"params"`
+		}
+		require.NoError(t, json.Unmarshal(body, &req))
+		lock.Lock()
+		status, ok := statuses[req.Params[0]]
+		lock.Unlock()
+		result := `"error": {"code": 29, "message": "Transaction hash not found"}`
+		switch {
+		case !ok:
+		case req.Method == "starknet_getTransactionStatus":
+			result = fmt.Sprintf(`"result": {"finality_status": "%s", "execution_status": "SUCCEEDED"}`, status)
+		case req.Method == "starknet_getTransactionReceipt":
+			result = fmt.Sprintf(`"result": {"type": "INVOKE", "transaction_hash": "%s", "actual_fee": {"amount": "0x1", "unit": "FRI"}, "execution_status": "SUCCEEDED", "finality_status": "%s", "block_hash": "0xb", "block_number": 5, "messages_sent": [], "events": [], "execution_resources": {"steps": 1}}`, req.Params[0], status)
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, %s}`, req.ID, result)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	txm := newTestTxm(t, publicKey)
+	cfg := txm.cfg.(*mocks.Config)
+	cfg.On("ConfirmationBatchSize").Return(uint32(0))
+	client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+	require.NoError(t, err)
+
+	// accepted txs are restored from their records, 0x2 has been reorged out
+	now := time.Now()
+	for id, hash := range map[string]string{"a": "0x1", "b": "0x2"} {
+		require.NoError(t, txm.storage.Save(TxRecord{
+			ID:             id,
+			AccountAddress: account,
+			PublicKey:      publicKey,
+			Call:           starknetrpc.FunctionCall{ContractAddress: account, EntryPointSelector: account},
+			State:          TxAccepted,
+			Nonce:          new(felt.Felt).SetUint64(1),
+			Hash:           hash,
+			Attempts:       []string{hash},
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}))
+	}
+	require.NoError(t, txm.restore())
+	require.Len(t, txm.accepted.hashes(), 2)
+
+	check := func() {
+		txm.checkAccepted(ctx, client, txm.fetchTxStatuses(ctx, client, txm.accepted.hashes()))
+	}
+	check()
+	record, err := txm.storage.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, TxAccepted, record.State)
+	assert.Equal(t, "0xb", record.BlockHash.String())
+	assert.Equal(t, uint64(5), record.BlockNumber)
+	record, err = txm.storage.Get("b")
+	require.NoError(t, err)
+	assert.Equal(t, TxAccepted, record.State)
+
+	// a missing tx is only queued again once it has been missing for reorgMisses polls
+	for i := 1; i < reorgMisses; i++ {
+		check()
+	}
+	record, err = txm.storage.Get("b")
+	require.NoError(t, err)
+	assert.Equal(t, TxEnqueued, record.State)
+	assert.Empty(t, record.Hash)
+	require.Len(t, record.History, 1)
+	assert.Equal(t, "0x2", record.History[0].Hash)
+	assert.Equal(t, 1, txm.queues.lenOf(account))
+	assert.InDelta(t, 1, testutil.ToFloat64(promTxReorgs.WithLabelValues(txm.chainID, account.String())), 0)
+
+	lock.Lock()
+	statuses["0x1"] = "ACCEPTED_ON_L1"
+	lock.Unlock()
+	check()
+	record, err = txm.storage.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, TxConfirmed, record.State)
+	assert.Empty(t, txm.accepted.hashes())
+
+	// only the fee of the tx that reached L1 is recorded, the reorged tx is charged for its next attempt
+	txm.done.Wait()
+	spends, err := txm.FeeSpend(ctx, SpendFilter{AccountAddress: account})
+	require.NoError(t, err)
+	require.Len(t, spends, 1)
+	assert.Equal(t, 1, spends[0].Calls)
+	entries, err := txm.ledger.Entries(SpendFilter{AccountAddress: account})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "0x1", entries[0].Hash)
+}
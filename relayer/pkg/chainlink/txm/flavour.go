@@ -0,0 +1,254 @@
+package txm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetaccount "github.com/NethermindEth/starknet.go/account"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// AccountFlavour is the account contract implementation of a transmitter account. It selects the calldata layout,
+// the signature layout and the transaction version of the invokes of the account.
+type AccountFlavour string
+
+const (
+	// FlavourOZ is the OpenZeppelin account (Cairo 1). Invokes are V3 txs signed with [r, s].
+	FlavourOZ AccountFlavour = "OpenZeppelin"
+	// FlavourArgent is the Argent account (Cairo 1). Invokes are V3 txs signed by the owner with [r, s], followed by
+	// the signature of the guardian if the account has one.
+	FlavourArgent AccountFlavour = "Argent"
+	// FlavourBraavos is the Braavos account (Cairo 1) with a single stark signer. Invokes are V3 txs signed with [r, s].
+	FlavourBraavos AccountFlavour = "Braavos"
+	// FlavourCairo0 is a legacy Cairo 0 account (OpenZeppelin or Argent). Invokes are V1 txs paid in ETH, with the
+	// Cairo 0 multicall calldata layout, and co-signed by the guardian of Argent accounts that have one.
+	FlavourCairo0 AccountFlavour = "Cairo0"
+)
+
+// ETHFeeToken is the ERC20 token that pays the fees of V1 txs.
+const ETHFeeToken = "0x049d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7"
+
+// IsValid returns true if f is a supported flavour.
+func (f AccountFlavour) IsValid() bool {
+	switch f {
+	case FlavourOZ, FlavourArgent, FlavourBraavos, FlavourCairo0:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasGuardian returns true if accounts of flavour f may be guardian-protected.
+func (f AccountFlavour) HasGuardian() bool {
+	return f == FlavourArgent || f == FlavourCairo0
+}
+
+func (f AccountFlavour) cairoVersion() int {
+	if f == FlavourCairo0 {
+		return 0
+	}
+	return 2
+}
+
+// legacy returns true if accounts of flavour f only accept V1 invokes.
+func (f AccountFlavour) legacy() bool {
+	return f == FlavourCairo0
+}
+
+// accountFlavour returns the flavour configured for accountAddress, accounts without one are OpenZeppelin accounts.
+func (txm *starktxm) accountFlavour(accountAddress *felt.Felt) AccountFlavour {
+	flavour := AccountFlavour(txm.cfg.AccountFlavour(accountAddress))
+	if flavour == "" {
+		return FlavourOZ
+	}
+	return flavour
+}
+
+// FeeToken returns the token that pays the fees of accountAddress.
+func (txm *starktxm) FeeToken(accountAddress *felt.Felt) string {
+	if txm.accountFlavour(accountAddress).legacy() {
+		return ETHFeeToken
+	}
+	return txm.cfg.FeeToken()
+}
+
+// feeUnit returns the unit of the fee token of accountAddress, see FeeToken.
+func (txm *starktxm) feeUnit(accountAddress *felt.Felt) starknetrpc.FeePaymentUnit {
+	if txm.accountFlavour(accountAddress).legacy() {
+		return starknetrpc.UnitWei
+	}
+	return starknetrpc.UnitStrk
+}
+
+// invokeTx is an unsigned invoke in the transaction version of the flavour of its account, exactly one of v1 and
+// v3 is set.
+type invokeTx struct {
+	v1 *starknetrpc.InvokeTxnV1
+	v3 *starknetrpc.InvokeTxnV3
+}
+
+// newInvokeTx builds an unsigned invoke of calls, the nonce and fees are filled in by the caller.
+func newInvokeTx(account *starknetaccount.Account, flavour AccountFlavour, calls []starknetrpc.FunctionCall) (*invokeTx, error) {
+	// Building the Calldata with the help of FmtCalldata where we pass in the FnCall struct along with the Cairo version
+	calldata, err := account.FmtCalldata(calls)
+	if err != nil {
+		return nil, err
+	}
+
+	if flavour.legacy() {
+		return &invokeTx{v1: &starknetrpc.InvokeTxnV1{
+			Type:          starknetrpc.TransactionType_Invoke,
+			SenderAddress: account.AccountAddress,
+			Version:       starknetrpc.TransactionV1,
+			Signature:     []*felt.Felt{},
+			Nonce:         &felt.Zero, // filled in below
+			MaxFee:        &felt.Zero, // filled in below
+			Calldata:      calldata,
+		}}, nil
+	}
+	return &invokeTx{v3: &starknetrpc.InvokeTxnV3{
+		Type:          starknetrpc.TransactionType_Invoke,
+		SenderAddress: account.AccountAddress,
+		Version:       starknetrpc.TransactionV3,
+		Signature:     []*felt.Felt{},
+		Nonce:         &felt.Zero, // filled in below
+		ResourceBounds: starknetrpc.ResourceBoundsMapping{
+			L1Gas: starknetrpc.ResourceBounds{
+				MaxAmount:       "0x0",
+				MaxPricePerUnit: "0x0",
+			},
+			L2Gas: starknetrpc.ResourceBounds{
+				MaxAmount:       "0x0",
+				MaxPricePerUnit: "0x0",
+			},
+		},
+		Tip:                   "0x0",
+		PayMasterData:         []*felt.Felt{},
+		AccountDeploymentData: []*felt.Felt{},
+		NonceDataMode:         starknetrpc.DAModeL1,
+		FeeMode:               starknetrpc.DAModeL1,
+		Calldata:              calldata,
+	}}, nil
+}
+
+// txn returns the invoke for estimation, simulation and broadcast.
+func (tx *invokeTx) txn() starknetrpc.Transaction {
+	if tx.v1 != nil {
+		return *tx.v1
+	}
+	return *tx.v3
+}
+
+func (tx *invokeTx) senderAddress() *felt.Felt {
+	if tx.v1 != nil {
+		return tx.v1.SenderAddress
+	}
+	return tx.v3.SenderAddress
+}
+
+func (tx *invokeTx) nonce() *felt.Felt {
+	if tx.v1 != nil {
+		return tx.v1.Nonce
+	}
+	return tx.v3.Nonce
+}
+
+func (tx *invokeTx) setNonce(nonce *felt.Felt) {
+	if tx.v1 != nil {
+		tx.v1.Nonce = nonce
+		return
+	}
+	tx.v3.Nonce = nonce
+}
+
+// feeUnit is the unit of the fee estimates of the tx, V1 txs are paid in ETH.
+func (tx *invokeTx) feeUnit() string {
+	if tx.v1 != nil {
+		return string(starknetrpc.UnitWei)
+	}
+	return string(starknetrpc.UnitStrk)
+}
+
+// setFees sets the max fee of a V1 tx, or the resource bounds and tip of a V3 tx.
+func (tx *invokeTx) setFees(params fees.Params) {
+	if tx.v1 != nil {
+		tx.v1.MaxFee = starknetutils.BigIntToFelt(params.MaxFee())
+		return
+	}
+	tx.v3.ResourceBounds = params.ResourceBoundsMapping()
+	tx.v3.Tip = starknetrpc.U64(fmt.Sprintf("0x%x", params.Tip))
+}
+
+func (tx *invokeTx) setSignature(signature []*felt.Felt) {
+	if tx.v1 != nil {
+		tx.v1.Signature = signature
+		return
+	}
+	tx.v3.Signature = signature
+}
+
+// invokeFees computes the fees of an invoke from its estimate, see estimateFees. The fees of V1 txs are always
+// padded, since the other FeeEstimator modes are based on FRI prices, and are limited by MaxFeeWei.
+func (txm *starktxm) invokeFees(ctx context.Context, client *starknet.Client, tx *invokeTx, estimate *starknetrpc.FeeEstimate, attempts int) (fees.Params, error) {
+	if tx.v3 != nil {
+		return txm.estimateFees(ctx, client, estimate, attempts)
+	}
+	cfg := txm.cfg.FeeEstimator()
+	estimator, err := fees.NewEstimator(fees.Config{Mode: fees.ModePadded, AmountPaddingPercent: cfg.AmountPaddingPercent, PricePaddingPercent: cfg.PricePaddingPercent})
+	if err != nil {
+		return fees.Params{}, fmt.Errorf("failed to create fee estimator: %+w", err)
+	}
+	params, err := estimator.Estimate(ctx, client, estimate)
+	if err != nil {
+		return fees.Params{}, fmt.Errorf("failed to estimate fees: %+w", err)
+	}
+	for i := 0; i < attempts; i++ {
+		params = params.Bump(txm.cfg.FeeBumpPercent())
+	}
+
+	if maxFee := cfg.MaxFeeWei; maxFee != nil && params.MaxFee().Cmp(maxFee) > 0 {
+		return fees.Params{}, fmt.Errorf("%w: max fee %s WEI, limit %s WEI", fees.ErrExceedsMax, params.MaxFee(), maxFee)
+	}
+	return params, nil
+}
+
+// signInvoke signs the tx, see signHash.
+func (txm *starktxm) signInvoke(ctx context.Context, client *starknet.Client, account *starknetaccount.Account, tx *invokeTx) error {
+	hash, err := account.TransactionHashInvoke(tx.txn())
+	if err != nil {
+		return err
+	}
+	signature, err := txm.signHash(ctx, client, account, hash)
+	if err != nil {
+		return err
+	}
+	tx.setSignature(signature)
+	return nil
+}
+
+// signHash signs hash with the account key, and with the guardian key if one is configured for the account.
+func (txm *starktxm) signHash(ctx context.Context, client *starknet.Client, account *starknetaccount.Account, hash *felt.Felt) ([]*felt.Felt, error) {
+	signature, err := account.Sign(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if guardian := txm.cfg.AccountGuardian(account.AccountAddress); guardian != nil {
+		flavour := txm.accountFlavour(account.AccountAddress)
+		guardianAccount, err := starknetaccount.NewAccount(client.Provider, account.AccountAddress, guardian.String(), txm.ks, flavour.cairoVersion())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create guardian account: %+w", err)
+		}
+		guardianSignature, err := guardianAccount.Sign(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign as guardian: %+w", err)
+		}
+		signature = append(signature, guardianSignature...)
+	}
+	return signature, nil
+}
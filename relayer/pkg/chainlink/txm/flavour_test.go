@@ -0,0 +1,164 @@
+package txm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+func TestTxm_AccountFlavours(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.Unmarshal(body, &req))
+		assert.Equal(t, "starknet_chainId", req.Method)
+		_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, "result": "0x534e5f5345504f4c4941"}`, req.ID)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	accountAddress := new(felt.Felt).SetUint64(1)
+	owner := new(felt.Felt).SetUint64(2)
+	guardian := new(felt.Felt).SetUint64(3)
+	call := starknetrpc.FunctionCall{
+		ContractAddress:    new(felt.Felt).SetUint64(4),
+		EntryPointSelector: new(felt.Felt).SetUint64(5),
+		Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(6)},
+	}
+	params := fees.Params{
+		L1Gas: fees.ResourceBound{MaxAmount: 10, MaxPricePerUnit: big.NewInt(3)},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		flavour   string
+		guardian  *felt.Felt
+		calldata  []uint64
+		signature int
+	}{
+		{name: "default", flavour: "", calldata: []uint64{1, 4, 5, 1, 6}, signature: 2},
+		{name: "braavos", flavour: string(FlavourBraavos), calldata: []uint64{1, 4, 5, 1, 6}, signature: 2},
+		{name: "argent with guardian", flavour: string(FlavourArgent), guardian: guardian, calldata: []uint64{1, 4, 5, 1, 6}, signature: 4},
+		{name: "cairo0", flavour: string(FlavourCairo0), calldata: []uint64{1, 4, 5, 0, 1, 1, 6}, signature: 2},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			txm := newTestTxm(t, owner, guardian)
+			cfg := txm.cfg.(*mocks.Config)
+			// replace the defaults of newTestTxm
+			var defaults []*mock.Call
+			for _, c := range cfg.ExpectedCalls {
+				if c.Method == "AccountFlavour" || c.Method == "AccountGuardian" {
+					defaults = append(defaults, c)
+				}
+			}
+			for _, c := range defaults {
+				c.Unset()
+			}
+			cfg.On("AccountFlavour", mock.Anything).Return(tc.flavour)
+			cfg.On("AccountGuardian", mock.Anything).Return(tc.guardian)
+			client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+			require.NoError(t, err)
+
+			flavour := txm.accountFlavour(accountAddress)
+			account, err := txm.newAccount(client, accountAddress, owner)
+			require.NoError(t, err)
+			tx, err := newInvokeTx(account, flavour, []starknetrpc.FunctionCall{call})
+			require.NoError(t, err)
+			tx.setNonce(new(felt.Felt).SetUint64(7))
+			tx.setFees(params)
+			require.NoError(t, txm.signInvoke(tests.Context(t), client, account, tx))
+
+			var calldata, signature []*felt.Felt
+			if flavour == FlavourCairo0 {
+				require.NotNil(t, tx.v1)
+				assert.Equal(t, ETHFeeToken, txm.FeeToken(accountAddress))
+				assert.Equal(t, string(starknetrpc.UnitWei), tx.feeUnit())
+				assert.Equal(t, params.MaxFee(), tx.v1.MaxFee.BigInt(new(big.Int)))
+				calldata, signature = tx.v1.Calldata, tx.v1.Signature
+			} else {
+				require.NotNil(t, tx.v3)
+				assert.Equal(t, string(starknetrpc.UnitStrk), tx.feeUnit())
+				calldata, signature = tx.v3.Calldata, tx.v3.Signature
+			}
+			require.Len(t, calldata, len(tc.calldata))
+			for i, v := range tc.calldata {
+				assert.Equal(t, new(felt.Felt).SetUint64(v), calldata[i], "calldata %d", i)
+			}
+			assert.Len(t, signature, tc.signature)
+			assert.Equal(t, new(felt.Felt).SetUint64(7), tx.nonce())
+		})
+	}
+}
+
+func TestTxm_InvokeFees(t *testing.T) {
+	t.Parallel()
+
+	// 10 gas at 3 WEI, padded by 150% to 15 gas at 4 WEI
+	estimate := &starknetrpc.FeeEstimate{
+		GasConsumed:     new(felt.Felt).SetUint64(10),
+		GasPrice:        new(felt.Felt).SetUint64(3),
+		DataGasConsumed: new(felt.Felt),
+		DataGasPrice:    new(felt.Felt),
+	}
+	v1 := &invokeTx{v1: &starknetrpc.InvokeTxnV1{}}
+
+	for _, tc := range []struct {
+		name      string
+		maxFeeWei *big.Int
+		err       error
+	}{
+		{name: "no limit"},
+		{name: "within limit", maxFeeWei: big.NewInt(60)},
+		{name: "exceeds limit", maxFeeWei: big.NewInt(59), err: fees.ErrExceedsMax},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			txm := newTestTxm(t)
+			cfg := txm.cfg.(*mocks.Config)
+			for _, c := range cfg.ExpectedCalls {
+				if c.Method == "FeeEstimator" {
+					c.Unset()
+					break
+				}
+			}
+			// the FRI limit doesn't apply to WEI fees
+			cfg.On("FeeEstimator").Return(fees.Config{Mode: fees.ModeFixed, AmountPaddingPercent: 150, PricePaddingPercent: 150, MaxFee: big.NewInt(1), MaxFeeWei: tc.maxFeeWei})
+
+			params, err := txm.invokeFees(tests.Context(t), nil, v1, estimate, 0)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, big.NewInt(60), params.MaxFee())
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package txm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+)
+
+// FeeEntry is the share of the actual fee of an accepted invoke that is attributed to one of its calls. The fee of
+// a multicall invoke is split evenly across its calls, the first call gets the remainder.
+type FeeEntry struct {
+	Time            time.Time
+	TxID            string
+	Hash            string
+	AccountAddress  *felt.Felt
+	ContractAddress *felt.Felt
+	Selector        *felt.Felt
+	Amount          *big.Int
+	// Unit is the unit of Amount, FRI or WEI
+	Unit string
+}
+
+// SpendFilter selects the fee entries that are aggregated by FeeSpend. Nil addresses and zero times match everything,
+// From is inclusive and To is exclusive.
+type SpendFilter struct {
+	AccountAddress  *felt.Felt
+	ContractAddress *felt.Felt
+	From            time.Time
+	To              time.Time
+}
+
+func (f SpendFilter) matches(e FeeEntry) bool {
+	switch {
+	case f.AccountAddress != nil && !f.AccountAddress.Equal(e.AccountAddress):
+		return false
+	case f.ContractAddress != nil && !f.ContractAddress.Equal(e.ContractAddress):
+		return false
+	case !f.From.IsZero() && e.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.Time.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// FeeSpend is the fee spent by an account on the calls to a contract selector.
+type FeeSpend struct {
+	AccountAddress  *felt.Felt
+	ContractAddress *felt.Felt
+	Selector        *felt.Felt
+	Unit            string
+	Amount          *big.Int
+	// Calls is the number of accepted calls the fee was paid for
+	Calls int
+}
+
+// FeeLedger keeps the fee entries of accepted txs for reconciliation.
+type FeeLedger interface {
+	// Record adds entries and returns the added ones, entries of a tx hash and ID that was already recorded are ignored.
+	Record(entries ...FeeEntry) ([]FeeEntry, error)
+	// Entries returns the recorded entries that match filter, oldest first.
+	Entries(filter SpendFilter) ([]FeeEntry, error)
+	Close() error
+}
+
+// entries are pruned after this many records. For the file ledger the log is rewritten at the same time.
+const feeLedgerPruneInterval = 1000
+
+var _ FeeLedger = (*feeLedger)(nil)
+
+// feeLedger keeps the entries of the retention period in memory, and appends them to the file at path if it is set.
+// The file is rewritten without the expired entries when the ledger is opened and whenever entries are pruned.
+type feeLedger struct {
+	lock sync.Mutex
+
+	path      string
+	file      *os.File
+	entries   []FeeEntry
+	keys      map[string]bool
+	records   int
+	retention time.Duration
+}
+
+// NewMemoryFeeLedger returns a FeeLedger that only lives in memory, entries older than retention are dropped.
+func NewMemoryFeeLedger(retention time.Duration) FeeLedger {
+	return &feeLedger{keys: map[string]bool{}, retention: retention}
+}
+
+// NewFileFeeLedger opens (or creates) the fee ledger at path, entries older than retention are dropped.
+func NewFileFeeLedger(path string, retention time.Duration) (FeeLedger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create fee ledger directory: %w", err)
+	}
+	l := &feeLedger{path: path, keys: map[string]bool{}, retention: retention}
+	if err := l.replay(); err != nil {
+		return nil, err
+	}
+	if err := l.prune(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// feeLedgerPath returns the path of the fee ledger kept next to the tx storage at txStoragePath.
+func feeLedgerPath(txStoragePath string) string {
+	return txStoragePath + ".fees"
+}
+
+func feeEntryKey(e FeeEntry) string {
+	return e.Hash + "/" + e.TxID
+}
+
+func (l *feeLedger) replay() error {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open fee ledger: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry FeeEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// only the last line can be partially written
+			break
+		}
+		l.add(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read fee ledger: %w", err)
+	}
+	return nil
+}
+
+// add keeps entry in memory and returns false if it was already recorded. Must be called with the lock held.
+func (l *feeLedger) add(entry FeeEntry) bool {
+	key := feeEntryKey(entry)
+	if l.keys[key] {
+		return false
+	}
+	l.keys[key] = true
+	l.entries = append(l.entries, entry)
+	return true
+}
+
+// prune drops the expired entries and rewrites the file. Must be called with the lock held.
+func (l *feeLedger) prune() error {
+	cutoff := time.Now().Add(-l.retention)
+	live := l.entries[:0]
+	for _, entry := range l.entries {
+		if entry.Time.Before(cutoff) {
+			delete(l.keys, feeEntryKey(entry))
+			continue
+		}
+		live = append(live, entry)
+	}
+	l.entries = live
+	l.records = 0
+	if l.path == "" {
+		return nil
+	}
+
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return fmt.Errorf("failed to close fee ledger: %w", err)
+		}
+		l.file = nil
+	}
+	tmpPath := l.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create pruned fee ledger: %w", err)
+	}
+	err = writeFeeEntries(tmp, l.entries)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write pruned fee ledger: %w", err)
+	}
+	if err = os.Rename(tmpPath, l.path); err != nil {
+		return fmt.Errorf("failed to replace fee ledger: %w", err)
+	}
+	l.file, err = os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open fee ledger: %w", err)
+	}
+	return nil
+}
+
+func (l *feeLedger) Record(entries ...FeeEntry) ([]FeeEntry, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.path != "" && l.file == nil {
+		return nil, errors.New("fee ledger is closed")
+	}
+	var added []FeeEntry
+	for _, entry := range entries {
+		if l.add(entry) {
+			added = append(added, entry)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if l.path != "" {
+		if err := writeFeeEntries(l.file, added); err != nil {
+			return added, fmt.Errorf("failed to write to fee ledger: %w", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return added, fmt.Errorf("failed to sync fee ledger: %w", err)
+		}
+	}
+
+	l.records += len(added)
+	if l.records > feeLedgerPruneInterval {
+		return added, l.prune()
+	}
+	return added, nil
+}
+
+func (l *feeLedger) Entries(filter SpendFilter) ([]FeeEntry, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	entries := []FeeEntry{}
+	for _, entry := range l.entries {
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+func (l *feeLedger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func writeFeeEntries(f *os.File, entries []FeeEntry) error {
+	w := bufio.NewWriter(f)
+	for _, entry := range entries {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode fee entry: %w", err)
+		}
+		if _, err = w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// splitFee splits amount evenly into n shares, the first share gets the remainder.
+func splitFee(amount *big.Int, n int) []*big.Int {
+	if n <= 0 {
+		return nil
+	}
+	share, remainder := new(big.Int).QuoRem(amount, big.NewInt(int64(n)), new(big.Int))
+	shares := make([]*big.Int, n)
+	for i := range shares {
+		shares[i] = new(big.Int).Set(share)
+	}
+	shares[0].Add(shares[0], remainder)
+	return shares
+}
+
+// aggregateSpend sums entries by account, contract, selector and unit, ordered by account, contract and selector.
+func aggregateSpend(entries []FeeEntry) []FeeSpend {
+	spends := map[string]*FeeSpend{}
+	var keys []string
+	for _, e := range entries {
+		key := fmt.Sprintf("%s/%s/%s/%s", e.AccountAddress, e.ContractAddress, e.Selector, e.Unit)
+		spend, ok := spends[key]
+		if !ok {
+			spend = &FeeSpend{
+				AccountAddress:  e.AccountAddress,
+				ContractAddress: e.ContractAddress,
+				Selector:        e.Selector,
+				Unit:            e.Unit,
+				Amount:          new(big.Int),
+			}
+			spends[key] = spend
+			keys = append(keys, key)
+		}
+		spend.Amount.Add(spend.Amount, e.Amount)
+		spend.Calls++
+	}
+	sort.Strings(keys)
+	result := make([]FeeSpend, len(keys))
+	for i, key := range keys {
+		result[i] = *spends[key]
+	}
+	return result
+}
+
+// FeeSpend aggregates the actual fees recorded in the fee ledger that match filter.
+func (txm *starktxm) FeeSpend(ctx context.Context, filter SpendFilter) ([]FeeSpend, error) {
+	entries, err := txm.ledger.Entries(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fee entries: %+w", err)
+	}
+	return aggregateSpend(entries), nil
+}
+
+// recordFees records the fee of a final tx in the fee ledger, attributed to the calls of its records.
+func (txm *starktxm) recordFees(accountAddress *felt.Felt, ids []string, hash string, fee starknetrpc.FeePayment) {
+	if fee.Amount == nil {
+		return
+	}
+	var records []TxRecord
+	for _, id := range ids {
+		record, err := txm.storage.Get(id)
+		if err != nil {
+			txm.lggr.Warnw("failed to load record of accepted tx", "id", id, "error", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	now := time.Now()
+	unit := string(fee.Unit)
+	shares := splitFee(fee.Amount.BigInt(new(big.Int)), len(records))
+	entries := make([]FeeEntry, len(records))
+	for i, record := range records {
+		entries[i] = FeeEntry{
+			Time:            now,
+			TxID:            record.ID,
+			Hash:            hash,
+			AccountAddress:  accountAddress,
+			ContractAddress: record.Call.ContractAddress,
+			Selector:        record.Call.EntryPointSelector,
+			Amount:          shares[i],
+			Unit:            unit,
+		}
+	}
+	added, err := txm.ledger.Record(entries...)
+	if err != nil {
+		txm.lggr.Errorw("failed to record fees", "hash", hash, "error", err)
+	}
+	// a fee is counted once, even if its tx is seen again after a restart
+	for _, e := range added {
+		amount, _ := new(big.Float).SetInt(e.Amount).Float64()
+		promFeeSpent.WithLabelValues(txm.chainID, e.AccountAddress.String(), e.ContractAddress.String(), e.Selector.String(), e.Unit).Add(amount)
+	}
+}
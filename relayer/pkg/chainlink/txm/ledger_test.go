@@ -0,0 +1,135 @@
+package txm
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+)
+
+func testFeeEntry(id string, contract uint64, amount int64, at time.Time) FeeEntry {
+	return FeeEntry{
+		Time:            at,
+		TxID:            id,
+		Hash:            "0x" + id,
+		AccountAddress:  new(felt.Felt).SetUint64(1),
+		ContractAddress: new(felt.Felt).SetUint64(contract),
+		Selector:        new(felt.Felt).SetUint64(4),
+		Amount:          big.NewInt(amount),
+		Unit:            string(starknetrpc.UnitStrk),
+	}
+}
+
+func TestFeeLedger(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	t.Run("memory", func(t *testing.T) {
+		t.Parallel()
+
+		l := NewMemoryFeeLedger(time.Hour)
+		added, err := l.Record(testFeeEntry("b", 3, 2, now), testFeeEntry("a", 3, 1, now.Add(-time.Minute)))
+		require.NoError(t, err)
+		assert.Len(t, added, 2)
+		// recorded again, e.g. after a restart
+		added, err = l.Record(testFeeEntry("a", 3, 1, now.Add(-time.Minute)), testFeeEntry("c", 5, 4, now))
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+		assert.Equal(t, "c", added[0].TxID)
+
+		entries, err := l.Entries(SpendFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "a", entries[0].TxID)
+
+		entries, err = l.Entries(SpendFilter{ContractAddress: new(felt.Felt).SetUint64(3), From: now.Add(-time.Second)})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "b", entries[0].TxID)
+
+		entries, err = l.Entries(SpendFilter{AccountAddress: new(felt.Felt).SetUint64(2)})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "txs.log.fees")
+		l, err := NewFileFeeLedger(path, time.Hour)
+		require.NoError(t, err)
+		_, err = l.Record(testFeeEntry("a", 3, 1, now.Add(-2*time.Hour)), testFeeEntry("b", 3, 2, now))
+		require.NoError(t, err)
+		entries, err := l.Entries(SpendFilter{})
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+		require.NoError(t, l.Close())
+		_, err = l.Record(testFeeEntry("c", 3, 1, now))
+		require.Error(t, err)
+
+		// expired entries are dropped on open
+		l, err = NewFileFeeLedger(path, time.Hour)
+		require.NoError(t, err)
+		t.Cleanup(func() { assert.NoError(t, l.Close()) })
+		entries, err = l.Entries(SpendFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "b", entries[0].TxID)
+		assert.Equal(t, big.NewInt(2), entries[0].Amount)
+		assert.Equal(t, "0x3", entries[0].ContractAddress.String())
+	})
+}
+
+func TestSplitFee(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []*big.Int{big.NewInt(4), big.NewInt(3), big.NewInt(3)}, splitFee(big.NewInt(10), 3))
+	assert.Equal(t, []*big.Int{big.NewInt(7)}, splitFee(big.NewInt(7), 1))
+	assert.Nil(t, splitFee(big.NewInt(7), 0))
+}
+
+func TestTxm_FeeSpend(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	txm := newTestTxm(t)
+	// unique per test, the fee metric is global
+	account := new(felt.Felt).SetUint64(0xacc017)
+	now := time.Now()
+
+	// a multicall invoke of two calls to contract 3 and one call to contract 5, and a single call to contract 3
+	for id, contract := range map[string]uint64{"a": 3, "b": 3, "c": 5, "d": 3} {
+		record := testRecord(id, TxConfirmed, now)
+		record.AccountAddress = account
+		record.Call.ContractAddress = new(felt.Felt).SetUint64(contract)
+		require.NoError(t, txm.storage.Save(record))
+	}
+	txm.recordFees(account, []string{"a", "b", "c"}, "0x1", starknetrpc.FeePayment{Amount: new(felt.Felt).SetUint64(31), Unit: starknetrpc.UnitStrk})
+	txm.recordFees(account, []string{"d"}, "0x2", starknetrpc.FeePayment{Amount: new(felt.Felt).SetUint64(5), Unit: starknetrpc.UnitStrk})
+	txm.recordFees(account, []string{"unknown"}, "0x3", starknetrpc.FeePayment{Amount: new(felt.Felt).SetUint64(5), Unit: starknetrpc.UnitStrk})
+
+	spends, err := txm.FeeSpend(ctx, SpendFilter{AccountAddress: account})
+	require.NoError(t, err)
+	require.Len(t, spends, 2)
+	assert.Equal(t, "0x3", spends[0].ContractAddress.String())
+	assert.Equal(t, big.NewInt(11+10+5), spends[0].Amount)
+	assert.Equal(t, 3, spends[0].Calls)
+	assert.Equal(t, "0x5", spends[1].ContractAddress.String())
+	assert.Equal(t, big.NewInt(10), spends[1].Amount)
+	assert.Equal(t, string(starknetrpc.UnitStrk), spends[1].Unit)
+
+	spends, err = txm.FeeSpend(ctx, SpendFilter{AccountAddress: account, To: now})
+	require.NoError(t, err)
+	assert.Empty(t, spends)
+
+	assert.InDelta(t, 26, testutil.ToFloat64(promFeeSpent.WithLabelValues(txm.chainID, account.String(), "0x3", "0x4", string(starknetrpc.UnitStrk))), 0)
+}
@@ -0,0 +1,144 @@
+package txm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+)
+
+var (
+	promTxRevertReasons = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_tx_revert_reasons",
+		Help: "Number of reverted and rejected txs by decoded reason",
+	}, []string{"chain_id", "account_address", "status", "kind"})
+	promQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "starknet_txm_queue_depth",
+		Help: "Number of txs of an account waiting to be broadcast",
+	}, []string{"chain_id", "account_address"})
+	promBroadcasts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_broadcasts",
+		Help: "Number of invoke broadcasts by result, either success or the class of the error",
+	}, []string{"chain_id", "account_address", "result"})
+	promConfirmationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "starknet_txm_tx_confirmation_seconds",
+		Help:    "Time from enqueue until a tx is accepted",
+		Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+	}, []string{"chain_id", "account_address"})
+	promFeePaid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_fee_paid_fri",
+		Help: "Sum of the actual fees of accepted txs in FRI",
+	}, []string{"chain_id", "account_address"})
+	promFeeSpent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_fee_spent",
+		Help: "Sum of the actual fees of accepted txs by target contract and selector of their calls, in the fee unit",
+	}, []string{"chain_id", "account_address", "contract_address", "selector", "unit"})
+	promFeeEstimated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_fee_estimated_fri",
+		Help: "Sum of the estimated fees of accepted txs in FRI",
+	}, []string{"chain_id", "account_address"})
+	promFeePaidRatio = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "starknet_txm_fee_paid_to_estimated_ratio",
+		Help:    "Ratio of the actual fee of an accepted tx to its estimated fee",
+		Buckets: []float64{0.25, 0.5, 0.75, 0.9, 1, 1.1, 1.25, 1.5, 2, 3},
+	}, []string{"chain_id", "account_address"})
+	promNonceResyncs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_nonce_resyncs",
+		Help: "Number of times the locally tracked nonce of an account was reset to the nonce of the node",
+	}, []string{"chain_id", "account_address"})
+	promStaleTxDrops = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_stale_tx_drops",
+		Help: "Number of broadcast txs that were dropped after a nonce resync",
+	}, []string{"chain_id", "account_address"})
+	promTxDeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_tx_dead_letters",
+		Help: "Number of txs abandoned to the dead letters by cause",
+	}, []string{"chain_id", "account_address", "cause"})
+	promTxReorgs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "starknet_txm_tx_reorgs",
+		Help: "Number of accepted txs that disappeared from L2 before reaching L1 finality and were queued again",
+	}, []string{"chain_id", "account_address"})
+	promAccountBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "starknet_txm_account_balance",
+		Help: "Last checked fee token balance of an account, in the unit of its fee token",
+	}, []string{"chain_id", "account_address", "unit"})
+	promAccountPaused = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "starknet_txm_account_paused",
+		Help: "Set to 1 while the broadcasts of an account are paused for an insufficient balance",
+	}, []string{"chain_id", "account_address"})
+)
+
+const (
+	broadcastSuccess = "success"
+	// error classes of failed broadcasts
+	broadcastErrClient             = "client_unavailable"
+	broadcastErrEstimate           = "estimate_failed"
+	broadcastErrSimulation         = "simulation_revert"
+	broadcastErrMaxFee             = "max_fee_exceeded"
+	broadcastErrNonce              = "invalid_nonce"
+	broadcastErrInsufficientFunds  = "insufficient_balance"
+	broadcastErrInsufficientMaxFee = "insufficient_max_fee"
+	broadcastErrAccountNotDeployed = "account_not_deployed"
+	broadcastErrDeployPending      = "deploy_pending"
+	broadcastErrOther              = "other"
+)
+
+// broadcastErrorClass classifies a broadcast error for the result label of promBroadcasts.
+func broadcastErrorClass(err error) string {
+	var simErr *SimulationError
+	var rpcErr *starknetrpc.RPCError
+	switch {
+	case errors.As(err, &simErr):
+		return broadcastErrSimulation
+	case errors.Is(err, fees.ErrExceedsMax):
+		return broadcastErrMaxFee
+	case errors.Is(err, ErrAccountNotDeployed):
+		return broadcastErrAccountNotDeployed
+	case errors.Is(err, errDeployPending):
+		return broadcastErrDeployPending
+	case errors.Is(err, errEstimateFailed):
+		return broadcastErrEstimate
+	case strings.Contains(err.Error(), RPCNonceErrMsg),
+		errors.As(err, &rpcErr) && strings.Contains(fmt.Sprintf("%+v", rpcErr.Data), RPCNonceErrMsg):
+		return broadcastErrNonce
+	case errors.Is(err, ErrInsufficientBalance), isInsufficientBalance(err):
+		return broadcastErrInsufficientFunds
+	case errors.As(err, &rpcErr) && rpcErr.Code == starknetrpc.ErrInsufficientMaxFee.Code:
+		return broadcastErrInsufficientMaxFee
+	default:
+		return broadcastErrOther
+	}
+}
+
+// receiptActualFee returns the actual fee in FRI charged for a tx, and false if the receipt has none.
+func receiptActualFee(receipt starknetrpc.TransactionReceipt) (*big.Int, bool) {
+	fee, ok := receiptFeePayment(receipt)
+	if !ok || fee.Unit != starknetrpc.UnitStrk {
+		return nil, false
+	}
+	return fee.Amount.BigInt(new(big.Int)), true
+}
+
+// receiptFeePayment returns the actual fee charged for a tx with its unit, and false if the receipt has none.
+func receiptFeePayment(receipt starknetrpc.TransactionReceipt) (starknetrpc.FeePayment, bool) {
+	var fee starknetrpc.FeePayment
+	switch r := receipt.(type) {
+	case *starknetrpc.TransactionReceiptWithBlockInfo:
+		return receiptFeePayment(r.TransactionReceipt)
+	case starknetrpc.InvokeTransactionReceipt:
+		fee = r.ActualFee
+	case starknetrpc.DeployAccountTransactionReceipt:
+		fee = r.ActualFee
+	case starknetrpc.CommonTransactionReceipt:
+		fee = r.ActualFee
+	default:
+		return fee, false
+	}
+	return fee, fee.Amount != nil
+}
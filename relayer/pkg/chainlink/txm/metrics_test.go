@@ -0,0 +1,55 @@
+package txm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+)
+
+func TestBroadcastErrorClass(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err   error
+		class string
+	}{
+		{&SimulationError{Reason: &RevertReason{Kind: RevertStaleReport}}, broadcastErrSimulation},
+		{fmt.Errorf("%w: max fee 2, limit 1", fees.ErrExceedsMax), broadcastErrMaxFee},
+		{fmt.Errorf("first broadcast of account: %w", ErrAccountNotDeployed), broadcastErrAccountNotDeployed},
+		{fmt.Errorf("%w: %w", errEstimateFailed, errors.New("boom")), broadcastErrEstimate},
+		{fmt.Errorf("failed to invoke tx: %w", &starknetrpc.RPCError{Code: 55, Message: "Account validation failed", Data: "Invalid transaction nonce of contract"}), broadcastErrNonce},
+		{fmt.Errorf("failed to invoke tx: %w", &starknetrpc.RPCError{Code: 54, Message: "Account balance is smaller than the transaction's max_fee"}), broadcastErrInsufficientFunds},
+		{fmt.Errorf("failed to invoke tx: %w", &starknetrpc.RPCError{Code: 53, Message: "Max fee is smaller than the minimal transaction cost"}), broadcastErrInsufficientMaxFee},
+		{errors.New("boom"), broadcastErrOther},
+	} {
+		assert.Equal(t, tc.class, broadcastErrorClass(tc.err), tc.err.Error())
+	}
+}
+
+func TestReceiptActualFee(t *testing.T) {
+	t.Parallel()
+
+	fee, ok := receiptActualFee(&starknetrpc.TransactionReceiptWithBlockInfo{
+		TransactionReceipt: starknetrpc.InvokeTransactionReceipt{
+			ActualFee: starknetrpc.FeePayment{Amount: new(felt.Felt).SetUint64(42), Unit: starknetrpc.UnitStrk},
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(42), fee)
+
+	// fees paid in ETH are not comparable with FRI estimates
+	_, ok = receiptActualFee(starknetrpc.InvokeTransactionReceipt{
+		ActualFee: starknetrpc.FeePayment{Amount: new(felt.Felt).SetUint64(42), Unit: starknetrpc.UnitWei},
+	})
+	assert.False(t, ok)
+
+	_, ok = receiptActualFee(&starknetrpc.TransactionReceiptWithBlockInfo{})
+	assert.False(t, ok)
+}
@@ -3,9 +3,11 @@
 package mocks
 
 import (
-	time "time"
-
+	felt "github.com/NethermindEth/juno/core/felt"
+	fees "github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Config is an autogenerated mock type for the Config type
@@ -13,6 +15,226 @@ type Config struct {
 	mock.Mock
 }
 
+// AccountClass provides a mock function with given fields:
+func (_m *Config) AccountClass() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccountClass")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// AccountClassHash provides a mock function with given fields:
+func (_m *Config) AccountClassHash() *felt.Felt {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccountClassHash")
+	}
+
+	var r0 *felt.Felt
+	if rf, ok := ret.Get(0).(func() *felt.Felt); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	return r0
+}
+
+// AccountFlavour provides a mock function with given fields: accountAddress
+func (_m *Config) AccountFlavour(accountAddress *felt.Felt) string {
+	ret := _m.Called(accountAddress)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccountFlavour")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*felt.Felt) string); ok {
+		r0 = rf(accountAddress)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// AccountGuardian provides a mock function with given fields: accountAddress
+func (_m *Config) AccountGuardian(accountAddress *felt.Felt) *felt.Felt {
+	ret := _m.Called(accountAddress)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccountGuardian")
+	}
+
+	var r0 *felt.Felt
+	if rf, ok := ret.Get(0).(func(*felt.Felt) *felt.Felt); ok {
+		r0 = rf(accountAddress)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	return r0
+}
+
+// AutoDeployAccounts provides a mock function with given fields:
+func (_m *Config) AutoDeployAccounts() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for AutoDeployAccounts")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// BalancePollInterval provides a mock function with given fields:
+func (_m *Config) BalancePollInterval() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BalancePollInterval")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// BatchMaxCalldataLen provides a mock function with given fields:
+func (_m *Config) BatchMaxCalldataLen() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BatchMaxCalldataLen")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// BatchMaxCalls provides a mock function with given fields:
+func (_m *Config) BatchMaxCalls() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BatchMaxCalls")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// BroadcastMaxAttempts provides a mock function with given fields:
+func (_m *Config) BroadcastMaxAttempts() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BroadcastMaxAttempts")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// BroadcastRetryBackoff provides a mock function with given fields:
+func (_m *Config) BroadcastRetryBackoff() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BroadcastRetryBackoff")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// BroadcastWorkers provides a mock function with given fields:
+func (_m *Config) BroadcastWorkers() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BroadcastWorkers")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// ConfirmationBatchSize provides a mock function with given fields:
+func (_m *Config) ConfirmationBatchSize() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmationBatchSize")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
 // ConfirmationPoll provides a mock function with given fields:
 func (_m *Config) ConfirmationPoll() time.Duration {
 	ret := _m.Called()
@@ -31,6 +253,282 @@ func (_m *Config) ConfirmationPoll() time.Duration {
 	return r0
 }
 
+// DrainTimeout provides a mock function with given fields:
+func (_m *Config) DrainTimeout() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DrainTimeout")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// FeeBumpPercent provides a mock function with given fields:
+func (_m *Config) FeeBumpPercent() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeBumpPercent")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// FeeEstimator provides a mock function with given fields:
+func (_m *Config) FeeEstimator() fees.Config {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeEstimator")
+	}
+
+	var r0 fees.Config
+	if rf, ok := ret.Get(0).(func() fees.Config); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(fees.Config)
+	}
+
+	return r0
+}
+
+// FeeLedgerRetention provides a mock function with given fields:
+func (_m *Config) FeeLedgerRetention() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeLedgerRetention")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// FeeToken provides a mock function with given fields:
+func (_m *Config) FeeToken() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeToken")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// FinalityLevel provides a mock function with given fields:
+func (_m *Config) FinalityLevel() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FinalityLevel")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MaxFeeBumps provides a mock function with given fields:
+func (_m *Config) MaxFeeBumps() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaxFeeBumps")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// MaxQueueLenPerAccount provides a mock function with given fields:
+func (_m *Config) MaxQueueLenPerAccount() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaxQueueLenPerAccount")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// RebroadcastTimeout provides a mock function with given fields:
+func (_m *Config) RebroadcastTimeout() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for RebroadcastTimeout")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// ReplaceSelectors provides a mock function with given fields:
+func (_m *Config) ReplaceSelectors() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceSelectors")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// SimulateTxs provides a mock function with given fields:
+func (_m *Config) SimulateTxs() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SimulateTxs")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// SponsorAccount provides a mock function with given fields:
+func (_m *Config) SponsorAccount() *felt.Felt {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SponsorAccount")
+	}
+
+	var r0 *felt.Felt
+	if rf, ok := ret.Get(0).(func() *felt.Felt); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	return r0
+}
+
+// SponsorPublicKey provides a mock function with given fields:
+func (_m *Config) SponsorPublicKey() *felt.Felt {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SponsorPublicKey")
+	}
+
+	var r0 *felt.Felt
+	if rf, ok := ret.Get(0).(func() *felt.Felt); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	return r0
+}
+
+// TxStoragePath provides a mock function with given fields:
+func (_m *Config) TxStoragePath() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxStoragePath")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// TxTTL provides a mock function with given fields:
+func (_m *Config) TxTTL() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxTTL")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
 // TxTimeout provides a mock function with given fields:
 func (_m *Config) TxTimeout() time.Duration {
 	ret := _m.Called()
@@ -0,0 +1,126 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NethermindEth/juno/core/crypto"
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// Sponsorship is the outside execution through which a sponsor account invoked the calls of a transmitter, see
+// SponsorAccount.
+type Sponsorship struct {
+	AccountAddress *felt.Felt
+	PublicKey      *felt.Felt
+	// Call is the execute_from_outside_v2 call of the sponsor invoke
+	Call starknetrpc.FunctionCall
+}
+
+// outsideExecutionWindow is how long a signed outside execution remains valid.
+const outsideExecutionWindow = time.Hour
+
+var errOutsideExecutionUnsupported = errors.New("outside execution is not supported by Cairo0 accounts")
+
+// SNIP-12 revision 1 type hashes of SNIP-9 v2, see https://github.com/starknet-io/SNIPs/blob/main/SNIPS/snip-9.md
+var (
+	starknetDomainTypeHash   = starknetutils.GetSelectorFromNameFelt(`"StarknetDomain"("name":"shortstring","version":"shortstring","chainId":"shortstring","revision":"shortstring")`)
+	outsideExecutionTypeHash = starknetutils.GetSelectorFromNameFelt(`"OutsideExecution"("Caller":"ContractAddress","Nonce":"felt","Execute After":"u128","Execute Before":"u128","Calls":"Call*")"Call"("To":"ContractAddress","Selector":"selector","Calldata":"felt*")`)
+	callTypeHash             = starknetutils.GetSelectorFromNameFelt(`"Call"("To":"ContractAddress","Selector":"selector","Calldata":"felt*")`)
+
+	executeFromOutsideV2Selector = starknetutils.GetSelectorFromNameFelt("execute_from_outside_v2")
+	outsideExecutionDomainName   = new(felt.Felt).SetBytes([]byte("Account.execute_from_outside"))
+	starknetMessagePrefix        = new(felt.Felt).SetBytes([]byte("StarkNet Message"))
+)
+
+// outsideExecution is a SNIP-9 v2 OutsideExecution, it lets caller execute calls on behalf of the signing account.
+type outsideExecution struct {
+	caller        *felt.Felt
+	nonce         *felt.Felt
+	executeAfter  uint64
+	executeBefore uint64
+	calls         []starknetrpc.FunctionCall
+}
+
+// hash returns the SNIP-12 message hash that accountAddress signs on chainID.
+func (e outsideExecution) hash(chainID *felt.Felt, accountAddress *felt.Felt) *felt.Felt {
+	domain := crypto.PoseidonArray(
+		starknetDomainTypeHash,
+		outsideExecutionDomainName,
+		new(felt.Felt).SetUint64(2), // version
+		chainID,
+		new(felt.Felt).SetUint64(1), // revision
+	)
+	callHashes := make([]*felt.Felt, len(e.calls))
+	for i, call := range e.calls {
+		callHashes[i] = crypto.PoseidonArray(callTypeHash, call.ContractAddress, call.EntryPointSelector, crypto.PoseidonArray(call.Calldata...))
+	}
+	message := crypto.PoseidonArray(
+		outsideExecutionTypeHash,
+		e.caller,
+		e.nonce,
+		new(felt.Felt).SetUint64(e.executeAfter),
+		new(felt.Felt).SetUint64(e.executeBefore),
+		crypto.PoseidonArray(callHashes...),
+	)
+	return crypto.PoseidonArray(starknetMessagePrefix, domain, accountAddress, message)
+}
+
+// calldata serializes the arguments of execute_from_outside_v2.
+func (e outsideExecution) calldata(signature []*felt.Felt) []*felt.Felt {
+	calldata := []*felt.Felt{
+		e.caller,
+		e.nonce,
+		new(felt.Felt).SetUint64(e.executeAfter),
+		new(felt.Felt).SetUint64(e.executeBefore),
+		new(felt.Felt).SetUint64(uint64(len(e.calls))),
+	}
+	for _, call := range e.calls {
+		calldata = append(calldata, call.ContractAddress, call.EntryPointSelector, new(felt.Felt).SetUint64(uint64(len(call.Calldata))))
+		calldata = append(calldata, call.Calldata...)
+	}
+	calldata = append(calldata, new(felt.Felt).SetUint64(uint64(len(signature))))
+	return append(calldata, signature...)
+}
+
+// outsideExecutionNonce derives the nonce of the outside execution of a batch from its tx IDs, so that every
+// attempt of the batch uses the same nonce and at most one of them executes.
+func outsideExecutionNonce(ids []string) *felt.Felt {
+	return starknetutils.GetSelectorFromNameFelt(strings.Join(ids, ","))
+}
+
+// sponsorCall wraps the calls of accountAddress into an outside execution signed with publicKey, which the sponsor
+// account invokes with execute_from_outside_v2 and pays the fees for.
+func (txm *starktxm) sponsorCall(ctx context.Context, client *starknet.Client, accountAddress, publicKey, sponsor *felt.Felt,
+	ids []string, calls []starknetrpc.FunctionCall) (starknetrpc.FunctionCall, error) {
+	if txm.accountFlavour(accountAddress).legacy() {
+		return starknetrpc.FunctionCall{}, errOutsideExecutionUnsupported
+	}
+	account, err := txm.newAccount(client, accountAddress, publicKey)
+	if err != nil {
+		return starknetrpc.FunctionCall{}, err
+	}
+
+	execution := outsideExecution{
+		caller:        sponsor,
+		nonce:         outsideExecutionNonce(ids),
+		executeBefore: uint64(time.Now().Add(outsideExecutionWindow).Unix()),
+		calls:         calls,
+	}
+	signature, err := txm.signHash(ctx, client, account, execution.hash(account.ChainId, accountAddress))
+	if err != nil {
+		return starknetrpc.FunctionCall{}, fmt.Errorf("failed to sign outside execution: %+w", err)
+	}
+	return starknetrpc.FunctionCall{
+		ContractAddress:    accountAddress,
+		EntryPointSelector: executeFromOutsideV2Selector,
+		Calldata:           execution.calldata(signature),
+	}, nil
+}
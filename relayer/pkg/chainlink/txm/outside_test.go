@@ -0,0 +1,236 @@
+package txm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+func TestOutsideExecution(t *testing.T) {
+	t.Parallel()
+
+	// type hashes of the SNIP-9 v2 reference implementation
+	assert.Equal(t, "0x1ff2f602e42168014d405a94f75e8a93d640751d71d16311266e140d8b0a210", starknetDomainTypeHash.String())
+	assert.Equal(t, "0x312b56c05a7965066ddbda31c016d8d05afc305071c0ca3cdc2192c3c2f1f0f", outsideExecutionTypeHash.String())
+	assert.Equal(t, "0x3635c7f2a7ba93844c0d064e18e487f35ab90f7c39d00f186a781fc3f0c2ca9", callTypeHash.String())
+
+	execution := outsideExecution{
+		caller:        new(felt.Felt).SetUint64(1),
+		nonce:         new(felt.Felt).SetUint64(2),
+		executeAfter:  3,
+		executeBefore: 4,
+		calls: []starknetrpc.FunctionCall{{
+			ContractAddress:    new(felt.Felt).SetUint64(5),
+			EntryPointSelector: new(felt.Felt).SetUint64(6),
+			Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(7), new(felt.Felt).SetUint64(8)},
+		}},
+	}
+	calldata := execution.calldata([]*felt.Felt{new(felt.Felt).SetUint64(9), new(felt.Felt).SetUint64(10)})
+	expected := []uint64{1, 2, 3, 4, 1, 5, 6, 2, 7, 8, 2, 9, 10}
+	require.Len(t, calldata, len(expected))
+	for i, v := range expected {
+		assert.Equal(t, new(felt.Felt).SetUint64(v), calldata[i], "calldata %d", i)
+	}
+
+	// the hash commits to the chain, the signing account and the calls
+	chainID := new(felt.Felt).SetBytes([]byte("SN_SEPOLIA"))
+	account := new(felt.Felt).SetUint64(11)
+	hash := execution.hash(chainID, account)
+	assert.Equal(t, hash, execution.hash(chainID, account))
+	assert.NotEqual(t, hash, execution.hash(new(felt.Felt).SetBytes([]byte("SN_MAIN")), account))
+	assert.NotEqual(t, hash, execution.hash(chainID, new(felt.Felt).SetUint64(12)))
+	execution.calls = nil
+	assert.NotEqual(t, hash, execution.hash(chainID, account))
+
+	// every attempt of a batch uses the same nonce
+	assert.Equal(t, outsideExecutionNonce([]string{"a", "b"}), outsideExecutionNonce([]string{"a", "b"}))
+	assert.NotEqual(t, outsideExecutionNonce([]string{"a", "b"}), outsideExecutionNonce([]string{"a"}))
+}
+
+func TestTxm_SponsorCall(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.Unmarshal(body, &req))
+		assert.Equal(t, "starknet_chainId", req.Method)
+		_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, "result": "0x534e5f5345504f4c4941"}`, req.ID)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	accountAddress := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+	sponsor := new(felt.Felt).SetUint64(3)
+	calls := []starknetrpc.FunctionCall{{
+		ContractAddress:    new(felt.Felt).SetUint64(4),
+		EntryPointSelector: new(felt.Felt).SetUint64(5),
+		Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(6)},
+	}}
+
+	txm := newTestTxm(t, publicKey)
+	client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+	require.NoError(t, err)
+
+	call, err := txm.sponsorCall(tests.Context(t), client, accountAddress, publicKey, sponsor, []string{"a"}, calls)
+	require.NoError(t, err)
+	assert.Equal(t, accountAddress, call.ContractAddress)
+	assert.Equal(t, executeFromOutsideV2Selector, call.EntryPointSelector)
+	require.Len(t, call.Calldata, 12)
+	assert.Equal(t, sponsor, call.Calldata[0])
+	assert.Equal(t, outsideExecutionNonce([]string{"a"}), call.Calldata[1])
+	assert.Equal(t, &felt.Zero, call.Calldata[2])
+	assert.Positive(t, call.Calldata[3].Cmp(new(felt.Felt).SetUint64(uint64(time.Now().Unix()))))
+	// signature of the fake keystore
+	for i, v := range []uint64{2, 7, 11} {
+		assert.Equal(t, new(felt.Felt).SetUint64(v), call.Calldata[9+i])
+	}
+
+	t.Run("cairo0", func(t *testing.T) {
+		t.Parallel()
+
+		txm := newTestTxm(t, publicKey)
+		cfg := txm.cfg.(*mocks.Config)
+		var defaults []*mock.Call
+		for _, c := range cfg.ExpectedCalls {
+			if c.Method == "AccountFlavour" {
+				defaults = append(defaults, c)
+			}
+		}
+		for _, c := range defaults {
+			c.Unset()
+		}
+		cfg.On("AccountFlavour", mock.Anything).Return(string(FlavourCairo0))
+
+		_, err := txm.sponsorCall(tests.Context(t), client, accountAddress, publicKey, sponsor, []string{"a"}, calls)
+		require.ErrorIs(t, err, errOutsideExecutionUnsupported)
+	})
+}
+
+func TestTxm_RestoreSponsored(t *testing.T) {
+	t.Parallel()
+
+	accountAddress := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+	sponsorship := &Sponsorship{
+		AccountAddress: new(felt.Felt).SetUint64(3),
+		PublicKey:      new(felt.Felt).SetUint64(4),
+		Call: starknetrpc.FunctionCall{
+			ContractAddress:    accountAddress,
+			EntryPointSelector: executeFromOutsideV2Selector,
+			Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(5)},
+		},
+	}
+
+	txm := newTestTxm(t, publicKey)
+	now := time.Now()
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, txm.storage.Save(TxRecord{
+			ID:             id,
+			AccountAddress: accountAddress,
+			PublicKey:      publicKey,
+			Call:           starknetrpc.FunctionCall{ContractAddress: accountAddress, EntryPointSelector: accountAddress},
+			State:          TxBroadcast,
+			Nonce:          new(felt.Felt).SetUint64(6),
+			Hash:           "0x1",
+			Attempts:       []string{"0x1"},
+			Sponsor:        sponsorship,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}))
+	}
+	require.NoError(t, txm.restore())
+
+	// the invoke is restored for the sponsor, which owns its nonce
+	assert.Nil(t, txm.accountStore.GetTxStore(accountAddress))
+	txStore := txm.accountStore.GetTxStore(sponsorship.AccountAddress)
+	require.NotNil(t, txStore)
+	unconfirmed := txStore.GetUnconfirmed()
+	require.Len(t, unconfirmed, 1)
+	assert.ElementsMatch(t, []string{"a", "b"}, unconfirmed[0].IDs)
+	assert.Equal(t, []starknetrpc.FunctionCall{sponsorship.Call}, unconfirmed[0].Calls)
+	assert.Equal(t, sponsorship.PublicKey, unconfirmed[0].PublicKey)
+}
+
+func TestTxm_DropStaleSponsored(t *testing.T) {
+	t.Parallel()
+
+	accountAddress := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+	sponsorship := &Sponsorship{
+		AccountAddress: new(felt.Felt).SetUint64(3),
+		PublicKey:      new(felt.Felt).SetUint64(4),
+		Call: starknetrpc.FunctionCall{
+			ContractAddress:    accountAddress,
+			EntryPointSelector: executeFromOutsideV2Selector,
+			Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(5)},
+		},
+	}
+
+	txm := newTestTxm(t, publicKey)
+	cfg := txm.cfg.(*mocks.Config)
+	cfg.On("BroadcastMaxAttempts").Return(uint32(3))
+	cfg.On("BroadcastRetryBackoff").Return(time.Millisecond)
+	cfg.On("TxTTL").Return(time.Hour)
+	now := time.Now()
+	calls := map[string]starknetrpc.FunctionCall{}
+	for i, id := range []string{"a", "b"} {
+		calls[id] = starknetrpc.FunctionCall{ContractAddress: accountAddress, EntryPointSelector: new(felt.Felt).SetUint64(uint64(10 + i))}
+		require.NoError(t, txm.storage.Save(TxRecord{
+			ID:             id,
+			AccountAddress: accountAddress,
+			PublicKey:      publicKey,
+			Call:           calls[id],
+			State:          TxBroadcast,
+			Nonce:          new(felt.Felt).SetUint64(6),
+			Hash:           "0x1",
+			Attempts:       []string{"0x1"},
+			Sponsor:        sponsorship,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}))
+	}
+	require.NoError(t, txm.restore())
+
+	// a resync finds that the node never saw the multicall
+	stale := txm.accountStore.GetTxStore(sponsorship.AccountAddress).SetNextNonce(new(felt.Felt).SetUint64(6))
+	require.Len(t, stale, 1)
+	txm.dropStaleTxs(sponsorship.AccountAddress, stale)
+
+	// the txs are queued again for their account, with their own calls
+	require.Eventually(t, func() bool { return txm.queues.lenOf(accountAddress) == 2 }, tests.WaitTimeout(t), 10*time.Millisecond)
+	assert.Zero(t, txm.queues.lenOf(sponsorship.AccountAddress))
+	_, batch, ok := txm.queues.next(10, 0)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	for _, tx := range batch {
+		assert.Equal(t, accountAddress, tx.accountAddress)
+		assert.Equal(t, publicKey, tx.publicKey)
+		assert.Equal(t, calls[tx.id], tx.call)
+
+		record, err := txm.storage.Get(tx.id)
+		require.NoError(t, err)
+		assert.Equal(t, TxEnqueued, record.State)
+	}
+}
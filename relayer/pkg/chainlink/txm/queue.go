@@ -0,0 +1,264 @@
+package txm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// ErrQueueFull is returned when the queue of an account has reached its MaxQueueLenPerAccount
+var ErrQueueFull = errors.New("queue full")
+
+// Priority orders the queued txs of an account, txs of a higher priority are broadcast first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+// accountQueue holds the txs of an account that are waiting to be broadcast, in the order of insertTx.
+type accountQueue struct {
+	accountAddress *felt.Felt
+	txs            []Tx
+	// busy is set while a worker broadcasts a batch of the account, the txs of an account are broadcast serially
+	busy bool
+	// paused accounts are not scheduled, see ErrInsufficientBalance
+	paused bool
+}
+
+// txQueues holds a queue per account. Accounts with queued txs are scheduled round-robin: a worker takes a single
+// batch of the account at the front of the ready list, and the account moves to the back once the batch has been
+// broadcast. Every account thus gets a fair share of the workers, and a slow account only ever occupies one. Ready
+// accounts whose next tx has a higher priority are served first.
+type txQueues struct {
+	lock   sync.Mutex
+	maxLen int
+	// a queued call of one of these selectors is replaced by a newer call of the same account and contract
+	replace map[string]bool
+	queues  map[string]*accountQueue
+	// accounts that have queued txs and are not busy, in scheduling order
+	ready []*accountQueue
+	// notify wakes up an idle worker when an account becomes ready
+	notify chan struct{}
+	// flavour returns the account flavour whose calldata layout limits the batches of an account, OpenZeppelin if nil
+	flavour func(accountAddress *felt.Felt) AccountFlavour
+}
+
+func newTxQueues(maxLen int, replaceSelectors []*felt.Felt) *txQueues {
+	replace := map[string]bool{}
+	for _, selector := range replaceSelectors {
+		replace[selector.String()] = true
+	}
+	return &txQueues{
+		maxLen:  maxLen,
+		replace: replace,
+		queues:  map[string]*accountQueue{},
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// push queues tx, and returns the queued tx that it replaced. If tx is older than the queued tx that it would
+// replace, tx itself is returned and not queued.
+func (q *txQueues) push(tx Tx) (replaced []Tx, err error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	addressStr := tx.accountAddress.String()
+	aq, ok := q.queues[addressStr]
+	if !ok {
+		aq = &accountQueue{accountAddress: tx.accountAddress}
+		q.queues[addressStr] = aq
+	}
+	i := -1
+	if q.replace[tx.call.EntryPointSelector.String()] {
+		i = slices.IndexFunc(aq.txs, func(queued Tx) bool {
+			return queued.call.ContractAddress.Equal(tx.call.ContractAddress) && queued.call.EntryPointSelector.Equal(tx.call.EntryPointSelector)
+		})
+	}
+	if i >= 0 {
+		if aq.txs[i].createdAt.After(tx.createdAt) {
+			return []Tx{tx}, nil
+		}
+		replaced = []Tx{aq.txs[i]}
+		// the account is already scheduled
+		aq.txs = insertTx(slices.Delete(aq.txs, i, i+1), tx)
+		return replaced, nil
+	}
+	if len(aq.txs) >= q.maxLen {
+		return nil, fmt.Errorf("%w: account %s has %d queued txs", ErrQueueFull, tx.accountAddress, len(aq.txs))
+	}
+	aq.txs = insertTx(aq.txs, tx)
+	if len(aq.txs) == 1 && !aq.busy && !aq.paused {
+		q.ready = append(q.ready, aq)
+		q.wake()
+	}
+	return replaced, nil
+}
+
+// insertTx inserts tx into the queued txs of an account. Txs are ordered by priority, and within a priority by
+// round: the n-th queued tx of each caller belongs to round n. A caller with many queued txs thus cannot delay the
+// txs of the other callers of the account by more than one tx each.
+func insertTx(txs []Tx, tx Tx) []Tx {
+	round := 0
+	for _, queued := range txs {
+		if queued.priority == tx.priority && queued.caller == tx.caller {
+			round++
+		}
+	}
+	rounds := map[string]int{}
+	for i, queued := range txs {
+		if queued.priority < tx.priority {
+			return slices.Insert(txs, i, tx)
+		}
+		if queued.priority > tx.priority {
+			continue
+		}
+		if rounds[queued.caller] > round {
+			return slices.Insert(txs, i, tx)
+		}
+		rounds[queued.caller]++
+	}
+	return append(txs, tx)
+}
+
+// requeue puts a batch taken by next back at the front of its account queue, regardless of maxLen.
+func (q *txQueues) requeue(aq *accountQueue, batch []Tx) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	aq.txs = append(slices.Clone(batch), aq.txs...)
+}
+
+// pause stops scheduling the account until resume is called. A paused account keeps accepting txs.
+func (q *txQueues) pause(accountAddress *felt.Felt) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	addressStr := accountAddress.String()
+	aq, ok := q.queues[addressStr]
+	if !ok {
+		aq = &accountQueue{accountAddress: accountAddress}
+		q.queues[addressStr] = aq
+	}
+	aq.paused = true
+	q.ready = slices.DeleteFunc(q.ready, func(ready *accountQueue) bool { return ready == aq })
+}
+
+func (q *txQueues) resume(accountAddress *felt.Felt) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	aq, ok := q.queues[accountAddress.String()]
+	if !ok || !aq.paused {
+		return
+	}
+	aq.paused = false
+	if aq.busy {
+		return
+	}
+	if len(aq.txs) == 0 {
+		delete(q.queues, accountAddress.String())
+		return
+	}
+	q.ready = append(q.ready, aq)
+	q.wake()
+}
+
+// next takes the next batch to broadcast and marks its account busy until done is called. It returns false if no
+// account is ready.
+func (q *txQueues) next(maxCalls, maxCalldataLen int) (aq *accountQueue, batch []Tx, ok bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.ready) == 0 {
+		return nil, nil, false
+	}
+	// the first account of the highest priority, accounts of the same priority are served round-robin
+	next := 0
+	for i, ready := range q.ready {
+		if ready.txs[0].priority > q.ready[next].txs[0].priority {
+			next = i
+		}
+	}
+	aq = q.ready[next]
+	q.ready = slices.Delete(q.ready, next, next+1)
+	aq.busy = true
+	flavour := FlavourOZ
+	if q.flavour != nil {
+		flavour = q.flavour(aq.accountAddress)
+	}
+	batch, aq.txs = takeBatch(aq.txs, flavour, maxCalls, maxCalldataLen)
+	if len(q.ready) > 0 {
+		// pass the wakeup on to another idle worker
+		q.wake()
+	}
+	return aq, batch, true
+}
+
+// done releases an account taken by next, and reschedules it behind the other ready accounts.
+func (q *txQueues) done(aq *accountQueue) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	aq.busy = false
+	if aq.paused {
+		return
+	}
+	if len(aq.txs) == 0 {
+		delete(q.queues, aq.accountAddress.String())
+		return
+	}
+	q.ready = append(q.ready, aq)
+	q.wake()
+}
+
+// len returns the number of queued txs of all accounts.
+func (q *txQueues) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	n := 0
+	for _, aq := range q.queues {
+		n += len(aq.txs)
+	}
+	return n
+}
+
+// idle returns true if no account is ready or busy. Paused accounts may still have queued txs.
+func (q *txQueues) idle() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.ready) > 0 {
+		return false
+	}
+	for _, aq := range q.queues {
+		if aq.busy {
+			return false
+		}
+	}
+	return true
+}
+
+// lenOf returns the number of queued txs of an account.
+func (q *txQueues) lenOf(accountAddress *felt.Felt) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if aq, ok := q.queues[accountAddress.String()]; ok {
+		return len(aq.txs)
+	}
+	return 0
+}
+
+func (q *txQueues) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
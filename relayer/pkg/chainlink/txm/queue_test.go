@@ -0,0 +1,174 @@
+package txm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pushTx(t *testing.T, q *txQueues, tx Tx) {
+	replaced, err := q.push(tx)
+	require.NoError(t, err)
+	require.Empty(t, replaced)
+}
+
+// drainQueues returns the IDs of all queued txs in broadcast order.
+func drainQueues(q *txQueues) []string {
+	var order []string
+	for {
+		aq, batch, ok := q.next(1, 0)
+		if !ok {
+			return order
+		}
+		order = append(order, txIDs(batch)...)
+		q.done(aq)
+	}
+}
+
+func TestTxQueues(t *testing.T) {
+	t.Parallel()
+
+	accountA := new(felt.Felt).SetUint64(1)
+	accountB := new(felt.Felt).SetUint64(2)
+	publicKey := new(felt.Felt).SetUint64(3)
+
+	t.Run("queue limit", func(t *testing.T) {
+		t.Parallel()
+
+		q := newTxQueues(2, nil)
+		pushTx(t, q, newTestTx("a0", accountA, publicKey, 0))
+		pushTx(t, q, newTestTx("a1", accountA, publicKey, 0))
+		_, err := q.push(newTestTx("a2", accountA, publicKey, 0))
+		require.ErrorIs(t, err, ErrQueueFull)
+		// the limit is per account
+		pushTx(t, q, newTestTx("b0", accountB, publicKey, 0))
+		assert.Equal(t, 3, q.len())
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		t.Parallel()
+
+		q := newTxQueues(10, nil)
+		for i := 0; i < 3; i++ {
+			pushTx(t, q, newTestTx(fmt.Sprintf("a%d", i), accountA, publicKey, 0))
+		}
+		pushTx(t, q, newTestTx("b0", accountB, publicKey, 0))
+
+		var order []string
+		for {
+			aq, batch, ok := q.next(1, 0)
+			if !ok {
+				break
+			}
+			order = append(order, txIDs(batch)...)
+			q.done(aq)
+		}
+		assert.Equal(t, []string{"a0", "b0", "a1", "a2"}, order)
+		assert.Equal(t, 0, q.len())
+		assert.Empty(t, q.queues)
+	})
+
+	t.Run("busy account", func(t *testing.T) {
+		t.Parallel()
+
+		q := newTxQueues(10, nil)
+		pushTx(t, q, newTestTx("a0", accountA, publicKey, 0))
+		aq, batch, ok := q.next(1, 0)
+		require.True(t, ok)
+		assert.Equal(t, []string{"a0"}, txIDs(batch))
+
+		// txs of a busy account are not handed to a second worker
+		pushTx(t, q, newTestTx("a1", accountA, publicKey, 0))
+		_, _, ok = q.next(1, 0)
+		assert.False(t, ok)
+
+		q.done(aq)
+		_, batch, ok = q.next(1, 0)
+		require.True(t, ok)
+		assert.Equal(t, []string{"a1"}, txIDs(batch))
+	})
+
+	t.Run("paused account", func(t *testing.T) {
+		t.Parallel()
+
+		q := newTxQueues(1, nil)
+		pushTx(t, q, newTestTx("a0", accountA, publicKey, 0))
+		pushTx(t, q, newTestTx("b0", accountB, publicKey, 0))
+		aq, batch, ok := q.next(1, 0)
+		require.True(t, ok)
+
+		// the batch of a paused account goes back to the front of its queue, regardless of the limit
+		q.pause(accountA)
+		pushTx(t, q, newTestTx("a1", accountA, publicKey, 0))
+		q.requeue(aq, batch)
+		q.done(aq)
+		assert.Equal(t, 2, q.lenOf(accountA))
+
+		_, batch, ok = q.next(1, 0)
+		require.True(t, ok)
+		assert.Equal(t, []string{"b0"}, txIDs(batch))
+		_, _, ok = q.next(1, 0)
+		assert.False(t, ok)
+
+		q.resume(accountA)
+		_, batch, ok = q.next(1, 0)
+		require.True(t, ok)
+		assert.Equal(t, []string{"a0"}, txIDs(batch))
+	})
+
+	t.Run("priorities", func(t *testing.T) {
+		t.Parallel()
+
+		q := newTxQueues(10, nil)
+		newTx := func(id string, account *felt.Felt, priority Priority, caller string) Tx {
+			tx := newTestTx(id, account, publicKey, 0)
+			tx.priority, tx.caller = priority, caller
+			return tx
+		}
+		// feed x is busier than feed y, the transfer of account A overtakes both
+		pushTx(t, q, newTx("x0", accountA, PriorityNormal, "x"))
+		pushTx(t, q, newTx("x1", accountA, PriorityNormal, "x"))
+		pushTx(t, q, newTx("x2", accountA, PriorityNormal, "x"))
+		pushTx(t, q, newTx("y0", accountA, PriorityNormal, "y"))
+		pushTx(t, q, newTx("y1", accountA, PriorityNormal, "y"))
+		pushTx(t, q, newTx("l0", accountA, PriorityLow, "l"))
+		pushTx(t, q, newTx("t0", accountA, PriorityHigh, "t"))
+		assert.Equal(t, []string{"t0", "x0", "y0", "x1", "y1", "x2", "l0"}, drainQueues(q))
+
+		// the account with the higher priority tx is served first
+		pushTx(t, q, newTx("a0", accountA, PriorityNormal, "x"))
+		pushTx(t, q, newTx("b0", accountB, PriorityHigh, "x"))
+		assert.Equal(t, []string{"b0", "a0"}, drainQueues(q))
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		t.Parallel()
+
+		call := newTestTx("", accountA, publicKey, 0).call
+		q := newTxQueues(2, []*felt.Felt{call.EntryPointSelector})
+		now := time.Now()
+		newTx := func(id string, contract uint64, createdAt time.Time) Tx {
+			tx := newTestTx(id, accountA, publicKey, 0)
+			tx.call.ContractAddress = new(felt.Felt).SetUint64(contract)
+			tx.createdAt = createdAt
+			return tx
+		}
+		pushTx(t, q, newTx("a0", 1, now))
+		pushTx(t, q, newTx("b0", 2, now))
+
+		// the newest call to a contract replaces the queued one, even if the queue is full
+		replaced, err := q.push(newTx("a1", 1, now.Add(time.Second)))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a0"}, txIDs(replaced))
+		// an older call is replaced itself, e.g. after a retry
+		replaced, err = q.push(newTx("a2", 1, now))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a2"}, txIDs(replaced))
+
+		assert.Equal(t, []string{"b0", "a1"}, drainQueues(q))
+	})
+}
@@ -0,0 +1,89 @@
+package txm
+
+import (
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// maxRetryBackoff caps the exponential backoff between broadcast attempts
+const maxRetryBackoff = 5 * time.Minute
+
+// causes of dead letters, used as metric label
+const (
+	deadLetterBroadcastFailed = "broadcast_failed"
+	deadLetterExpired         = "expired"
+	deadLetterQueueFull       = "queue_full"
+	deadLetterReplaced        = "replaced"
+)
+
+// retryBackoff returns the delay before the next broadcast of a tx that failed to broadcast attempts times.
+func retryBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// expired returns true if a tx enqueued at createdAt has outlived its TxTTL.
+func (txm *starktxm) expired(createdAt time.Time) bool {
+	ttl := txm.cfg.TxTTL()
+	return ttl > 0 && time.Since(createdAt) > ttl
+}
+
+// deadLetter abandons txs that will not be broadcast again, reason is kept as the last error of their records.
+func (txm *starktxm) deadLetter(accountAddress *felt.Felt, ids []string, cause string, reason string) {
+	txm.lggr.Errorw("moving txs to dead letters", "accountAddress", accountAddress, "ids", ids, "cause", cause, "reason", reason)
+	promTxDeadLetters.WithLabelValues(txm.chainID, accountAddress.String(), cause).Add(float64(len(ids)))
+	txm.updateRecords(ids, func(r *TxRecord) {
+		r.State = TxDeadLetter
+		r.Error = reason
+	})
+}
+
+// retryOrDeadLetter records a failed broadcast attempt of txs. A tx is queued again after a backoff unless it has
+// used up its BroadcastMaxAttempts or expired, in which case it is moved to the dead letters. hash is set if the
+// failed attempt had been broadcast, e.g. for txs dropped by a nonce resync.
+func (txm *starktxm) retryOrDeadLetter(txs []Tx, hash string, attemptErr string) {
+	now := time.Now()
+	for _, tx := range txs {
+		var attempts int
+		txm.updateRecord(tx.id, func(r *TxRecord) {
+			r.History = append(r.History, TxAttempt{Time: now, Hash: hash, Error: attemptErr})
+			r.Error = attemptErr
+			r.State = TxEnqueued
+			attempts = len(r.History)
+			tx.priority, tx.caller, tx.createdAt = r.Priority, r.Caller, r.CreatedAt
+		})
+
+		switch {
+		case attempts >= int(txm.cfg.BroadcastMaxAttempts()):
+			txm.deadLetter(tx.accountAddress, []string{tx.id}, deadLetterBroadcastFailed, attemptErr)
+		case txm.expired(tx.createdAt):
+			txm.deadLetter(tx.accountAddress, []string{tx.id}, deadLetterExpired, "expired: "+attemptErr)
+		default:
+			txm.retryLater(tx, retryBackoff(txm.cfg.BroadcastRetryBackoff(), attempts))
+		}
+	}
+}
+
+// retryLater pushes tx back onto its account queue after delay. If the txm stops first, the tx is restored from
+// its record on the next start.
+func (txm *starktxm) retryLater(tx Tx, delay time.Duration) {
+	txm.lggr.Debugw("retrying tx broadcast", "id", tx.id, "delay", delay)
+	txm.done.Add(1)
+	txm.pendingRetries.Add(1)
+	go func() {
+		defer txm.done.Done()
+		defer txm.pendingRetries.Add(-1)
+		select {
+		case <-time.After(delay):
+		case <-txm.stop:
+			return
+		}
+		if err := txm.push(tx); err != nil {
+			txm.deadLetter(tx.accountAddress, []string{tx.id}, deadLetterQueueFull, err.Error())
+		}
+	}()
+}
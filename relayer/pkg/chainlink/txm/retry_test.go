@@ -0,0 +1,92 @@
+package txm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Second, retryBackoff(time.Second, 1))
+	assert.Equal(t, 2*time.Second, retryBackoff(time.Second, 2))
+	assert.Equal(t, 8*time.Second, retryBackoff(time.Second, 4))
+	assert.Equal(t, maxRetryBackoff, retryBackoff(time.Second, 100))
+}
+
+func TestTxm_RetryOrDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	account := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+	call := starknetrpc.FunctionCall{
+		ContractAddress:    new(felt.Felt).SetUint64(3),
+		EntryPointSelector: new(felt.Felt).SetUint64(4),
+	}
+	txm := newTestTxm(t, publicKey)
+	cfg := txm.cfg.(*mocks.Config)
+	cfg.On("BroadcastMaxAttempts").Return(uint32(2))
+	cfg.On("BroadcastRetryBackoff").Return(10 * time.Millisecond)
+	cfg.On("TxTTL").Return(time.Duration(0))
+
+	id, err := txm.Enqueue(ctx, account, publicKey, call, "", TxOpts{})
+	require.NoError(t, err)
+
+	// the first failure is retried after the backoff
+	aq, batch, ok := txm.queues.next(1, 0)
+	require.True(t, ok)
+	txm.queues.done(aq)
+	txm.retryOrDeadLetter(batch, "", "boom")
+	record, err := txm.GetTransaction(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, TxEnqueued, record.State)
+	require.Len(t, record.History, 1)
+	assert.Equal(t, "boom", record.History[0].Error)
+	require.Eventually(t, func() bool { return txm.queues.len() == 1 }, tests.WaitTimeout(t), 5*time.Millisecond)
+
+	// the second failure uses up the attempts
+	aq, batch, ok = txm.queues.next(1, 0)
+	require.True(t, ok)
+	txm.queues.done(aq)
+	txm.retryOrDeadLetter(batch, "0x5", "dropped after nonce resync")
+	record, err = txm.GetTransaction(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, TxDeadLetter, record.State)
+	assert.Equal(t, "dropped after nonce resync", record.Error)
+	assert.Equal(t, call, record.Call)
+	require.Len(t, record.History, 2)
+	assert.Equal(t, "0x5", record.History[1].Hash)
+
+	status, err := txm.GetTransactionStatus(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, commontypes.Fatal, status)
+	deadLetters, err := txm.ListTransactions(ctx, nil, TxDeadLetter)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, id, deadLetters[0].ID)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, txm.queues.len())
+}
+
+func TestTxm_Expired(t *testing.T) {
+	t.Parallel()
+
+	txm := newTestTxm(t)
+	cfg := txm.cfg.(*mocks.Config)
+	cfg.On("TxTTL").Return(time.Minute)
+
+	assert.False(t, txm.expired(time.Now()))
+	assert.True(t, txm.expired(time.Now().Add(-2*time.Minute)))
+}
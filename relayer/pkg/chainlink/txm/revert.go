@@ -0,0 +1,146 @@
+package txm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+)
+
+type RevertKind string
+
+const (
+	RevertUnknown RevertKind = "Unknown"
+	// aggregator errors
+	RevertStaleReport          RevertKind = "StaleReport"
+	RevertConfigDigestMismatch RevertKind = "ConfigDigestMismatch"
+	RevertUnknownSender        RevertKind = "UnknownSender"
+	RevertWrongSignatureCount  RevertKind = "WrongSignatureCount"
+	RevertInvalidSignature     RevertKind = "InvalidSignature"
+	RevertMedianOutOfRange     RevertKind = "MedianOutOfRange"
+	// account and sequencer errors
+	RevertInvalidNonce          RevertKind = "InvalidNonce"
+	RevertInsufficientResources RevertKind = "InsufficientResources"
+)
+
+// knownRevertMessages maps the panic messages of the OCR2 aggregator contract to their kind
+var knownRevertMessages = map[string]RevertKind{
+	"stale report":                   RevertStaleReport,
+	"config digest mismatch":         RevertConfigDigestMismatch,
+	"unknown sender":                 RevertUnknownSender,
+	"wrong number of signatures":     RevertWrongSignatureCount,
+	"invalid signer":                 RevertInvalidSignature,
+	"duplicate signer":               RevertInvalidSignature,
+	"median is out of min-max range": RevertMedianOutOfRange,
+}
+
+// knownRevertSubstrings classifies reasons that are not a contract panic, matched case insensitively
+var knownRevertSubstrings = []struct {
+	substring string
+	kind      RevertKind
+}{
+	{"invalid transaction nonce", RevertInvalidNonce},
+	{"out of gas", RevertInsufficientResources},
+	{"insufficient max", RevertInsufficientResources},
+	{"exceeds balance", RevertInsufficientResources},
+	{"insufficient balance", RevertInsufficientResources},
+}
+
+const (
+	RevertSourceRPC    = "rpc"
+	RevertSourceFeeder = "feeder"
+)
+
+// RevertReason is the decoded reason of a reverted or rejected tx.
+type RevertReason struct {
+	Kind RevertKind
+	// Messages holds the Cairo panic data of the reason decoded as short strings, in the order reported
+	Messages []string `json:",omitempty"`
+	// Reason is the reason as reported by Source
+	Reason string
+	Source string
+}
+
+func (r *RevertReason) Error() string {
+	if len(r.Messages) == 0 {
+		return fmt.Sprintf("%s: %s", r.Kind, r.Reason)
+	}
+	return fmt.Sprintf("%s: %s", r.Kind, strings.Join(r.Messages, ", "))
+}
+
+var feltPattern = regexp.MustCompile(`0x[0-9a-fA-F]{1,64}`)
+
+// DecodeRevertReason decodes the revert reason of a receipt or feeder tx failure. Starknet reports the panic
+// data of a failed call as hex felts (e.g. "Failure reason: 0x7374616c65207265706f7274 ('stale report')"),
+// every felt that is a printable Cairo short string is decoded into Messages.
+func DecodeRevertReason(reason string, source string) *RevertReason {
+	decoded := &RevertReason{
+		Kind:   RevertUnknown,
+		Reason: reason,
+		Source: source,
+	}
+	for _, hex := range feltPattern.FindAllString(reason, -1) {
+		f, err := new(felt.Felt).SetString(hex)
+		if err != nil {
+			continue
+		}
+		if msg, ok := shortString(f); ok && !slices.Contains(decoded.Messages, msg) {
+			decoded.Messages = append(decoded.Messages, msg)
+		}
+	}
+
+	for _, msg := range decoded.Messages {
+		if kind, ok := knownRevertMessages[msg]; ok {
+			decoded.Kind = kind
+			return decoded
+		}
+	}
+	lower := strings.ToLower(reason)
+	for msg, kind := range knownRevertMessages {
+		if strings.Contains(lower, msg) {
+			decoded.Kind = kind
+			return decoded
+		}
+	}
+	for _, known := range knownRevertSubstrings {
+		if strings.Contains(lower, known.substring) {
+			decoded.Kind = known.kind
+			return decoded
+		}
+	}
+	return decoded
+}
+
+// shortString decodes f as a Cairo short string, it fails unless every byte is printable ASCII.
+func shortString(f *felt.Felt) (string, bool) {
+	b := f.Bytes()
+	trimmed := strings.TrimLeft(string(b[:]), "\x00")
+	if trimmed == "" {
+		return "", false
+	}
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] < 0x20 || trimmed[i] > 0x7e {
+			return "", false
+		}
+	}
+	return trimmed, true
+}
+
+// receiptRevertReason returns the revert reason of a tx receipt, and false if the receipt has none.
+func receiptRevertReason(receipt starknetrpc.TransactionReceipt) (string, bool) {
+	switch r := receipt.(type) {
+	case *starknetrpc.TransactionReceiptWithBlockInfo:
+		return receiptRevertReason(r.TransactionReceipt)
+	case starknetrpc.InvokeTransactionReceipt:
+		return r.RevertReason, r.RevertReason != ""
+	case starknetrpc.DeployAccountTransactionReceipt:
+		return r.RevertReason, r.RevertReason != ""
+	case starknetrpc.CommonTransactionReceipt:
+		return r.RevertReason, r.RevertReason != ""
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,80 @@
+package txm
+
+import (
+	"testing"
+
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRevertReason(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		reason   string
+		kind     RevertKind
+		messages []string
+	}{
+		{
+			name: "aggregator panic",
+			reason: "Error in the called contract (0x04a35b2a1f6bbe3b8cbc1e0ff1ea2db9db5d3b6e6a14ae5c2d5b8a7d4e35e8fc):\n" +
+				"Error at pc=0:4835:\nGot an exception while executing a hint: Execution failed. " +
+				"Failure reason: 0x7374616c65207265706f7274 ('stale report').\n",
+			kind:     RevertStaleReport,
+			messages: []string{"stale report"},
+		},
+		{
+			name:     "nested panic",
+			reason:   "Execution failed. Failure reason: (0x636f6e66696720646967657374206d69736d61746368 ('config digest mismatch'), 0x454e545259504f494e545f4641494c4544 ('ENTRYPOINT_FAILED')).",
+			kind:     RevertConfigDigestMismatch,
+			messages: []string{"config digest mismatch", "ENTRYPOINT_FAILED"},
+		},
+		{
+			name:   "plain message",
+			reason: "Transaction execution has failed: wrong number of signatures",
+			kind:   RevertWrongSignatureCount,
+		},
+		{
+			name:   "insufficient resources",
+			reason: "Insufficient max L1 gas: max amount: 10, actual used: 20.",
+			kind:   RevertInsufficientResources,
+		},
+		{
+			name:     "unknown",
+			reason:   "Failure reason: 0x6e6f7065 ('nope').",
+			kind:     RevertUnknown,
+			messages: []string{"nope"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			decoded := DecodeRevertReason(tc.reason, RevertSourceRPC)
+			assert.Equal(t, tc.kind, decoded.Kind)
+			assert.Equal(t, tc.messages, decoded.Messages)
+			assert.Equal(t, tc.reason, decoded.Reason)
+			assert.Equal(t, RevertSourceRPC, decoded.Source)
+		})
+	}
+}
+
+func TestReceiptRevertReason(t *testing.T) {
+	t.Parallel()
+
+	reason, ok := receiptRevertReason(&starknetrpc.TransactionReceiptWithBlockInfo{
+		TransactionReceipt: starknetrpc.InvokeTransactionReceipt{RevertReason: "stale report"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "stale report", reason)
+
+	_, ok = receiptRevertReason(&starknetrpc.TransactionReceiptWithBlockInfo{
+		TransactionReceipt: starknetrpc.InvokeTransactionReceipt{},
+	})
+	assert.False(t, ok)
+
+	// receipts decoded by the provider lose their body
+	_, ok = receiptRevertReason(&starknetrpc.TransactionReceiptWithBlockInfo{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,69 @@
+package txm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// SimulationError is returned for an invoke that reverts in simulation, such an invoke is not broadcast.
+type SimulationError struct {
+	Reason *RevertReason
+}
+
+func (e *SimulationError) Error() string {
+	return fmt.Sprintf("simulation reverted: %s", e.Reason.Error())
+}
+
+// simulate runs the signed invoke against the pending block and fails with a SimulationError if its execution
+// reverts. Errors reaching the node are only logged: the simulation is a safeguard, and the invoke is broadcast
+// as if simulation were disabled.
+func (txm *starktxm) simulate(ctx context.Context, client *starknet.Client, tx *invokeTx) error {
+	simulated, err := client.Provider.SimulateTransactions(ctx, starknetrpc.WithBlockTag(starknet.BlockTagPending), []starknetrpc.Transaction{tx.txn()}, []starknetrpc.SimulationFlag{})
+	if err != nil {
+		var rpcErr *starknetrpc.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == starknetrpc.ErrTxnExec.Code {
+			// validation or execution failed before a trace could be produced
+			return &SimulationError{Reason: DecodeRevertReason(fmt.Sprintf("%+v", rpcErr.Data), RevertSourceRPC)}
+		}
+		txm.lggr.Warnw("failed to simulate tx, broadcasting without simulation", "accountAddress", tx.senderAddress(), "nonce", tx.nonce(), "error", err)
+		return nil
+	}
+	if len(simulated) != 1 {
+		txm.lggr.Warnw("unexpected simulation result, broadcasting without simulation", "accountAddress", tx.senderAddress(), "nonce", tx.nonce(), "results", len(simulated))
+		return nil
+	}
+
+	revertReason, err := traceRevertReason(simulated[0].TxnTrace)
+	if err != nil {
+		txm.lggr.Warnw("failed to decode simulation trace, broadcasting without simulation", "accountAddress", tx.senderAddress(), "nonce", tx.nonce(), "error", err)
+		return nil
+	}
+	if revertReason != "" {
+		return &SimulationError{Reason: DecodeRevertReason(revertReason, RevertSourceRPC)}
+	}
+	return nil
+}
+
+// traceRevertReason returns the revert reason of the execute invocation of an invoke trace. The trace is decoded
+// generically by starknet.go, so it is re-encoded into the fields of interest.
+func traceRevertReason(trace starknetrpc.TxnTrace) (string, error) {
+	b, err := json.Marshal(trace)
+	if err != nil {
+		return "", err
+	}
+	var invokeTrace struct {
+		ExecuteInvocation struct {
+			RevertReason string `json:"revert_reason"`
+		} `json:"execute_invocation"`
+	}
+	if err = json.Unmarshal(b, &invokeTrace); err != nil {
+		return "", err
+	}
+	return invokeTrace.ExecuteInvocation.RevertReason, nil
+}
@@ -0,0 +1,87 @@
+package txm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+func TestTxm_Simulate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		result string
+		kind   RevertKind
+	}{
+		{
+			name:   "success",
+			result: `"result": [{"transaction_trace": {"type": "INVOKE", "execute_invocation": {"function_invocation": {}}}, "fee_estimation": {}}]`,
+		},
+		{
+			name:   "reverted",
+			result: `"result": [{"transaction_trace": {"type": "INVOKE", "execute_invocation": {"revert_reason": "Execution failed. Failure reason: 0x7374616c65207265706f7274 ('stale report')."}}, "fee_estimation": {}}]`,
+			kind:   RevertStaleReport,
+		},
+		{
+			name:   "execution error",
+			result: `"error": {"code": 41, "message": "Transaction execution error", "data": {"transaction_index": 0, "execution_error": "Failure reason: 0x756e6b6e6f776e2073656e646572 ('unknown sender')."}}`,
+			kind:   RevertUnknownSender,
+		},
+		{
+			// other errors do not block the broadcast
+			name:   "unavailable",
+			result: `"error": {"code": -32603, "message": "Internal error"}`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				var req struct {
+					ID     json.RawMessage `json:"id"`
+					Method string          `json:"method"`
+				}
+				require.NoError(t, json.Unmarshal(body, &req))
+				assert.Equal(t, "starknet_simulateTransactions", req.Method)
+				_, err = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, %s}`, req.ID, tc.result)
+				require.NoError(t, err)
+			}))
+			t.Cleanup(server.Close)
+
+			client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+			require.NoError(t, err)
+
+			txm := &starktxm{lggr: logger.Test(t)}
+			err = txm.simulate(tests.Context(t), client, &invokeTx{v3: &starknetrpc.InvokeTxnV3{
+				Type:          starknetrpc.TransactionType_Invoke,
+				Version:       starknetrpc.TransactionV3,
+				SenderAddress: new(felt.Felt).SetUint64(1),
+				Nonce:         new(felt.Felt).SetUint64(2),
+			}})
+			if tc.kind == "" {
+				require.NoError(t, err)
+				return
+			}
+			var simErr *SimulationError
+			require.ErrorAs(t, err, &simErr)
+			assert.Equal(t, tc.kind, simErr.Reason.Kind)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package txm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/NethermindEth/juno/core/felt"
+
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types"
+)
+
+// TransactionStatus maps the state to the tx status model shared by the Chainlink relayers.
+func (s TxState) TransactionStatus() commontypes.TransactionStatus {
+	switch s {
+	case TxEnqueued:
+		return commontypes.Pending
+	case TxBroadcast, TxAccepted:
+		return commontypes.Unconfirmed
+	case TxConfirmed:
+		return commontypes.Finalized
+	case TxFailed:
+		return commontypes.Failed
+	case TxDeadLetter:
+		return commontypes.Fatal
+	default:
+		return commontypes.Unknown
+	}
+}
+
+func (txm *starktxm) GetTransactionStatus(ctx context.Context, txID string) (commontypes.TransactionStatus, error) {
+	record, err := txm.storage.Get(txID)
+	if err != nil {
+		return commontypes.Unknown, err
+	}
+	return record.State.TransactionStatus(), nil
+}
+
+func (txm *starktxm) GetTransaction(ctx context.Context, txID string) (TxRecord, error) {
+	return txm.storage.Get(txID)
+}
+
+func (txm *starktxm) ListTransactions(ctx context.Context, accountAddress *felt.Felt, states ...TxState) ([]TxRecord, error) {
+	records, err := txm.storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load txs: %+w", err)
+	}
+	filtered := []TxRecord{}
+	for _, record := range records {
+		if accountAddress != nil && !record.AccountAddress.Equal(accountAddress) {
+			continue
+		}
+		if len(states) > 0 && !slices.Contains(states, record.State) {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+	return filtered, nil
+}
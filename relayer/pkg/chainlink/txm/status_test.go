@@ -0,0 +1,144 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	adapters "github.com/smartcontractkit/chainlink-common/pkg/loop/adapters/starknet"
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// fakeKeystore only knows the public keys in keys
+type fakeKeystore struct {
+	keys []string
+}
+
+func (ks *fakeKeystore) Sign(ctx context.Context, account string, data []byte) ([]byte, error) {
+	for _, k := range ks.keys {
+		if k != account {
+			continue
+		}
+		if data == nil {
+			// key existence check
+			return nil, nil
+		}
+		sig, err := adapters.SignatureFromBigInts(big.NewInt(7), big.NewInt(11))
+		if err != nil {
+			return nil, err
+		}
+		return sig.Bytes()
+	}
+	return nil, errors.New("unknown key")
+}
+
+func (ks *fakeKeystore) Accounts(ctx context.Context) ([]string, error) {
+	return ks.keys, nil
+}
+
+func newTestTxm(t *testing.T, keys ...*felt.Felt) *starktxm {
+	cfg := mocks.NewConfig(t)
+	cfg.On("TxStoragePath").Return("")
+	cfg.On("FeeLedgerRetention").Return(24 * time.Hour)
+	cfg.On("AccountFlavour", mock.Anything).Return("").Maybe()
+	cfg.On("AccountGuardian", mock.Anything).Return(nil).Maybe()
+	cfg.On("SponsorAccount").Return(nil).Maybe()
+	cfg.On("ReplaceSelectors").Return([]string(nil))
+	cfg.On("MaxQueueLenPerAccount").Return(uint32(2))
+	cfg.On("FeeEstimator").Return(fees.Config{Mode: fees.ModePadded, AmountPaddingPercent: 150, PricePaddingPercent: 150})
+
+	ks := &fakeKeystore{}
+	for _, k := range keys {
+		ks.keys = append(ks.keys, k.String())
+	}
+	noClient := func() (*starknet.Client, error) { return nil, errors.New("no client") }
+	noFeederClient := func() (*starknet.FeederClient, error) { return nil, errors.New("no client") }
+	txm, err := New(logger.Test(t), "SN_SEPOLIA", ks, cfg, noClient, noFeederClient)
+	require.NoError(t, err)
+	return txm.(*starktxm)
+}
+
+func TestTxm_TransactionStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	accountA := new(felt.Felt).SetUint64(1)
+	accountB := new(felt.Felt).SetUint64(2)
+	publicKey := new(felt.Felt).SetUint64(3)
+	call := starknetrpc.FunctionCall{
+		ContractAddress:    new(felt.Felt).SetUint64(4),
+		EntryPointSelector: new(felt.Felt).SetUint64(5),
+	}
+	txm := newTestTxm(t, publicKey)
+
+	_, err := txm.Enqueue(ctx, accountA, new(felt.Felt).SetUint64(6), call, "", TxOpts{})
+	require.ErrorContains(t, err, "failed to sign")
+
+	id, err := txm.Enqueue(ctx, accountA, publicKey, call, "", TxOpts{})
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	// a caller supplied ID is only enqueued once
+	keyed, err := txm.Enqueue(ctx, accountB, publicKey, call, "report-1", TxOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, "report-1", keyed)
+	keyed, err = txm.Enqueue(ctx, accountB, publicKey, call, "report-1", TxOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, "report-1", keyed)
+	queued, _ := txm.InflightCount()
+	assert.Equal(t, 2, queued)
+	assert.Equal(t, float64(1), testutil.ToFloat64(promQueueDepth.WithLabelValues("SN_SEPOLIA", accountB.String())))
+
+	status, err := txm.GetTransactionStatus(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, commontypes.Pending, status)
+
+	txm.updateRecord(keyed, func(r *TxRecord) {
+		r.State = TxBroadcast
+		r.Hash = "0x1"
+	})
+	status, err = txm.GetTransactionStatus(ctx, keyed)
+	require.NoError(t, err)
+	assert.Equal(t, commontypes.Unconfirmed, status)
+	record, err := txm.GetTransaction(ctx, keyed)
+	require.NoError(t, err)
+	assert.Equal(t, "0x1", record.Hash)
+	assert.Equal(t, call, record.Call)
+	// txs without a caller are scheduled per contract
+	assert.Equal(t, PriorityNormal, record.Priority)
+	assert.Equal(t, call.ContractAddress.String(), record.Caller)
+
+	status, err = txm.GetTransactionStatus(ctx, "unknown")
+	require.ErrorIs(t, err, ErrTxNotFound)
+	assert.Equal(t, commontypes.Unknown, status)
+
+	records, err := txm.ListTransactions(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	records, err = txm.ListTransactions(ctx, accountA)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(records))
+	assert.Equal(t, id, records[0].ID)
+	records, err = txm.ListTransactions(ctx, nil, TxBroadcast, TxConfirmed)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(records))
+	assert.Equal(t, keyed, records[0].ID)
+	records, err = txm.ListTransactions(ctx, accountA, TxFailed)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
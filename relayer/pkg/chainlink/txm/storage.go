@@ -0,0 +1,398 @@
+package txm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"golang.org/x/exp/maps"
+)
+
+type TxState string
+
+const (
+	TxEnqueued  TxState = "enqueued"
+	TxBroadcast TxState = "broadcast"
+	// TxAccepted holds txs that were accepted on L2 and wait for L1 finality, see FinalityLevel
+	TxAccepted  TxState = "accepted"
+	TxConfirmed TxState = "confirmed"
+	TxFailed    TxState = "failed"
+	// TxDeadLetter holds txs that were abandoned without an outcome, because they failed to broadcast too often or
+	// expired. Their record keeps the call, the last error and the attempt history for post-mortems.
+	TxDeadLetter TxState = "dead_letter"
+)
+
+// IsTerminal returns true if a tx in this state will not be processed any further.
+func (s TxState) IsTerminal() bool {
+	return s == TxConfirmed || s == TxFailed || s == TxDeadLetter
+}
+
+// TxAttempt is a single broadcast attempt of a tx, Error is set if the attempt failed.
+type TxAttempt struct {
+	Time  time.Time
+	Hash  string `json:",omitempty"`
+	Error string `json:",omitempty"`
+}
+
+// TxRecord is the persisted representation of a tx managed by the txm.
+type TxRecord struct {
+	ID             string
+	AccountAddress *felt.Felt
+	PublicKey      *felt.Felt
+	Call           starknetrpc.FunctionCall
+	Priority       Priority `json:",omitempty"`
+	Caller         string   `json:",omitempty"`
+	State          TxState
+	Nonce          *felt.Felt `json:",omitempty"`
+	Hash           string     `json:",omitempty"`
+	// Attempts holds the hashes of every broadcast of the tx, oldest first
+	Attempts []string `json:",omitempty"`
+	Error    string   `json:",omitempty"`
+	// RevertReason is the decoded reason of a reverted or rejected tx
+	RevertReason *RevertReason `json:",omitempty"`
+	// EstimatedFee is the fee estimate of the most recent attempt, in FRI (WEI for Cairo0 accounts)
+	EstimatedFee *big.Int `json:",omitempty"`
+	// History holds the failed broadcast attempts of the tx, oldest first
+	History []TxAttempt `json:",omitempty"`
+	// Sponsor is set if the tx was invoked by a sponsor account, see SponsorAccount
+	Sponsor *Sponsorship `json:",omitempty"`
+	// BlockHash and BlockNumber identify the L2 block that includes the tx, only tracked with L1 finality
+	BlockHash   *felt.Felt `json:",omitempty"`
+	BlockNumber uint64     `json:",omitempty"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TxStorage persists tx records so that queued and inflight txs survive a restart of the txm.
+type TxStorage interface {
+	// Save inserts the record, or replaces an existing record with the same ID.
+	Save(record TxRecord) error
+	// Get returns the record with the given ID, or ErrTxNotFound.
+	Get(id string) (TxRecord, error)
+	// Update applies update to the record with the given ID and saves it, atomically with respect to the other
+	// writes. It returns ErrTxNotFound for an unknown ID.
+	Update(id string, update func(r *TxRecord)) error
+	// Delete removes the record with the given ID. Deleting an unknown ID is not an error.
+	Delete(id string) error
+	// Load returns all stored records ordered by creation time.
+	Load() ([]TxRecord, error)
+	Close() error
+}
+
+var ErrTxNotFound = errors.New("tx not found")
+
+var _ TxStorage = (*memoryTxStorage)(nil)
+
+// memoryTxStorage is the default TxStorage, it does not survive restarts.
+type memoryTxStorage struct {
+	lock      sync.RWMutex
+	records   map[string]TxRecord
+	saves     int
+	retention time.Duration
+}
+
+// NewMemoryTxStorage returns a TxStorage that only lives in memory. Confirmed and failed records older
+// than retention are periodically dropped.
+func NewMemoryTxStorage(retention time.Duration) TxStorage {
+	return &memoryTxStorage{
+		records:   map[string]TxRecord{},
+		retention: retention,
+	}
+}
+
+func (s *memoryTxStorage) Save(record TxRecord) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.save(record)
+}
+
+// save stores record. Must be called with the lock held.
+func (s *memoryTxStorage) save(record TxRecord) error {
+	s.records[record.ID] = record
+
+	s.saves++
+	if s.saves > storagePruneInterval {
+		pruneTerminal(s.records, s.retention)
+		s.saves = 0
+	}
+	return nil
+}
+
+func (s *memoryTxStorage) Get(id string) (TxRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	record, ok := s.records[id]
+	if !ok {
+		return TxRecord{}, fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	return record, nil
+}
+
+func (s *memoryTxStorage) Update(id string, update func(r *TxRecord)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	update(&record)
+	return s.save(record)
+}
+
+func (s *memoryTxStorage) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+func (s *memoryTxStorage) Load() ([]TxRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return sortRecords(maps.Values(s.records)), nil
+}
+
+func (s *memoryTxStorage) Close() error {
+	return nil
+}
+
+// fileStorageEntry is a single line of the file storage log.
+type fileStorageEntry struct {
+	Record  *TxRecord `json:",omitempty"`
+	Deleted string    `json:",omitempty"`
+}
+
+// terminal records are pruned after this many writes. For the file storage this is also the number of
+// stale log entries that triggers a compaction.
+const storagePruneInterval = 1000
+
+var _ TxStorage = (*fileTxStorage)(nil)
+
+// fileTxStorage is an append-only log of JSON encoded records. Every write is synced to disk before returning,
+// and the log is compacted (written to a temporary file and atomically renamed) when opened and once it has
+// grown past storagePruneInterval. A partially written trailing line, left behind by a crash mid-append,
+// is discarded when the log is replayed.
+type fileTxStorage struct {
+	lock sync.Mutex
+
+	path      string
+	file      *os.File
+	entries   int
+	records   map[string]TxRecord
+	retention time.Duration
+}
+
+// NewFileTxStorage opens (or creates) the tx log at path. Confirmed and failed records older than retention
+// are dropped whenever the log is compacted.
+func NewFileTxStorage(path string, retention time.Duration) (TxStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create tx storage directory: %w", err)
+	}
+
+	s := &fileTxStorage{
+		path:      path,
+		records:   map[string]TxRecord{},
+		retention: retention,
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileTxStorage) replay() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open tx storage: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var entry fileStorageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// only the last line can be partially written, everything after it is lost anyway
+			break
+		}
+		if entry.Record != nil {
+			s.records[entry.Record.ID] = *entry.Record
+		} else if entry.Deleted != "" {
+			delete(s.records, entry.Deleted)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read tx storage: %w", err)
+	}
+	return nil
+}
+
+// compact rewrites the log so that it only contains live records. Must be called with the lock held.
+func (s *fileTxStorage) compact() error {
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			return fmt.Errorf("failed to close tx storage: %w", err)
+		}
+		s.file = nil
+	}
+
+	pruneTerminal(s.records, s.retention)
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted tx storage: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	for _, record := range sortRecords(maps.Values(s.records)) {
+		record := record
+		if err = writeEntry(w, fileStorageEntry{Record: &record}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write compacted tx storage: %w", err)
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace tx storage: %w", err)
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open tx storage: %w", err)
+	}
+	s.entries = len(s.records)
+	return nil
+}
+
+func (s *fileTxStorage) append(entry fileStorageEntry) error {
+	if s.file == nil {
+		return errors.New("tx storage is closed")
+	}
+	w := bufio.NewWriter(s.file)
+	if err := writeEntry(w, entry); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write to tx storage: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync tx storage: %w", err)
+	}
+	s.entries++
+
+	if s.entries-len(s.records) > storagePruneInterval {
+		return s.compact()
+	}
+	return nil
+}
+
+func (s *fileTxStorage) Save(record TxRecord) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.records[record.ID] = record
+	return s.append(fileStorageEntry{Record: &record})
+}
+
+func (s *fileTxStorage) Get(id string) (TxRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return TxRecord{}, fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	return record, nil
+}
+
+func (s *fileTxStorage) Update(id string, update func(r *TxRecord)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	update(&record)
+	s.records[id] = record
+	return s.append(fileStorageEntry{Record: &record})
+}
+
+func (s *fileTxStorage) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.records[id]; !ok {
+		return nil
+	}
+	delete(s.records, id)
+	return s.append(fileStorageEntry{Deleted: id})
+}
+
+func (s *fileTxStorage) Load() ([]TxRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return sortRecords(maps.Values(s.records)), nil
+}
+
+func (s *fileTxStorage) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func writeEntry(w *bufio.Writer, entry fileStorageEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode tx storage entry: %w", err)
+	}
+	if _, err = w.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write to tx storage: %w", err)
+	}
+	return nil
+}
+
+// pruneTerminal removes confirmed and failed records that were last updated before the retention period.
+func pruneTerminal(records map[string]TxRecord, retention time.Duration) {
+	cutoff := time.Now().Add(-retention)
+	for id, record := range records {
+		if record.State.IsTerminal() && record.UpdatedAt.Before(cutoff) {
+			delete(records, id)
+		}
+	}
+}
+
+func sortRecords(records []TxRecord) []TxRecord {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records
+}
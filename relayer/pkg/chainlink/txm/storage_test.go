@@ -0,0 +1,190 @@
+package txm
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRecord(id string, state TxState, createdAt time.Time) TxRecord {
+	return TxRecord{
+		ID:             id,
+		AccountAddress: new(felt.Felt).SetUint64(1),
+		PublicKey:      new(felt.Felt).SetUint64(2),
+		Call: starknetrpc.FunctionCall{
+			ContractAddress:    new(felt.Felt).SetUint64(3),
+			EntryPointSelector: new(felt.Felt).SetUint64(4),
+			Calldata:           []*felt.Felt{new(felt.Felt).SetUint64(5)},
+		},
+		State:     state,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+func TestMemoryTxStorage(t *testing.T) {
+	t.Parallel()
+
+	s := NewMemoryTxStorage(time.Hour)
+	now := time.Now()
+
+	require.NoError(t, s.Save(testRecord("b", TxEnqueued, now.Add(time.Second))))
+	require.NoError(t, s.Save(testRecord("a", TxEnqueued, now)))
+
+	records, err := s.Load()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(records))
+	assert.Equal(t, "a", records[0].ID)
+	assert.Equal(t, "b", records[1].ID)
+
+	_, err = s.Get("c")
+	require.ErrorIs(t, err, ErrTxNotFound)
+
+	require.NoError(t, s.Delete("a"))
+	require.NoError(t, s.Delete("a"))
+	records, err = s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+}
+
+func TestFileTxStorage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reload", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "txs.jsonl")
+		now := time.Now()
+
+		s, err := NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Save(testRecord("a", TxEnqueued, now)))
+		require.NoError(t, s.Save(testRecord("b", TxEnqueued, now.Add(time.Second))))
+		require.NoError(t, s.Save(testRecord("c", TxEnqueued, now.Add(2*time.Second))))
+
+		broadcast := testRecord("a", TxBroadcast, now)
+		broadcast.Nonce = new(felt.Felt).SetUint64(9)
+		broadcast.Hash = "0x123"
+		require.NoError(t, s.Save(broadcast))
+		require.NoError(t, s.Delete("b"))
+		require.NoError(t, s.Close())
+
+		s, err = NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+		defer s.Close()
+
+		records, err := s.Load()
+		require.NoError(t, err)
+		require.Equal(t, 2, len(records))
+		assert.Equal(t, "a", records[0].ID)
+		assert.Equal(t, TxBroadcast, records[0].State)
+		assert.Equal(t, "0x123", records[0].Hash)
+		assert.Equal(t, 0, records[0].Nonce.Cmp(broadcast.Nonce))
+		assert.Equal(t, broadcast.Call, records[0].Call)
+		assert.Equal(t, "c", records[1].ID)
+	})
+
+	t.Run("partial write", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "txs.jsonl")
+
+		s, err := NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, s.Save(testRecord("a", TxEnqueued, time.Now())))
+		require.NoError(t, s.Close())
+
+		// simulate a crash in the middle of appending an entry
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"Record":{"ID":"b","Sta`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		s, err = NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+		records, err := s.Load()
+		require.NoError(t, err)
+		require.Equal(t, 1, len(records))
+		assert.Equal(t, "a", records[0].ID)
+
+		// the log is usable after recovering
+		require.NoError(t, s.Save(testRecord("c", TxEnqueued, time.Now())))
+		require.NoError(t, s.Close())
+		s, err = NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+		defer s.Close()
+		records, err = s.Load()
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(records))
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "txs.jsonl")
+		old := time.Now().Add(-2 * time.Hour)
+
+		s, err := NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, s.Save(testRecord("confirmed", TxConfirmed, old)))
+		require.NoError(t, s.Save(testRecord("failed", TxFailed, old)))
+		require.NoError(t, s.Save(testRecord("enqueued", TxEnqueued, old)))
+		require.NoError(t, s.Close())
+
+		// terminal records past retention are dropped on compaction
+		s, err = NewFileTxStorage(path, time.Hour)
+		require.NoError(t, err)
+		defer s.Close()
+		records, err := s.Load()
+		require.NoError(t, err)
+		require.Equal(t, 1, len(records))
+		assert.Equal(t, "enqueued", records[0].ID)
+	})
+}
+
+func TestTxStorage_Update(t *testing.T) {
+	t.Parallel()
+
+	fileStorage, err := NewFileTxStorage(filepath.Join(t.TempDir(), "txs.jsonl"), time.Hour)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, fileStorage.Close()) })
+
+	for name, s := range map[string]TxStorage{"memory": NewMemoryTxStorage(time.Hour), "file": fileStorage} {
+		s := s
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.ErrorIs(t, s.Update("a", func(r *TxRecord) {}), ErrTxNotFound)
+			require.NoError(t, s.Save(testRecord("a", TxBroadcast, time.Now())))
+
+			// concurrent updates of different fields do not overwrite each other
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, s.Update("a", func(r *TxRecord) { r.History = append(r.History, TxAttempt{}) }))
+				}()
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, s.Update("a", func(r *TxRecord) { r.BlockNumber++ }))
+				}()
+			}
+			wg.Wait()
+
+			record, err := s.Get("a")
+			require.NoError(t, err)
+			assert.Len(t, record.History, 50)
+			assert.Equal(t, uint64(50), record.BlockNumber)
+		})
+	}
+}
@@ -9,10 +9,14 @@ import (
 
 	starknetutils "github.com/NethermindEth/starknet.go/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/utils"
 	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/mocks"
 )
 
 var (
@@ -87,3 +91,42 @@ func TestKeys(t *testing.T, count int) (rawkeys [][]byte) {
 	}
 	return rawkeys
 }
+
+// NewTestConfig returns a Config mock with defaults for tests that run a txm against a test node. The expectations
+// registered by override take precedence over the defaults.
+func NewTestConfig(t *testing.T, override func(cfg *mocks.Config)) *mocks.Config {
+	cfg := mocks.NewConfig(t)
+	if override != nil {
+		override(cfg)
+	}
+	cfg.On("TxTimeout").Return(5 * time.Second).Maybe()
+	cfg.On("ConfirmationPoll").Return(50 * time.Millisecond).Maybe()
+	cfg.On("ConfirmationBatchSize").Return(uint32(100)).Maybe()
+	cfg.On("FinalityLevel").Return(string(FinalityAcceptedOnL2)).Maybe()
+	cfg.On("RebroadcastTimeout").Return(time.Minute).Maybe()
+	cfg.On("FeeBumpPercent").Return(uint32(20)).Maybe()
+	cfg.On("MaxFeeBumps").Return(uint32(5)).Maybe()
+	cfg.On("MaxQueueLenPerAccount").Return(uint32(1000)).Maybe()
+	cfg.On("BroadcastWorkers").Return(uint32(1)).Maybe()
+	cfg.On("BatchMaxCalls").Return(uint32(1)).Maybe()
+	cfg.On("BatchMaxCalldataLen").Return(uint32(0)).Maybe()
+	cfg.On("FeeEstimator").Return(fees.Config{Mode: fees.ModePadded, AmountPaddingPercent: 150, PricePaddingPercent: 150}).Maybe()
+	cfg.On("SimulateTxs").Return(true).Maybe()
+	cfg.On("AccountClass").Return(string(AccountClassOZ)).Maybe()
+	cfg.On("AccountClassHash").Return(nil).Maybe()
+	cfg.On("AccountFlavour", mock.Anything).Return("").Maybe()
+	cfg.On("AccountGuardian", mock.Anything).Return(nil).Maybe()
+	cfg.On("AutoDeployAccounts").Return(false).Maybe()
+	cfg.On("BroadcastMaxAttempts").Return(uint32(3)).Maybe()
+	cfg.On("BroadcastRetryBackoff").Return(100 * time.Millisecond).Maybe()
+	cfg.On("TxTTL").Return(time.Hour).Maybe()
+	cfg.On("FeeToken").Return("0x04718f5a0fc34cc1af16a1cdee98ffb20c31f5cd61d6ab07201858f4287c938d").Maybe() // STRK
+	cfg.On("BalancePollInterval").Return(time.Minute).Maybe()
+	cfg.On("DrainTimeout").Return(time.Second).Maybe()
+	cfg.On("FeeLedgerRetention").Return(24 * time.Hour).Maybe()
+	cfg.On("ReplaceSelectors").Return([]string(nil)).Maybe()
+	cfg.On("SponsorAccount").Return(nil).Maybe()
+	cfg.On("SponsorPublicKey").Return(nil).Maybe()
+	cfg.On("TxStoragePath").Return("").Maybe()
+	return cfg
+}
@@ -5,36 +5,81 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NethermindEth/juno/core/felt"
 	starknetaccount "github.com/NethermindEth/starknet.go/account"
 	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
 	starknetutils "github.com/NethermindEth/starknet.go/utils"
+	"github.com/google/uuid"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/logger"
 	"github.com/smartcontractkit/chainlink-common/pkg/loop"
 	"github.com/smartcontractkit/chainlink-common/pkg/services"
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types"
 	"github.com/smartcontractkit/chainlink-common/pkg/utils"
 
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/chainlink/txm/fees"
 	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
 )
 
 const (
-	MaxQueueLen = 1000
+	// TxRecordRetention is how long confirmed and failed txs are kept in the TxStorage
+	TxRecordRetention = 24 * time.Hour
 )
 
 type TxManager interface {
-	Enqueue(ctx context.Context, accountAddress *felt.Felt, publicKey *felt.Felt, txFn starknetrpc.FunctionCall) error
+	// Enqueue queues a call for broadcast and returns its tx ID. If txID is empty a random ID is generated,
+	// otherwise it is used as an idempotency key: enqueueing a known txID returns it without queueing the call again.
+	Enqueue(ctx context.Context, accountAddress *felt.Felt, publicKey *felt.Felt, txFn starknetrpc.FunctionCall, txID string, opts TxOpts) (string, error)
+	// GetTransactionStatus returns the status of the tx with the given ID, or ErrTxNotFound.
+	GetTransactionStatus(ctx context.Context, txID string) (commontypes.TransactionStatus, error)
+	// GetTransaction returns the full record of the tx with the given ID, or ErrTxNotFound.
+	GetTransaction(ctx context.Context, txID string) (TxRecord, error)
+	// ListTransactions returns the txs of accountAddress (all accounts if nil) that are in one of states
+	// (any state if empty), ordered by creation time.
+	ListTransactions(ctx context.Context, accountAddress *felt.Felt, states ...TxState) ([]TxRecord, error)
 	InflightCount() (int, int)
+	// FeeSpend returns the actual fees paid for the accepted txs that match filter, summed by account, target contract
+	// and selector. Fees are kept for FeeLedgerRetention.
+	FeeSpend(ctx context.Context, filter SpendFilter) ([]FeeSpend, error)
+	// AccountAddress returns the counterfactual address of the account deployed for publicKey (see DeployAccount).
+	AccountAddress(publicKey *felt.Felt) (*felt.Felt, error)
+	// DeployAccount deploys the account of the configured AccountClass for a keystore key once its counterfactual
+	// address is funded, and returns the hash of the DEPLOY_ACCOUNT tx after it was accepted.
+	DeployAccount(ctx context.Context, publicKey *felt.Felt) (string, error)
+	// FeeToken returns the address of the ERC20 token that pays the fees of accountAddress, see AccountFlavour.
+	FeeToken(accountAddress *felt.Felt) string
+	// EstimateMaxFee returns the most that accountAddress may be charged in its FeeToken for an invoke of call, zero if
+	// its fees are paid by the SponsorAccount.
+	EstimateMaxFee(ctx context.Context, accountAddress *felt.Felt, publicKey *felt.Felt, call starknetrpc.FunctionCall) (*big.Int, error)
 }
 
 type Tx struct {
+	id             string
 	publicKey      *felt.Felt
 	accountAddress *felt.Felt
 	call           starknetrpc.FunctionCall
+	priority       Priority
+	caller         string
+	createdAt      time.Time
+}
+
+// TxOpts schedule a tx among the queued txs of its account.
+type TxOpts struct {
+	Priority Priority
+	// Caller identifies the feed or component that enqueued the tx, the txs of different callers of the same
+	// priority are broadcast round-robin. Defaults to the called contract.
+	Caller string
+}
+
+// txFromRecord returns the queued tx of a record.
+func txFromRecord(r TxRecord) Tx {
+	return Tx{id: r.ID, publicKey: r.PublicKey, accountAddress: r.AccountAddress, call: r.Call, priority: r.Priority, caller: r.Caller, createdAt: r.CreatedAt}
 }
 
 type StarkTXM interface {
@@ -45,29 +90,76 @@ type StarkTXM interface {
 type starktxm struct {
 	starter utils.StartStopOnce
 	lggr    logger.Logger
+	chainID string
 	done    sync.WaitGroup
 	stop    chan struct{}
-	queue   chan Tx
+	queues  *txQueues
 	ks      KeystoreAdapter
 	cfg     Config
 
 	client       *utils.LazyLoad[*starknet.Client]
 	feederClient *utils.LazyLoad[*starknet.FeederClient]
 	accountStore *AccountStore
+	storage      TxStorage
+	ledger       FeeLedger
+	feeEstimator fees.Estimator
+	balances     *balanceGuard
+	deploys      *pendingDeploys
+	accepted     *acceptedTxs
+	// set by Close, new txs are rejected while the queues are drained
+	draining atomic.Bool
+	// number of txs waiting for retryLater to queue them again
+	pendingRetries atomic.Int64
+	// serializes enqueues with a caller supplied tx ID
+	enqueueLock sync.Mutex
+	// serializes the broadcasts of sponsored txs, see SponsorAccount
+	sponsorLock sync.Mutex
 }
 
-func New(lggr logger.Logger, keystore loop.Keystore, cfg Config, getClient func() (*starknet.Client, error),
+func New(lggr logger.Logger, chainID string, keystore loop.Keystore, cfg Config, getClient func() (*starknet.Client, error),
 	getFeederClient func() (*starknet.FeederClient, error)) (StarkTXM, error) {
+	storage := NewMemoryTxStorage(TxRecordRetention)
+	ledger := NewMemoryFeeLedger(cfg.FeeLedgerRetention())
+	if path := cfg.TxStoragePath(); path != "" {
+		var err error
+		storage, err = NewFileTxStorage(path, TxRecordRetention)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open tx storage: %w", err)
+		}
+		ledger, err = NewFileFeeLedger(feeLedgerPath(path), cfg.FeeLedgerRetention())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open fee ledger: %w", err)
+		}
+	}
+
+	feeEstimator, err := fees.NewEstimator(cfg.FeeEstimator())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fee estimator: %w", err)
+	}
+
+	var replaceSelectors []*felt.Felt
+	for _, name := range cfg.ReplaceSelectors() {
+		replaceSelectors = append(replaceSelectors, starknetutils.GetSelectorFromNameFelt(name))
+	}
+
 	txm := &starktxm{
 		lggr:         logger.Named(lggr, "Txm"),
-		queue:        make(chan Tx, MaxQueueLen),
+		chainID:      chainID,
+		queues:       newTxQueues(int(cfg.MaxQueueLenPerAccount()), replaceSelectors),
 		stop:         make(chan struct{}),
 		client:       utils.NewLazyLoad(getClient),
 		feederClient: utils.NewLazyLoad(getFeederClient),
 		ks:           NewKeystoreAdapter(keystore),
 		cfg:          cfg,
 		accountStore: NewAccountStore(),
+		storage:      storage,
+		ledger:       ledger,
+		feeEstimator: feeEstimator,
+		balances:     newBalanceGuard(),
+		deploys:      newPendingDeploys(),
+		accepted:     newAcceptedTxs(),
 	}
+	txm.queues.flavour = txm.accountFlavour
 
 	return txm, nil
 }
@@ -78,47 +170,258 @@ func (txm *starktxm) Name() string {
 
 func (txm *starktxm) Start(ctx context.Context) error {
 	return txm.starter.StartOnce("Txm", func() error {
-		txm.done.Add(2) // waitgroup: broadcast loop and confirm loop
-		go txm.broadcastLoop()
+		if err := txm.restore(); err != nil {
+			return fmt.Errorf("failed to restore txs from storage: %w", err)
+		}
+
+		workers := max(int(txm.cfg.BroadcastWorkers()), 1)
+		txm.done.Add(workers + 1) // waitgroup: broadcast workers and confirm loop
+		for i := 0; i < workers; i++ {
+			go txm.broadcastWorker(i)
+		}
 		go txm.confirmLoop()
+		if txm.cfg.BalancePollInterval() > 0 {
+			txm.done.Add(1)
+			go txm.balanceLoop()
+		}
 
 		return nil
 	})
 }
 
-func (txm *starktxm) broadcastLoop() {
+// restore reloads the txs persisted by a previous run: queued txs are enqueued again, and broadcast txs are
+// placed back in their account's TxStore so that the confirm loop resumes tracking them.
+func (txm *starktxm) restore() error {
+	records, err := txm.storage.Load()
+	if err != nil {
+		return err
+	}
+
+	unconfirmed := map[string][]*UnconfirmedTx{}
+	accounts := map[string]*felt.Felt{}
+	// the calls of a multicall share the account and nonce of their invoke
+	invokes := map[string]*UnconfirmedTx{}
+	var queued, inflight int
+	for _, record := range records {
+		switch record.State {
+		case TxEnqueued:
+			if err := txm.push(txFromRecord(record)); err == nil {
+				queued++
+			} else {
+				txm.deadLetter(record.AccountAddress, []string{record.ID}, deadLetterQueueFull, "queue full after restart")
+			}
+		case TxBroadcast:
+			inflight++
+			// sponsored txs were invoked by the sponsor, with a single call that executes all txs of the invoke
+			sender, publicKey, call := record.AccountAddress, record.PublicKey, record.Call
+			if record.Sponsor != nil {
+				sender, publicKey, call = record.Sponsor.AccountAddress, record.Sponsor.PublicKey, record.Sponsor.Call
+			}
+			addressStr := sender.String()
+			invokeKey := addressStr + "/" + record.Nonce.String()
+			if invoke, ok := invokes[invokeKey]; ok {
+				invoke.IDs = append(invoke.IDs, record.ID)
+				if record.Sponsor == nil {
+					invoke.Calls = append(invoke.Calls, call)
+				}
+				continue
+			}
+			invoke := &UnconfirmedTx{
+				IDs:         []string{record.ID},
+				Hash:        record.Hash,
+				Attempts:    record.Attempts,
+				BroadcastAt: record.UpdatedAt,
+				PublicKey:   publicKey,
+				Nonce:       record.Nonce,
+				Calls:       []starknetrpc.FunctionCall{call},
+			}
+			invokes[invokeKey] = invoke
+			accounts[addressStr] = sender
+			unconfirmed[addressStr] = append(unconfirmed[addressStr], invoke)
+		case TxAccepted:
+			if tx, ok := txm.accepted.get(record.Hash); ok {
+				tx.ids = append(tx.ids, record.ID)
+				continue
+			}
+			sender := record.AccountAddress
+			if record.Sponsor != nil {
+				sender = record.Sponsor.AccountAddress
+			}
+			txm.accepted.add(&acceptedTx{
+				accountAddress: sender,
+				hash:           record.Hash,
+				ids:            []string{record.ID},
+				blockHash:      record.BlockHash,
+				blockNumber:    record.BlockNumber,
+			})
+		case TxConfirmed, TxFailed:
+			// kept for inspection only
+		}
+	}
+
+	for addressStr, txs := range unconfirmed {
+		// the next nonce follows the restored txs, if the chain has moved ahead in the meantime
+		// the nonce is fast-forwarded during the next estimation (see resyncNonce)
+		if _, err := txm.accountStore.RestoreTxStore(accounts[addressStr], &felt.Zero, txs); err != nil {
+			return err
+		}
+	}
+
+	txm.lggr.Infow("restored txs from storage", "queued", queued, "unconfirmed", inflight, "invokes", len(invokes))
+	return nil
+}
+
+// updateRecord applies update to the stored record of a tx. Storage errors are logged and otherwise ignored,
+// as the in-memory state remains authoritative while the txm is running.
+func (txm *starktxm) updateRecord(id string, update func(r *TxRecord)) {
+	err := txm.storage.Update(id, func(r *TxRecord) {
+		update(r)
+		r.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		txm.lggr.Errorw("failed to update tx record", "id", id, "error", err)
+	}
+}
+
+// updateRecords applies update to the stored records of all txs included in the same invoke.
+func (txm *starktxm) updateRecords(ids []string, update func(r *TxRecord)) {
+	for _, id := range ids {
+		txm.updateRecord(id, update)
+	}
+}
+
+// broadcastWorker broadcasts the batches handed out by the account queues, see txQueues for the scheduling.
+func (txm *starktxm) broadcastWorker(worker int) {
 	defer txm.done.Done()
 
 	ctx, cancel := utils.ContextFromChan(txm.stop)
 	defer cancel()
 
-	txm.lggr.Debugw("broadcastLoop: started")
+	txm.lggr.Debugw("broadcastWorker: started", "worker", worker)
 	for {
-		select {
-		case <-txm.stop:
-			txm.lggr.Debugw("broadcastLoop: stopped")
-			return
-		case tx := <-txm.queue:
+		aq, batch, ok := txm.queues.next(int(txm.cfg.BatchMaxCalls()), int(txm.cfg.BatchMaxCalldataLen()))
+		if !ok {
+			select {
+			case <-txm.stop:
+				txm.lggr.Debugw("broadcastWorker: stopped", "worker", worker)
+				return
+			case <-txm.queues.notify:
+			}
+			continue
+		}
+
+		txm.observeQueueDepth(aq.accountAddress)
+
+		var expired []string
+		batch = slices.DeleteFunc(batch, func(tx Tx) bool {
+			if txm.expired(tx.createdAt) {
+				expired = append(expired, tx.id)
+				return true
+			}
+			return false
+		})
+		if len(expired) > 0 {
+			txm.deadLetter(aq.accountAddress, expired, deadLetterExpired, "expired before broadcast")
+		}
+
+		if len(batch) > 0 {
 			if _, err := txm.client.Get(); err != nil {
 				txm.lggr.Errorw("failed to fetch client: skipping processing tx", "error", err)
-				continue
+				promBroadcasts.WithLabelValues(txm.chainID, aq.accountAddress.String(), broadcastErrClient).Inc()
+				txm.retryOrDeadLetter(batch, "", err.Error())
+			} else {
+				// broadcast the txs of an account serially - wait until accepted by mempool before processing next
+				if requeue := txm.broadcastBatch(ctx, batch); len(requeue) > 0 {
+					txm.queues.requeue(aq, requeue)
+					txm.observeQueueDepth(aq.accountAddress)
+				}
 			}
+		}
+		txm.queues.done(aq)
 
-			// broadcast tx serially - wait until accepted by mempool before processing next
-			hash, err := txm.broadcast(ctx, tx.publicKey, tx.accountAddress, tx.call)
-			if err != nil {
-				txm.lggr.Errorw("transaction failed to broadcast", "error", err, "tx", tx.call)
-			} else {
-				txm.lggr.Infow("transaction broadcast", "txhash", hash)
+		select {
+		case <-txm.stop:
+			txm.lggr.Debugw("broadcastWorker: stopped", "worker", worker)
+			return
+		default:
+		}
+	}
+}
+
+// broadcastBatch broadcasts the batch as a single invoke. If the fees of a multicall cannot be estimated, which
+// usually means that one of the calls fails, every call is retried in its own invoke so that the outcome of a
+// call does not depend on the others. It returns the txs that have to be queued again because the account has
+// been paused, see ErrInsufficientBalance.
+func (txm *starktxm) broadcastBatch(ctx context.Context, batch []Tx) (requeue []Tx) {
+	ids := txIDs(batch)
+	hash, err := txm.broadcast(ctx, batch)
+	if err != nil && ctx.Err() != nil {
+		// interrupted by Close, the txs are still queued in the TxStorage
+		txm.lggr.Warnw("transaction broadcast interrupted by shutdown", "ids", ids, "error", err)
+		return nil
+	}
+	var simErr *SimulationError
+	isSimErr := errors.As(err, &simErr)
+	if err != nil && len(batch) > 1 && (isSimErr || errors.Is(err, errEstimateFailed)) {
+		txm.lggr.Warnw("multicall would fail, broadcasting calls individually", "ids", ids, "error", err)
+		for i, tx := range batch {
+			if requeue = txm.broadcastBatch(ctx, []Tx{tx}); len(requeue) > 0 {
+				return batch[i:]
 			}
 		}
+		return nil
 	}
+	accountAddressStr := batch[0].accountAddress.String()
+	if err != nil {
+		promBroadcasts.WithLabelValues(txm.chainID, accountAddressStr, broadcastErrorClass(err)).Inc()
+		if errors.Is(err, errDeployPending) {
+			// not a failed attempt, the txs are broadcast once the account is deployed
+			txm.lggr.Infow("transaction broadcast waits for account deployment", "ids", ids, "error", err)
+			for _, tx := range batch {
+				txm.retryLater(tx, txm.cfg.BroadcastRetryBackoff())
+			}
+			return nil
+		}
+		if errors.Is(err, ErrInsufficientBalance) {
+			// not a failed attempt, the txs are broadcast once the account is funded
+			txm.lggr.Warnw("transaction broadcast paused", "ids", ids, "error", err)
+			txm.updateRecords(ids, func(r *TxRecord) {
+				r.Error = err.Error()
+			})
+			if !txm.balances.isPaused(batch[0].accountAddress) {
+				// an undeployed account is not tracked by the balance guard, check its funding again later
+				for _, tx := range batch {
+					txm.retryLater(tx, txm.cfg.BroadcastRetryBackoff())
+				}
+				return nil
+			}
+			return batch
+		}
+		txm.lggr.Errorw("transaction failed to broadcast", "ids", ids, "error", err, "calls", batch)
+		if !isSimErr {
+			txm.retryOrDeadLetter(batch, "", err.Error())
+			return nil
+		}
+		// the call would revert, retrying does not help
+		promTxRevertReasons.WithLabelValues(txm.chainID, accountAddressStr, "simulated", string(simErr.Reason.Kind)).Inc()
+		txm.updateRecords(ids, func(r *TxRecord) {
+			r.State = TxFailed
+			r.Error = err.Error()
+			r.RevertReason = simErr.Reason
+		})
+		return nil
+	}
+	promBroadcasts.WithLabelValues(txm.chainID, accountAddressStr, broadcastSuccess).Inc()
+	txm.lggr.Infow("transaction broadcast", "txhash", hash, "ids", ids, "calls", len(batch))
+	return nil
 }
 
 const FeeMargin uint32 = 115
 const RPCNonceErrMsg = "Invalid transaction nonce"
 
-func (txm *starktxm) estimateFriFee(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, tx starknetrpc.InvokeTxnV3) (*starknetrpc.FeeEstimate, *felt.Felt, error) {
+var errEstimateFailed = errors.New("failed to get FRI estimate")
+
+func (txm *starktxm) estimateFee(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, tx *invokeTx) (*starknetrpc.FeeEstimate, *felt.Felt, error) {
 	// skip prevalidation, which is known to overestimate amount of gas needed and error with L1GasBoundsExceedsBalance
 	simFlags := []starknetrpc.SimulationFlag{starknetrpc.SKIP_VALIDATE}
 
@@ -127,17 +430,18 @@ func (txm *starktxm) estimateFriFee(ctx context.Context, client *starknet.Client
 	for i := 1; i <= 5; i++ {
 		txm.lggr.Infow("attempt to estimate fee", "attempt", i)
 
-		estimateNonce, err := client.AccountNonce(ctx, accountAddress)
+		// the nonce accounts for pending txs, whatever the default block of the client
+		estimateNonce, err := client.AccountNonceAt(ctx, accountAddress, starknetrpc.WithBlockTag(starknet.BlockTagPending))
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to check account nonce: %+w", err)
 		}
-		tx.Nonce = estimateNonce
+		tx.setNonce(estimateNonce)
 
 		if largestEstimateNonce == nil || estimateNonce.Cmp(largestEstimateNonce) > 0 {
 			largestEstimateNonce = estimateNonce
 		}
 
-		feeEstimate, err := client.Provider.EstimateFee(ctx, []starknetrpc.BroadcastTxn{tx}, simFlags, starknetrpc.BlockID{Tag: "pending"})
+		feeEstimate, err := client.Provider.EstimateFee(ctx, []starknetrpc.BroadcastTxn{tx.txn()}, simFlags, starknetrpc.WithBlockTag(starknet.BlockTagPending))
 		if err != nil {
 			var dataErr *starknetrpc.RPCError
 			if !errors.As(err, &dataErr) {
@@ -155,84 +459,92 @@ func (txm *starktxm) estimateFriFee(ctx context.Context, client *starknet.Client
 			return nil, nil, fmt.Errorf("failed to estimate fee: %T %+v", err, err)
 		}
 
-		// track the FRI estimate, but keep looping so we print out all estimates
-		var friEstimate *starknetrpc.FeeEstimate
+		// track the estimate in the fee unit of the tx (FRI for V3), but keep looping so we print out all estimates
+		var unitEstimate *starknetrpc.FeeEstimate
 		for j, f := range feeEstimate {
 			txm.lggr.Infow("Estimated fee", "attempt", i, "index", j, "EstimateNonce", estimateNonce, "GasConsumed", f.GasConsumed, "GasPrice", f.GasPrice, "DataGasConsumed", f.DataGasConsumed, "DataGasPrice", f.DataGasPrice, "OverallFee", f.OverallFee, "FeeUnit", string(f.FeeUnit))
-			if f.FeeUnit == "FRI" {
-				friEstimate = &feeEstimate[j]
+			if string(f.FeeUnit) == tx.feeUnit() {
+				unitEstimate = &feeEstimate[j]
 			}
 		}
-		if friEstimate != nil {
-			return friEstimate, largestEstimateNonce, nil
+		if unitEstimate != nil {
+			return unitEstimate, largestEstimateNonce, nil
 		}
 
-		txm.lggr.Errorw("No FRI estimate was returned", "attempt", i)
+		txm.lggr.Errorw("No estimate in the fee unit of the tx was returned", "attempt", i, "feeUnit", tx.feeUnit())
 	}
 
 	txm.lggr.Errorw("all attempts to estimate fee failed")
 	return nil, nil, fmt.Errorf("all attempts to estimate fee failed")
 }
 
-func (txm *starktxm) broadcast(ctx context.Context, publicKey *felt.Felt, accountAddress *felt.Felt, call starknetrpc.FunctionCall) (txhash string, err error) {
+// broadcast sends the calls of txs, which must all belong to the same account, as a single invoke. If a
+// SponsorAccount is configured, the invoke is sent by the sponsor, which executes the calls from outside.
+func (txm *starktxm) broadcast(ctx context.Context, txs []Tx) (txhash string, err error) {
+	accountAddress, publicKey := txs[0].accountAddress, txs[0].publicKey
+	ids := txIDs(txs)
+	calls := make([]starknetrpc.FunctionCall, len(txs))
+	for i, tx := range txs {
+		calls[i] = tx.call
+	}
+
 	client, err := txm.client.Get()
 	if err != nil {
 		txm.client.Reset()
 		return txhash, fmt.Errorf("broadcast: failed to fetch client: %+w", err)
 	}
 
-	txStore := txm.accountStore.GetTxStore(accountAddress)
+	// sender sends the invoke and owns its nonce, the txs remain queued and paused under accountAddress
+	sender, senderKey := accountAddress, publicKey
+	var sponsorship *Sponsorship
+	if sponsor := txm.cfg.SponsorAccount(); sponsor != nil {
+		call, sponsorErr := txm.sponsorCall(ctx, client, accountAddress, publicKey, sponsor, ids, calls)
+		if sponsorErr != nil {
+			return txhash, sponsorErr
+		}
+		sponsorship = &Sponsorship{AccountAddress: sponsor, PublicKey: txm.cfg.SponsorPublicKey(), Call: call}
+		sender, senderKey, calls = sponsor, sponsorship.PublicKey, []starknetrpc.FunctionCall{call}
+		// the nonce of the sponsor is shared by the broadcasts of all accounts
+		txm.sponsorLock.Lock()
+		defer txm.sponsorLock.Unlock()
+	}
+
+	txStore := txm.accountStore.GetTxStore(sender)
 	if txStore == nil {
-		initialNonce, accountNonceErr := client.AccountNonce(ctx, accountAddress)
+		initialNonce, accountNonceErr := client.AccountNonceAt(ctx, sender, starknetrpc.WithBlockTag(starknet.BlockTagPending))
+		if accountNonceErr != nil && isContractNotFound(accountNonceErr) {
+			// the account is used for the first time, deploy it before its first invoke
+			if deployErr := txm.autoDeployAccount(ctx, client, sender, senderKey); deployErr != nil {
+				return txhash, fmt.Errorf("first broadcast of account: %+w", deployErr)
+			}
+			initialNonce, accountNonceErr = client.AccountNonceAt(ctx, sender, starknetrpc.WithBlockTag(starknet.BlockTagPending))
+		}
 		if accountNonceErr != nil {
 			return txhash, fmt.Errorf("failed to check account nonce during TxStore creation: %+w", accountNonceErr)
 		}
-		newTxStore, createErr := txm.accountStore.CreateTxStore(accountAddress, initialNonce)
+		// the account is deployed, possibly by an auto deployment that was not checked since
+		txm.deploys.remove(sender)
+		newTxStore, createErr := txm.accountStore.CreateTxStore(sender, initialNonce)
 		if createErr != nil {
 			return txhash, fmt.Errorf("failed to create TxStore: %+w", createErr)
 		}
 		txStore = newTxStore
 	}
 
-	// create new account
-	cairoVersion := 2
-	account, err := starknetaccount.NewAccount(client.Provider, accountAddress, publicKey.String(), txm.ks, cairoVersion)
+	flavour := txm.accountFlavour(sender)
+	account, err := txm.newAccount(client, sender, senderKey)
 	if err != nil {
-		return txhash, fmt.Errorf("failed to create new account: %+w", err)
-	}
-
-	tx := starknetrpc.InvokeTxnV3{
-		Type:          starknetrpc.TransactionType_Invoke,
-		SenderAddress: account.AccountAddress,
-		Version:       starknetrpc.TransactionV3,
-		Signature:     []*felt.Felt{},
-		Nonce:         &felt.Zero, // filled in below
-		ResourceBounds: starknetrpc.ResourceBoundsMapping{
-			L1Gas: starknetrpc.ResourceBounds{
-				MaxAmount:       "0x0",
-				MaxPricePerUnit: "0x0",
-			},
-			L2Gas: starknetrpc.ResourceBounds{
-				MaxAmount:       "0x0",
-				MaxPricePerUnit: "0x0",
-			},
-		},
-		Tip:                   "0x0",
-		PayMasterData:         []*felt.Felt{},
-		AccountDeploymentData: []*felt.Felt{},
-		NonceDataMode:         starknetrpc.DAModeL1,
-		FeeMode:               starknetrpc.DAModeL1,
-	}
-
-	// Building the Calldata with the help of FmtCalldata where we pass in the FnCall struct along with the Cairo version
-	tx.Calldata, err = account.FmtCalldata([]starknetrpc.FunctionCall{call})
+		return txhash, err
+	}
+
+	tx, err := newInvokeTx(account, flavour, calls)
 	if err != nil {
 		return txhash, err
 	}
 
-	friEstimate, largestEstimateNonce, err := txm.estimateFriFee(ctx, client, accountAddress, tx)
+	estimate, largestEstimateNonce, err := txm.estimateFee(ctx, client, sender, tx)
 	if err != nil {
-		return txhash, fmt.Errorf("failed to get FRI estimate: %+w", err)
+		return txhash, fmt.Errorf("%w: %+w", errEstimateFailed, err)
 	}
 
 	nonce := txStore.GetNextNonce()
@@ -242,55 +554,147 @@ func (txm *starktxm) broadcast(ctx context.Context, publicKey *felt.Felt, accoun
 		// See resyncNonce for a more detailed explanation.
 		staleTxs := txStore.SetNextNonce(largestEstimateNonce)
 		txm.lggr.Infow("fast-forwarding nonce after resync", "previousNonce", nonce, "updatedNonce", largestEstimateNonce, "staleTxs", len(staleTxs))
+		promNonceResyncs.WithLabelValues(txm.chainID, sender.String()).Inc()
 		if len(staleTxs) > 0 {
-			txm.lggr.Errorw("unexpected stale transactions after nonce fast-forward", "accountAddress", accountAddress)
+			txm.lggr.Errorw("unexpected stale transactions after nonce fast-forward", "accountAddress", sender)
 		}
+		txm.dropStaleTxs(sender, staleTxs)
 		nonce = largestEstimateNonce
 	}
 
-	// TODO: consider making this configurable
-	// pad estimate to 250% (add extra because estimate did not include validation)
-	gasConsumed := friEstimate.GasConsumed.BigInt(new(big.Int))
-	expandedGas := new(big.Int).Mul(gasConsumed, big.NewInt(250))
-	maxGas := new(big.Int).Div(expandedGas, big.NewInt(100))
-	tx.ResourceBounds.L1Gas.MaxAmount = starknetrpc.U64(starknetutils.BigIntToFelt(maxGas).String())
+	params, err := txm.invokeFees(ctx, client, tx, estimate, 0)
+	if err != nil {
+		return txhash, err
+	}
+	tx.setFees(params)
+	bounds := params.ResourceBoundsMapping()
+	txm.lggr.Infow("Set resource bounds", "flavour", flavour, "sponsored", sponsorship != nil, "L1MaxAmount", bounds.L1Gas.MaxAmount, "L1MaxPricePerUnit", bounds.L1Gas.MaxPricePerUnit, "L2MaxAmount", bounds.L2Gas.MaxAmount, "L2MaxPricePerUnit", bounds.L2Gas.MaxPricePerUnit, "Tip", params.Tip, "MaxFee", params.MaxFee())
+	if err = txm.checkBalance(ctx, client, accountAddress, params.MaxFee()); err != nil {
+		return txhash, err
+	}
+
+	tx.setNonce(nonce)
+	txhash, err = txm.signAndSend(ctx, client, account, tx, txm.cfg.SimulateTxs())
+	if err != nil {
+		if isInsufficientBalance(err) && txm.cfg.BalancePollInterval() > 0 {
+			pauseErr := txm.pauseAccount(pausedAccount{accountAddress: accountAddress, maxFee: params.MaxFee(), since: time.Now()})
+			return txhash, fmt.Errorf("%w: %+w", pauseErr, err)
+		}
+		return txhash, err
+	}
 
-	// pad by 150%
-	gasPrice := friEstimate.GasPrice.BigInt(new(big.Int))
-	overallFee := friEstimate.OverallFee.BigInt(new(big.Int)) // overallFee = gas_used*gas_price + data_gas_used*data_gas_price
+	// update nonce if transaction is successful
+	err = txStore.AddUnconfirmed(ids, nonce, txhash, calls, senderKey)
+	if err != nil {
+		return txhash, fmt.Errorf("failed to add unconfirmed tx: %+w", err)
+	}
+	txm.updateRecords(ids, func(r *TxRecord) {
+		r.State = TxBroadcast
+		r.Nonce = nonce
+		r.Hash = txhash
+		r.Attempts = []string{txhash}
+		r.EstimatedFee = estimate.OverallFee.BigInt(new(big.Int))
+		r.Sponsor = sponsorship
+	})
+	return txhash, nil
+}
 
-	// TODO: consider making this configurable
-	// pad estimate to 150% (add extra because estimate did not include validation)
-	gasUnits := new(big.Int).Div(overallFee, gasPrice)
-	expandedGasUnits := new(big.Int).Mul(gasUnits, big.NewInt(150))
-	maxGasUnits := new(big.Int).Div(expandedGasUnits, big.NewInt(100))
-	tx.ResourceBounds.L1Gas.MaxAmount = starknetrpc.U64(starknetutils.BigIntToFelt(maxGasUnits).String())
+// rebroadcast re-signs a stuck tx at its original nonce with bumped resource bounds. Every attempt bumps the
+// freshly estimated fee by another FeeBumpPercent, so that the replacement outbids the previous attempts.
+func (txm *starktxm) rebroadcast(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, unconfirmedTx *UnconfirmedTx) (txhash string, err error) {
+	account, err := txm.newAccount(client, accountAddress, unconfirmedTx.PublicKey)
+	if err != nil {
+		return txhash, err
+	}
 
-	// pad by 150%
-	expandedGasPrice := new(big.Int).Mul(gasPrice, big.NewInt(150))
-	maxGasPrice := new(big.Int).Div(expandedGasPrice, big.NewInt(100))
-	tx.ResourceBounds.L1Gas.MaxPricePerUnit = starknetrpc.U128(starknetutils.BigIntToFelt(maxGasPrice).String())
+	tx, err := newInvokeTx(account, txm.accountFlavour(accountAddress), unconfirmedTx.Calls)
+	if err != nil {
+		return txhash, err
+	}
 
-	txm.lggr.Infow("Set resource bounds", "L1MaxAmount", tx.ResourceBounds.L1Gas.MaxAmount, "L1MaxPricePerUnit", tx.ResourceBounds.L1Gas.MaxPricePerUnit)
+	estimate, _, err := txm.estimateFee(ctx, client, accountAddress, tx)
+	if err != nil {
+		return txhash, fmt.Errorf("%w: %+w", errEstimateFailed, err)
+	}
 
-	tx.Nonce = nonce
-	// Re-sign transaction now that we've determined MaxFee
-	// TODO: SignInvokeTransaction for V3 is missing so we do it by hand
-	hash, err := account.TransactionHashInvoke(tx)
+	params, err := txm.invokeFees(ctx, client, tx, estimate, len(unconfirmedTx.Attempts))
 	if err != nil {
 		return txhash, err
 	}
-	signature, err := account.Sign(ctx, hash)
+	tx.setFees(params)
+	bounds := params.ResourceBoundsMapping()
+	txm.lggr.Infow("Set bumped resource bounds", "nonce", unconfirmedTx.Nonce, "attempt", len(unconfirmedTx.Attempts)+1, "L1MaxAmount", bounds.L1Gas.MaxAmount, "L1MaxPricePerUnit", bounds.L1Gas.MaxPricePerUnit, "Tip", params.Tip, "MaxFee", params.MaxFee())
+	if err = txm.checkBalance(ctx, client, accountAddress, params.MaxFee()); err != nil {
+		return txhash, err
+	}
+
+	tx.setNonce(unconfirmedTx.Nonce)
+	// not simulated again, the previous attempts hold the nonce whatever the outcome
+	txhash, err = txm.signAndSend(ctx, client, account, tx, false)
 	if err != nil {
 		return txhash, err
 	}
-	tx.Signature = signature
+
+	txStore := txm.accountStore.GetTxStore(accountAddress)
+	if err = txStore.AddAttempt(unconfirmedTx.Nonce, txhash); err != nil {
+		return txhash, fmt.Errorf("failed to add tx attempt: %+w", err)
+	}
+	txm.updateRecords(unconfirmedTx.IDs, func(r *TxRecord) {
+		r.Hash = txhash
+		r.Attempts = append(r.Attempts, txhash)
+		r.EstimatedFee = estimate.OverallFee.BigInt(new(big.Int))
+	})
+	return txhash, nil
+}
+
+func (txm *starktxm) newAccount(client *starknet.Client, accountAddress *felt.Felt, publicKey *felt.Felt) (*starknetaccount.Account, error) {
+	cairoVersion := txm.accountFlavour(accountAddress).cairoVersion()
+	account, err := starknetaccount.NewAccount(client.Provider, accountAddress, publicKey.String(), txm.ks, cairoVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new account: %+w", err)
+	}
+	return account, nil
+}
+
+// estimateFees computes the fees of a tx from its FRI estimate. For rebroadcasts the fees are additionally
+// bumped by FeeBumpPercent, compounded once for every previous attempt. The result must not exceed MaxFee.
+func (txm *starktxm) estimateFees(ctx context.Context, client *starknet.Client, friEstimate *starknetrpc.FeeEstimate, attempts int) (fees.Params, error) {
+	params, err := txm.feeEstimator.Estimate(ctx, client, friEstimate)
+	if err != nil {
+		return fees.Params{}, fmt.Errorf("failed to estimate fees: %+w", err)
+	}
+	for i := 0; i < attempts; i++ {
+		params = params.Bump(txm.cfg.FeeBumpPercent())
+	}
+
+	if maxFee := txm.cfg.FeeEstimator().MaxFee; maxFee != nil && params.MaxFee().Cmp(maxFee) > 0 {
+		return fees.Params{}, fmt.Errorf("%w: max fee %s, limit %s", fees.ErrExceedsMax, params.MaxFee(), maxFee)
+	}
+	return params, nil
+}
+
+// signAndSend signs the tx with the account key and submits it to the mempool. If simulate is set, the signed tx
+// is only submitted if it does not revert in simulation.
+func (txm *starktxm) signAndSend(ctx context.Context, client *starknet.Client, account *starknetaccount.Account, tx *invokeTx, simulate bool) (txhash string, err error) {
+	accountAddress := account.AccountAddress
+
+	// Re-sign transaction now that we've determined MaxFee
+	// TODO: SignInvokeTransaction for V3 is missing so we do it by hand
+	if err = txm.signInvoke(ctx, client, account, tx); err != nil {
+		return txhash, err
+	}
+
+	if simulate {
+		if err = txm.simulate(ctx, client, tx); err != nil {
+			return txhash, err
+		}
+	}
 
 	execCtx, execCancel := context.WithTimeout(ctx, txm.cfg.TxTimeout())
 	defer execCancel()
 
 	// finally, transmit the invoke
-	res, err := account.AddInvokeTransaction(execCtx, tx)
+	res, err := account.AddInvokeTransaction(execCtx, tx.txn())
 	if err != nil {
 		// TODO: handle initial broadcast errors - what kind of errors occur?
 		var dataErr *starknetrpc.RPCError
@@ -317,13 +721,7 @@ func (txm *starktxm) broadcast(ctx context.Context, publicKey *felt.Felt, accoun
 		return txhash, errors.New("execute response and error are nil")
 	}
 
-	// update nonce if transaction is successful
-	txhash = res.TransactionHash.String()
-	err = txStore.AddUnconfirmed(nonce, txhash, call, publicKey)
-	if err != nil {
-		return txhash, fmt.Errorf("failed to add unconfirmed tx: %+w", err)
-	}
-	return txhash, nil
+	return res.TransactionHash.String(), nil
 }
 
 func (txm *starktxm) confirmLoop() {
@@ -348,6 +746,14 @@ func (txm *starktxm) confirmLoop() {
 			}
 
 			allUnconfirmedTxs := txm.accountStore.GetAllUnconfirmed()
+			var hashes []string
+			for _, unconfirmedTxs := range allUnconfirmedTxs {
+				for _, unconfirmedTx := range unconfirmedTxs {
+					hashes = append(hashes, unconfirmedTx.Attempts...)
+				}
+			}
+			hashes = append(hashes, txm.accepted.hashes()...)
+			statuses := txm.fetchTxStatuses(ctx, client, hashes)
 			for accountAddressStr, unconfirmedTxs := range allUnconfirmedTxs {
 				accountAddress, err := new(felt.Felt).SetString(accountAddressStr)
 				// this should never occur because the account address string key was created from the account address felt.
@@ -356,50 +762,10 @@ func (txm *starktxm) confirmLoop() {
 					continue
 				}
 				for _, unconfirmedTx := range unconfirmedTxs {
-					hash := unconfirmedTx.Hash
-					f, err := starknetutils.HexToFelt(hash)
-					if err != nil {
-						txm.lggr.Errorw("invalid felt value", "hash", hash)
-						continue
-					}
-					response, err := client.Provider.GetTransactionStatus(ctx, f)
-
-					// tx can be rejected due to a nonce error. but we cannot know from the Starknet RPC directly  so we have to wait for
-					// a broadcasted tx to fail in order to fix the nonce errors
-
-					if err != nil {
-						txm.lggr.Errorw("failed to fetch transaction status", "hash", hash, "nonce", unconfirmedTx.Nonce, "error", err)
-						continue
-					}
-
-					finalityStatus := response.FinalityStatus
-					executionStatus := response.ExecutionStatus
-
-					// any finalityStatus other than received
-					if finalityStatus == starknetrpc.TxnStatus_Accepted_On_L1 || finalityStatus == starknetrpc.TxnStatus_Accepted_On_L2 || finalityStatus == starknetrpc.TxnStatus_Rejected {
-						txm.lggr.Debugw(fmt.Sprintf("tx confirmed: %s", finalityStatus), "hash", hash, "nonce", unconfirmedTx.Nonce, "finalityStatus", finalityStatus)
-						if err := txm.accountStore.GetTxStore(accountAddress).Confirm(unconfirmedTx.Nonce, hash); err != nil {
-							txm.lggr.Errorw("failed to confirm tx in TxStore", "hash", hash, "accountAddress", accountAddress, "error", err)
-						}
-					}
-
-					// currently, feeder client is only way to get rejected reason
-					if finalityStatus == starknetrpc.TxnStatus_Rejected {
-						// we assume that all rejected transactions results in a unused rejected nonce, so
-						// resync. see the comment at resyncNonce for more details.
-						if resyncErr := txm.resyncNonce(ctx, client, accountAddress); resyncErr != nil {
-							txm.lggr.Errorw("resync failed for rejected tx", "error", resyncErr)
-						}
-
-						go txm.logFeederError(ctx, hash, f)
-					}
-
-					if executionStatus == starknetrpc.TxnExecutionStatusREVERTED {
-						// TODO: get revert reason?
-						txm.lggr.Errorw("transaction reverted", "hash", hash)
-					}
+					txm.checkUnconfirmed(ctx, client, accountAddress, unconfirmedTx, statuses)
 				}
 			}
+			txm.checkAccepted(ctx, client, statuses)
 		case <-txm.stop:
 			txm.lggr.Debugw("confirmLoop: stopped")
 			return
@@ -409,20 +775,215 @@ func (txm *starktxm) confirmLoop() {
 	}
 }
 
-func (txm *starktxm) logFeederError(ctx context.Context, hash string, f *felt.Felt) {
-	feederClient, err := txm.feederClient.Get()
+// checkUnconfirmed checks the statuses of every attempt of an unconfirmed tx, see fetchTxStatuses. The tx is confirmed
+// as soon as any attempt is accepted, and rebroadcast with a higher fee if it has been pending for longer than
+// RebroadcastTimeout.
+func (txm *starktxm) checkUnconfirmed(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, unconfirmedTx *UnconfirmedTx, statuses map[string]txStatusResult) {
+	var hash string
+	var finalityStatus starknetrpc.TxnStatus
+	var executionStatus starknetrpc.TxnExecutionStatus
+	pending := false
+
+	// newest attempt first, it is the most likely to land
+	for i := len(unconfirmedTx.Attempts) - 1; i >= 0; i-- {
+		attemptHash := unconfirmedTx.Attempts[i]
+		result, ok := statuses[attemptHash]
+		if !ok {
+			result.err = errors.New("status not fetched")
+		}
+		response, err := result.status, result.err
+
+		// tx can be rejected due to a nonce error. but we cannot know from the Starknet RPC directly  so we have to wait for
+		// a broadcasted tx to fail in order to fix the nonce errors
+
+		if err != nil {
+			txm.lggr.Errorw("failed to fetch transaction status", "hash", attemptHash, "nonce", unconfirmedTx.Nonce, "error", err)
+			pending = true
+			continue
+		}
+
+		hash = attemptHash
+		finalityStatus = response.FinalityStatus
+		executionStatus = response.ExecutionStatus
+		if finalityStatus == starknetrpc.TxnStatus_Accepted_On_L1 || finalityStatus == starknetrpc.TxnStatus_Accepted_On_L2 {
+			pending = false
+			break
+		}
+		if finalityStatus != starknetrpc.TxnStatus_Rejected {
+			pending = true
+		}
+	}
+
+	if hash == "" {
+		// no status could be fetched for any attempt, the tx may have been dropped from the mempool
+		pending = true
+	}
+
+	if pending && txm.abandonExpired(ctx, client, accountAddress, unconfirmedTx) {
+		return
+	}
+
+	if pending {
+		timeout := txm.cfg.RebroadcastTimeout()
+		if timeout == 0 || time.Since(unconfirmedTx.BroadcastAt) < timeout {
+			return
+		}
+		if txm.balances.isPaused(accountAddress) {
+			// the bumped fee is not affordable either
+			return
+		}
+		if len(unconfirmedTx.Attempts) > int(txm.cfg.MaxFeeBumps()) {
+			txm.lggr.Warnw("tx still unconfirmed after max fee bumps", "hash", unconfirmedTx.Hash, "nonce", unconfirmedTx.Nonce, "attempts", len(unconfirmedTx.Attempts))
+			return
+		}
+		newHash, err := txm.rebroadcast(ctx, client, accountAddress, unconfirmedTx)
+		if err != nil {
+			txm.lggr.Errorw("failed to rebroadcast stuck tx", "hash", unconfirmedTx.Hash, "nonce", unconfirmedTx.Nonce, "error", err)
+			promBroadcasts.WithLabelValues(txm.chainID, accountAddress.String(), broadcastErrorClass(err)).Inc()
+			return
+		}
+		promBroadcasts.WithLabelValues(txm.chainID, accountAddress.String(), broadcastSuccess).Inc()
+		txm.lggr.Infow("stuck transaction rebroadcast", "previousHash", unconfirmedTx.Hash, "txhash", newHash, "nonce", unconfirmedTx.Nonce)
+		return
+	}
+
+	// either an attempt was accepted, or every attempt was rejected
+	txm.lggr.Debugw(fmt.Sprintf("tx confirmed: %s", finalityStatus), "hash", hash, "nonce", unconfirmedTx.Nonce, "finalityStatus", finalityStatus)
+	if err := txm.accountStore.GetTxStore(accountAddress).Confirm(unconfirmedTx.Nonce, hash); err != nil {
+		txm.lggr.Errorw("failed to confirm tx in TxStore", "hash", hash, "accountAddress", accountAddress, "error", err)
+	}
+	// with L1 finality, accepted txs are tracked until their block reaches L1, see checkAccepted
+	awaitL1 := txm.l1Finality() && finalityStatus == starknetrpc.TxnStatus_Accepted_On_L2 && executionStatus != starknetrpc.TxnExecutionStatusREVERTED
+	txm.updateRecords(unconfirmedTx.IDs, func(r *TxRecord) {
+		r.Hash = hash
+		switch {
+		case finalityStatus == starknetrpc.TxnStatus_Rejected:
+			r.State = TxFailed
+			r.Error = "transaction rejected"
+		case executionStatus == starknetrpc.TxnExecutionStatusREVERTED:
+			r.State = TxFailed
+			r.Error = "transaction reverted"
+		default:
+			r.State = TxConfirmed
+			if awaitL1 {
+				r.State = TxAccepted
+			}
+			promConfirmationSeconds.WithLabelValues(txm.chainID, accountAddress.String()).Observe(time.Since(r.CreatedAt).Seconds())
+		}
+	})
+
+	rejected := finalityStatus == starknetrpc.TxnStatus_Rejected
+	if awaitL1 {
+		txm.accepted.add(&acceptedTx{accountAddress: accountAddress, hash: hash, ids: unconfirmedTx.IDs})
+	}
+	if rejected {
+		// we assume that all rejected transactions results in a unused rejected nonce, so
+		// resync. see the comment at resyncNonce for more details.
+		if resyncErr := txm.resyncNonce(ctx, client, accountAddress); resyncErr != nil {
+			txm.lggr.Errorw("resync failed for rejected tx", "error", resyncErr)
+		}
+	}
+
+	if rejected || executionStatus == starknetrpc.TxnExecutionStatusREVERTED {
+		txm.done.Add(1)
+		go txm.resolveRevertReason(ctx, client, accountAddress, unconfirmedTx.IDs, hash, rejected)
+	}
+	if !rejected {
+		// reverted txs are charged as well
+		txm.done.Add(1)
+		go txm.observeFee(ctx, client, accountAddress, unconfirmedTx.IDs, hash, !awaitL1)
+	}
+}
+
+// observeFee compares the actual fee of an accepted tx, taken from its receipt, with its estimated fee. If final is
+// set the fee is recorded in the fee ledger, otherwise it is recorded once the tx reaches L1, see recordFinalFee.
+func (txm *starktxm) observeFee(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, ids []string, hash string, final bool) {
+	defer txm.done.Done()
+
+	f, err := starknetutils.HexToFelt(hash)
 	if err != nil {
-		txm.lggr.Errorw("failed to load feeder client", "error", err)
+		txm.lggr.Errorw("invalid felt value", "hash", hash)
+		return
+	}
+	receipt, err := client.TransactionReceipt(ctx, f)
+	if err != nil {
+		txm.lggr.Warnw("failed to fetch receipt of accepted tx", "hash", hash, "error", err)
+		return
+	}
+	if fee, ok := receiptFeePayment(receipt); final && ok {
+		txm.recordFees(accountAddress, ids, hash, fee)
+	}
+
+	record, err := txm.storage.Get(ids[0])
+	if err != nil || record.EstimatedFee == nil {
+		// not known for txs restored from older records
+		return
+	}
+	actualFee, ok := receiptActualFee(receipt)
+	if !ok {
 		return
 	}
 
-	rejectedTx, err := feederClient.TransactionFailure(ctx, f)
+	paid, _ := new(big.Float).SetInt(actualFee).Float64()
+	estimated, _ := new(big.Float).SetInt(record.EstimatedFee).Float64()
+	accountAddressStr := accountAddress.String()
+	promFeePaid.WithLabelValues(txm.chainID, accountAddressStr).Add(paid)
+	promFeeEstimated.WithLabelValues(txm.chainID, accountAddressStr).Add(estimated)
+	if estimated > 0 {
+		promFeePaidRatio.WithLabelValues(txm.chainID, accountAddressStr).Observe(paid / estimated)
+	}
+}
+
+// resolveRevertReason fetches the reason of a failed tx and stores it on the records of the tx. The reason of a
+// reverted tx is taken from its receipt, the feeder is only queried for rejected txs (which have no receipt) or
+// if the RPC node does not report a reason.
+func (txm *starktxm) resolveRevertReason(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, ids []string, hash string, rejected bool) {
+	defer txm.done.Done()
+
+	f, err := starknetutils.HexToFelt(hash)
 	if err != nil {
-		txm.lggr.Errorw("failed to fetch reason for transaction failure", "hash", hash, "error", err)
+		txm.lggr.Errorw("invalid felt value", "hash", hash)
 		return
 	}
 
-	txm.lggr.Errorw("feeder rejected reason", "hash", hash, "errorMessage", rejectedTx.ErrorMessage)
+	var reason *RevertReason
+	if !rejected {
+		receipt, receiptErr := client.TransactionReceipt(ctx, f)
+		if receiptErr != nil {
+			txm.lggr.Warnw("failed to fetch receipt of reverted tx, falling back to feeder", "hash", hash, "error", receiptErr)
+		} else if revertReason, ok := receiptRevertReason(receipt); ok {
+			reason = DecodeRevertReason(revertReason, RevertSourceRPC)
+		}
+	}
+
+	if reason == nil {
+		feederClient, feederErr := txm.feederClient.Get()
+		if feederErr != nil {
+			txm.lggr.Errorw("failed to load feeder client", "error", feederErr)
+			return
+		}
+		failure, feederErr := feederClient.TransactionFailure(ctx, f)
+		if feederErr != nil {
+			txm.lggr.Errorw("failed to fetch reason for transaction failure", "hash", hash, "error", feederErr)
+			return
+		}
+		message := failure.ErrorMessage
+		if message == "" {
+			message = failure.Code
+		}
+		reason = DecodeRevertReason(message, RevertSourceFeeder)
+	}
+
+	status := "reverted"
+	if rejected {
+		status = "rejected"
+	}
+	txm.lggr.Errorw(fmt.Sprintf("transaction %s", status), "hash", hash, "ids", ids, "kind", reason.Kind, "messages", reason.Messages, "reason", reason.Reason, "source", reason.Source)
+	promTxRevertReasons.WithLabelValues(txm.chainID, accountAddress.String(), status, string(reason.Kind)).Inc()
+	txm.updateRecords(ids, func(r *TxRecord) {
+		r.RevertReason = reason
+		r.Error = fmt.Sprintf("transaction %s: %s", status, reason.Error())
+	})
 }
 
 func (txm *starktxm) resyncNonce(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt) error {
@@ -438,12 +999,12 @@ func (txm *starktxm) resyncNonce(ctx context.Context, client *starknet.Client, a
 	   next nonce value when pending transactions haven't yet been processed - resulting in more category 1
 	   invalid nonce broadcast errors.
 
-	   in order to recover from these cases, each time we do starknet_getNonce during estimation (see estimateFriFee),
+	   in order to recover from these cases, each time we do starknet_getNonce during estimation (see estimateFee),
 	   we compare it with our locally tracked nonce - if it is greater, than that means our locally tracked value is
 	   behind, and we fast forward. this ensures our locally tracked value will also eventually be correct.
 	*/
 
-	rpcNonce, err := client.AccountNonce(ctx, accountAddress)
+	rpcNonce, err := client.AccountNonceAt(ctx, accountAddress, starknetrpc.WithBlockTag(starknet.BlockTagPending))
 	if err != nil {
 		return fmt.Errorf("failed to check nonce during resync: %+w", err)
 	}
@@ -458,16 +1019,61 @@ func (txm *starktxm) resyncNonce(ctx context.Context, client *starknet.Client, a
 
 	staleTxs := txStore.SetNextNonce(rpcNonce)
 
-	txm.lggr.Infow("resynced nonce", "accountAddress", "accountAddress", "previousNonce", currentNonce, "updatedNonce", rpcNonce, "staleTxCount", len(staleTxs))
+	txm.lggr.Infow("resynced nonce", "accountAddress", accountAddress, "previousNonce", currentNonce, "updatedNonce", rpcNonce, "staleTxCount", len(staleTxs))
+	promNonceResyncs.WithLabelValues(txm.chainID, accountAddress.String()).Inc()
+	txm.dropStaleTxs(accountAddress, staleTxs)
 
 	return nil
 }
 
+// dropStaleTxs handles the txs that were removed from a TxStore by a nonce resync. Their nonce will not be
+// accepted anymore, so their calls count as a failed broadcast attempt and are queued again.
+func (txm *starktxm) dropStaleTxs(accountAddress *felt.Felt, staleTxs []*UnconfirmedTx) {
+	for _, stale := range staleTxs {
+		promStaleTxDrops.WithLabelValues(txm.chainID, accountAddress.String()).Add(float64(len(stale.IDs)))
+		// the txs are queued again from their records: a sponsored invoke was sent by accountAddress, the sponsor,
+		// with a single call that executes all of them
+		txs := make([]Tx, 0, len(stale.IDs))
+		for _, id := range stale.IDs {
+			record, err := txm.storage.Get(id)
+			if err != nil {
+				txm.lggr.Errorw("failed to get record of stale tx", "id", id, "hash", stale.Hash, "error", err)
+				continue
+			}
+			txs = append(txs, txFromRecord(record))
+		}
+		txm.retryOrDeadLetter(txs, stale.Hash, "dropped after nonce resync")
+	}
+}
+
+// abandonExpired moves a pending tx that has outlived its TxTTL to the dead letters. Its nonce may never be used,
+// so the nonce is resynced with the node afterwards.
+func (txm *starktxm) abandonExpired(ctx context.Context, client *starknet.Client, accountAddress *felt.Felt, unconfirmedTx *UnconfirmedTx) bool {
+	record, err := txm.storage.Get(unconfirmedTx.IDs[0])
+	if err != nil || !txm.expired(record.CreatedAt) {
+		return false
+	}
+	if err = txm.accountStore.GetTxStore(accountAddress).Abandon(unconfirmedTx.Nonce); err != nil {
+		txm.lggr.Errorw("failed to abandon tx in TxStore", "hash", unconfirmedTx.Hash, "accountAddress", accountAddress, "error", err)
+		return false
+	}
+	txm.deadLetter(accountAddress, unconfirmedTx.IDs, deadLetterExpired, fmt.Sprintf("expired while unconfirmed, last attempt %s", unconfirmedTx.Hash))
+	if err = txm.resyncNonce(ctx, client, accountAddress); err != nil {
+		txm.lggr.Errorw("resync failed for expired tx", "error", err)
+	}
+	return true
+}
+
+// Close stops accepting txs and gives the broadcast workers up to DrainTimeout to broadcast the queued txs before
+// stopping. Txs that are still queued afterwards are restored on the next start if TxStoragePath is set.
 func (txm *starktxm) Close() error {
 	return txm.starter.StopOnce("Txm", func() error {
+		txm.draining.Store(true)
+		txm.drain()
 		close(txm.stop)
 		txm.done.Wait()
-		return nil
+		txm.reportUndrained()
+		return errors.Join(txm.ledger.Close(), txm.storage.Close())
 	})
 }
 
@@ -479,28 +1085,92 @@ func (txm *starktxm) Ready() error {
 	return txm.starter.Ready()
 }
 
+// HealthReport reports an ErrInsufficientBalance per account that is paused by the balance guard.
 func (txm *starktxm) HealthReport() map[string]error {
-	return map[string]error{txm.Name(): txm.Healthy()}
+	report := map[string]error{txm.Name(): txm.Healthy()}
+	for _, p := range txm.balances.list() {
+		report[fmt.Sprintf("%s.Account.%s", txm.Name(), p.accountAddress)] = fmt.Errorf("paused since %s: %w", p.since.Format(time.RFC3339), p.err())
+	}
+	return report
 }
 
-func (txm *starktxm) Enqueue(ctx context.Context, accountAddress, publicKey *felt.Felt, tx starknetrpc.FunctionCall) error {
+func (txm *starktxm) Enqueue(ctx context.Context, accountAddress, publicKey *felt.Felt, tx starknetrpc.FunctionCall, txID string, opts TxOpts) (string, error) {
+	if txm.draining.Load() {
+		return "", ErrShuttingDown
+	}
+
 	// validate key exists for sender
 	// use the embedded Loopp Keystore to do this; the spec and design
 	// encourage passing nil data to the loop.Keystore.Sign as way to test
 	// existence of a key
 	if _, err := txm.ks.Loopp().Sign(ctx, publicKey.String(), nil); err != nil {
-		return fmt.Errorf("enqueue: failed to sign: %+w", err)
+		return "", fmt.Errorf("enqueue: failed to sign: %+w", err)
 	}
 
-	select {
-	case txm.queue <- Tx{publicKey: publicKey, accountAddress: accountAddress, call: tx}: // TODO fix naming here
-	default:
-		return fmt.Errorf("failed to enqueue transaction: %+v", tx)
+	if txID == "" {
+		txID = uuid.NewString()
+	} else {
+		txm.enqueueLock.Lock()
+		defer txm.enqueueLock.Unlock()
+		if _, err := txm.storage.Get(txID); err == nil {
+			txm.lggr.Debugw("tx already enqueued", "id", txID)
+			return txID, nil
+		} else if !errors.Is(err, ErrTxNotFound) {
+			return "", fmt.Errorf("enqueue: failed to look up tx: %+w", err)
+		}
+	}
+
+	if opts.Caller == "" {
+		opts.Caller = tx.ContractAddress.String()
+	}
+	now := time.Now()
+	record := TxRecord{
+		ID:             txID,
+		AccountAddress: accountAddress,
+		PublicKey:      publicKey,
+		Call:           tx,
+		Priority:       opts.Priority,
+		Caller:         opts.Caller,
+		State:          TxEnqueued,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	// persist before queueing so that the broadcast loop never sees a tx without a record
+	if err := txm.storage.Save(record); err != nil {
+		return "", fmt.Errorf("enqueue: failed to persist tx: %+w", err)
+	}
+
+	if err := txm.push(txFromRecord(record)); err != nil {
+		if deleteErr := txm.storage.Delete(record.ID); deleteErr != nil {
+			txm.lggr.Errorw("failed to delete tx record", "id", record.ID, "error", deleteErr)
+		}
+		return "", fmt.Errorf("failed to enqueue transaction: %+w", err)
 	}
 
+	return record.ID, nil
+}
+
+// push queues tx and moves the queued tx that it replaced to the dead letters, see ReplaceSelectors.
+func (txm *starktxm) push(tx Tx) error {
+	replaced, err := txm.queues.push(tx)
+	if err != nil {
+		return err
+	}
+	for _, r := range replaced {
+		reason := fmt.Sprintf("replaced by %s", tx.id)
+		if r.id == tx.id {
+			reason = "replaced by a newer tx"
+		}
+		txm.deadLetter(r.accountAddress, []string{r.id}, deadLetterReplaced, reason)
+	}
+	txm.observeQueueDepth(tx.accountAddress)
 	return nil
 }
 
+func (txm *starktxm) observeQueueDepth(accountAddress *felt.Felt) {
+	promQueueDepth.WithLabelValues(txm.chainID, accountAddress.String()).Set(float64(txm.queues.lenOf(accountAddress)))
+}
+
 func (txm *starktxm) InflightCount() (queue int, unconfirmed int) {
-	return len(txm.queue), txm.accountStore.GetTotalInflightCount()
+	return txm.queues.len(), txm.accountStore.GetTotalInflightCount()
 }
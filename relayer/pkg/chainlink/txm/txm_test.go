@@ -74,11 +74,16 @@ func TestIntegration_Txm(t *testing.T) {
 	}
 
 	// mock config to prevent import cycle
-	cfg := mocks.NewConfig(t)
-	cfg.On("TxTimeout").Return(20 * time.Second)
-	cfg.On("ConfirmationPoll").Return(1 * time.Second)
+	cfg := NewTestConfig(t, func(cfg *mocks.Config) {
+		cfg.On("TxTimeout").Return(20 * time.Second)
+		cfg.On("ConfirmationPoll").Return(1 * time.Second)
+		cfg.On("BroadcastWorkers").Return(uint32(4))
+		cfg.On("BroadcastRetryBackoff").Return(time.Second).Maybe()
+		cfg.On("BalancePollInterval").Return(time.Duration(0))
+		cfg.On("DrainTimeout").Return(10 * time.Second)
+	})
 
-	txm, err := New(lggr, ksAdapter.Loopp(), cfg, getClient, getFeederClient)
+	txm, err := New(lggr, "SN_SEPOLIA", ksAdapter.Loopp(), cfg, getClient, getFeederClient)
 	require.NoError(t, err)
 
 	// ready fail if start not called
@@ -102,10 +107,11 @@ func TestIntegration_Txm(t *testing.T) {
 		selector := starknetutils.GetSelectorFromNameFelt("totalSupply")
 
 		for i := 0; i < n; i++ {
-			require.NoError(t, txm.Enqueue(ctx, accountAddress, publicKey, starknetrpc.FunctionCall{
+			_, err := txm.Enqueue(ctx, accountAddress, publicKey, starknetrpc.FunctionCall{
 				ContractAddress:    contractAddress, // send to ETH token contract
 				EntryPointSelector: selector,
-			}))
+			}, "", TxOpts{})
+			require.NoError(t, err)
 		}
 	}
 	var empty bool
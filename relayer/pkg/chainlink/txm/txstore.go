@@ -2,19 +2,35 @@ package txm
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/NethermindEth/juno/core/felt"
 	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
 	"golang.org/x/exp/maps"
 )
 
+// UnconfirmedTx is a broadcast invoke, which may be a multicall of several queued calls.
 type UnconfirmedTx struct {
-	Hash      string
-	PublicKey *felt.Felt
-	Nonce     *felt.Felt
-	Call      starknetrpc.FunctionCall
+	// IDs of the queued txs included in the invoke, in call order
+	IDs []string
+	// Hash of the most recent attempt
+	Hash string
+	// Attempts holds the hashes of every broadcast of this tx, oldest first
+	Attempts    []string
+	BroadcastAt time.Time
+	PublicKey   *felt.Felt
+	Nonce       *felt.Felt
+	// Calls of the invoke, which do not match IDs one to one: a sponsored invoke has a single call that executes all
+	// txs, see TxRecord for the calls of the txs
+	Calls []starknetrpc.FunctionCall
+}
+
+// HasAttempt returns true if hash belongs to any broadcast attempt of the tx
+func (tx *UnconfirmedTx) HasAttempt(hash string) bool {
+	return slices.Contains(tx.Attempts, hash)
 }
 
 // TxStore tracks broadcast & unconfirmed txs per account address per chain id
@@ -62,7 +78,7 @@ func (s *TxStore) GetNextNonce() *felt.Felt {
 	return new(felt.Felt).Set(s.nextNonce)
 }
 
-func (s *TxStore) AddUnconfirmed(nonce *felt.Felt, hash string, call starknetrpc.FunctionCall, publicKey *felt.Felt) error {
+func (s *TxStore) AddUnconfirmed(ids []string, nonce *felt.Felt, hash string, calls []starknetrpc.FunctionCall, publicKey *felt.Felt) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -79,16 +95,42 @@ func (s *TxStore) AddUnconfirmed(nonce *felt.Felt, hash string, call starknetrpc
 	}
 
 	s.unconfirmedNonces[nonceStr] = &UnconfirmedTx{
-		Nonce:     new(felt.Felt).Set(nonce),
-		PublicKey: new(felt.Felt).Set(publicKey),
-		Hash:      hash,
-		Call:      call,
+		IDs:         ids,
+		Nonce:       new(felt.Felt).Set(nonce),
+		PublicKey:   new(felt.Felt).Set(publicKey),
+		Hash:        hash,
+		Attempts:    []string{hash},
+		BroadcastAt: time.Now(),
+		Calls:       calls,
 	}
 
 	s.nextNonce = new(felt.Felt).Add(s.nextNonce, new(felt.Felt).SetUint64(1))
 	return nil
 }
 
+// AddAttempt records a rebroadcast of the unconfirmed tx at nonce, which replaces the previous attempt.
+func (s *TxStore) AddAttempt(nonce *felt.Felt, hash string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	nonceStr := nonce.String()
+	unconfirmed, exists := s.unconfirmedNonces[nonceStr]
+	if !exists {
+		return fmt.Errorf("no such unconfirmed nonce: %s", nonce)
+	}
+	if unconfirmed.HasAttempt(hash) {
+		return fmt.Errorf("attempt already exists: nonce (%s), tx (%s)", nonce, hash)
+	}
+
+	// copy so that callers holding the previous value are unaffected
+	updated := *unconfirmed
+	updated.Hash = hash
+	updated.Attempts = append(slices.Clip(unconfirmed.Attempts), hash)
+	updated.BroadcastAt = time.Now()
+	s.unconfirmedNonces[nonceStr] = &updated
+	return nil
+}
+
 func (s *TxStore) Confirm(nonce *felt.Felt, hash string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -98,9 +140,22 @@ func (s *TxStore) Confirm(nonce *felt.Felt, hash string) error {
 	if !exists {
 		return fmt.Errorf("no such unconfirmed nonce: %s", nonce)
 	}
-	// sanity check that the hash matches
-	if unconfirmed.Hash != hash {
-		return fmt.Errorf("unexpected tx hash: expected %s, got %s", unconfirmed.Hash, hash)
+	// sanity check that the hash matches one of the attempts
+	if !unconfirmed.HasAttempt(hash) {
+		return fmt.Errorf("unexpected tx hash: expected one of %v, got %s", unconfirmed.Attempts, hash)
+	}
+	delete(s.unconfirmedNonces, nonceStr)
+	return nil
+}
+
+// Abandon stops tracking the unconfirmed tx at nonce without confirming it.
+func (s *TxStore) Abandon(nonce *felt.Felt) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	nonceStr := nonce.String()
+	if _, exists := s.unconfirmedNonces[nonceStr]; !exists {
+		return fmt.Errorf("no such unconfirmed nonce: %s", nonce)
 	}
 	delete(s.unconfirmedNonces, nonceStr)
 	return nil
@@ -150,6 +205,34 @@ func (c *AccountStore) CreateTxStore(accountAddress *felt.Felt, initialNonce *fe
 	return store, nil
 }
 
+// RestoreTxStore recreates the TxStore for the provided account from previously persisted unconfirmed txs.
+// The next nonce is set to follow the highest restored nonce, or to nextNonce if there is nothing to restore.
+func (c *AccountStore) RestoreTxStore(accountAddress *felt.Felt, nextNonce *felt.Felt, unconfirmed []*UnconfirmedTx) (*TxStore, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	addressStr := accountAddress.String()
+	if _, ok := c.store[addressStr]; ok {
+		return nil, fmt.Errorf("TxStore already exists: %s", accountAddress)
+	}
+
+	store := NewTxStore(nextNonce)
+	for _, tx := range unconfirmed {
+		nonceStr := tx.Nonce.String()
+		if _, exists := store.unconfirmedNonces[nonceStr]; exists {
+			return nil, fmt.Errorf("nonce used: tried to restore nonce (%s) for tx (%s) twice", tx.Nonce, tx.Hash)
+		}
+		if len(tx.Attempts) == 0 {
+			tx.Attempts = []string{tx.Hash}
+		}
+		store.unconfirmedNonces[nonceStr] = tx
+		if next := new(felt.Felt).Add(tx.Nonce, new(felt.Felt).SetUint64(1)); next.Cmp(store.nextNonce) > 0 {
+			store.nextNonce = next
+		}
+	}
+	c.store[addressStr] = store
+	return store, nil
+}
+
 // GetTxStore returns the TxStore for the provided account.
 func (c *AccountStore) GetTxStore(accountAddress *felt.Felt) *TxStore {
 	c.lock.Lock()
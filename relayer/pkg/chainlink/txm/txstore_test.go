@@ -29,7 +29,7 @@ func TestTxStore(t *testing.T) {
 		s := NewTxStore(nonce)
 		assert.True(t, s.GetNextNonce().Cmp(nonce) == 0)
 		assert.Equal(t, 0, s.InflightCount())
-		require.NoError(t, s.AddUnconfirmed(nonce, "0x42", call, publicKey))
+		require.NoError(t, s.AddUnconfirmed(nil, nonce, "0x42", []starknetrpc.FunctionCall{call}, publicKey))
 		assert.Equal(t, 1, s.InflightCount())
 		assert.Equal(t, 1, len(s.GetUnconfirmed()))
 		assert.Equal(t, "0x42", s.GetUnconfirmed()[0].Hash)
@@ -53,19 +53,19 @@ func TestTxStore(t *testing.T) {
 		publicKey := new(felt.Felt).SetUint64(7)
 
 		// accepts tx in order
-		require.NoError(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(0), "0x0", call, publicKey))
+		require.NoError(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(0), "0x0", []starknetrpc.FunctionCall{call}, publicKey))
 		assert.Equal(t, 1, s.InflightCount())
 
 		// reject tx that skips a nonce
-		require.ErrorContains(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(2), "0x2", call, publicKey), "tried to add an unconfirmed tx at a future nonce")
+		require.ErrorContains(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(2), "0x2", []starknetrpc.FunctionCall{call}, publicKey), "tried to add an unconfirmed tx at a future nonce")
 		assert.Equal(t, 1, s.InflightCount())
 
 		// accepts a subsequent tx
-		require.NoError(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(1), "0x1", call, publicKey))
+		require.NoError(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(1), "0x1", []starknetrpc.FunctionCall{call}, publicKey))
 		assert.Equal(t, 2, s.InflightCount())
 
 		// reject already in use nonce
-		require.ErrorContains(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(1), "0xskip", call, publicKey), "tried to add an unconfirmed tx at an old nonce")
+		require.ErrorContains(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(1), "0xskip", []starknetrpc.FunctionCall{call}, publicKey), "tried to add an unconfirmed tx at an old nonce")
 		assert.Equal(t, 2, s.InflightCount())
 
 		// race save
@@ -74,11 +74,11 @@ func TestTxStore(t *testing.T) {
 		var wg sync.WaitGroup
 		wg.Add(2)
 		go func() {
-			err0 = s.AddUnconfirmed(new(felt.Felt).SetUint64(2), "0x10", call, publicKey)
+			err0 = s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(2), "0x10", []starknetrpc.FunctionCall{call}, publicKey)
 			wg.Done()
 		}()
 		go func() {
-			err1 = s.AddUnconfirmed(new(felt.Felt).SetUint64(2), "0x10", call, publicKey)
+			err1 = s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(2), "0x10", []starknetrpc.FunctionCall{call}, publicKey)
 			wg.Done()
 		}()
 		wg.Wait()
@@ -106,7 +106,7 @@ func TestTxStore(t *testing.T) {
 		// init store
 		s := NewTxStore(new(felt.Felt).SetUint64(0))
 		for i := uint64(0); i < 6; i++ {
-			require.NoError(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(i), "0x"+fmt.Sprintf("%d", i), call, publicKey))
+			require.NoError(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(i), "0x"+fmt.Sprintf("%d", i), []starknetrpc.FunctionCall{call}, publicKey))
 		}
 
 		// confirm in order
@@ -143,6 +143,61 @@ func TestTxStore(t *testing.T) {
 		assert.Equal(t, 0, s.InflightCount())
 	})
 
+	t.Run("attempts", func(t *testing.T) {
+		t.Parallel()
+
+		call := starknetrpc.FunctionCall{
+			ContractAddress:    new(felt.Felt).SetUint64(0),
+			EntryPointSelector: new(felt.Felt).SetUint64(0),
+		}
+
+		publicKey := new(felt.Felt).SetUint64(7)
+		nonce := new(felt.Felt).SetUint64(0)
+
+		s := NewTxStore(nonce)
+		require.NoError(t, s.AddUnconfirmed(nil, nonce, "0x0", []starknetrpc.FunctionCall{call}, publicKey))
+		original := s.GetUnconfirmed()[0]
+		assert.Equal(t, []string{"0x0"}, original.Attempts)
+
+		// rebroadcast at the same nonce
+		require.NoError(t, s.AddAttempt(nonce, "0x1"))
+		require.NoError(t, s.AddAttempt(nonce, "0x2"))
+		require.ErrorContains(t, s.AddAttempt(nonce, "0x1"), "attempt already exists")
+		require.ErrorContains(t, s.AddAttempt(new(felt.Felt).SetUint64(1), "0x3"), "no such unconfirmed nonce")
+		assert.Equal(t, 1, s.InflightCount())
+		assert.True(t, s.GetNextNonce().Cmp(new(felt.Felt).SetUint64(1)) == 0)
+
+		unconfirmed := s.GetUnconfirmed()[0]
+		assert.Equal(t, "0x2", unconfirmed.Hash)
+		assert.Equal(t, []string{"0x0", "0x1", "0x2"}, unconfirmed.Attempts)
+		// previously returned values are not modified
+		assert.Equal(t, []string{"0x0"}, original.Attempts)
+
+		// any attempt can confirm the tx
+		require.ErrorContains(t, s.Confirm(nonce, "0x9"), "unexpected tx hash")
+		require.NoError(t, s.Confirm(nonce, "0x1"))
+		assert.Equal(t, 0, s.InflightCount())
+	})
+
+	t.Run("abandon", func(t *testing.T) {
+		t.Parallel()
+
+		call := starknetrpc.FunctionCall{
+			ContractAddress:    new(felt.Felt).SetUint64(0),
+			EntryPointSelector: new(felt.Felt).SetUint64(0),
+		}
+		publicKey := new(felt.Felt).SetUint64(7)
+		nonce := new(felt.Felt).SetUint64(0)
+
+		s := NewTxStore(nonce)
+		require.NoError(t, s.AddUnconfirmed(nil, nonce, "0x0", []starknetrpc.FunctionCall{call}, publicKey))
+		require.NoError(t, s.Abandon(nonce))
+		require.ErrorContains(t, s.Abandon(nonce), "no such unconfirmed nonce")
+		assert.Equal(t, 0, s.InflightCount())
+		// the nonce is not released, see resyncNonce
+		assert.True(t, s.GetNextNonce().Cmp(new(felt.Felt).SetUint64(1)) == 0)
+	})
+
 	t.Run("resync", func(t *testing.T) {
 		t.Parallel()
 
@@ -157,7 +212,7 @@ func TestTxStore(t *testing.T) {
 		// init store
 		s := NewTxStore(new(felt.Felt).SetUint64(0))
 		for i := uint64(0); i < txCount; i++ {
-			require.NoError(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(i), "0x"+fmt.Sprintf("%d", i), call, publicKey))
+			require.NoError(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(i), "0x"+fmt.Sprintf("%d", i), []starknetrpc.FunctionCall{call}, publicKey))
 		}
 		assert.Equal(t, s.InflightCount(), 6)
 
@@ -167,14 +222,14 @@ func TestTxStore(t *testing.T) {
 		for i := uint64(0); i < txCount; i++ {
 			staleTx := staleTxs[i]
 			assert.Equal(t, staleTx.Nonce.Cmp(new(felt.Felt).SetUint64(i)), 0)
-			assert.Equal(t, staleTx.Call, call)
+			assert.Equal(t, staleTx.Calls, []starknetrpc.FunctionCall{call})
 			assert.Equal(t, staleTx.PublicKey.Cmp(publicKey), 0)
 			assert.Equal(t, staleTx.Hash, "0x"+fmt.Sprintf("%d", i))
 		}
 		assert.Equal(t, s.InflightCount(), 0)
 
 		for i := uint64(0); i < txCount; i++ {
-			require.NoError(t, s.AddUnconfirmed(new(felt.Felt).SetUint64(i), "0x"+fmt.Sprintf("%d", i), call, publicKey))
+			require.NoError(t, s.AddUnconfirmed(nil, new(felt.Felt).SetUint64(i), "0x"+fmt.Sprintf("%d", i), []starknetrpc.FunctionCall{call}, publicKey))
 		}
 
 		newNextNonce := txCount - 1
@@ -214,8 +269,8 @@ func TestAccountStore(t *testing.T) {
 	}
 
 	// inflight count
-	require.NoError(t, store0.AddUnconfirmed(felt0, "0x0", call, publicKey))
-	require.NoError(t, store1.AddUnconfirmed(felt1, "0x1", call, publicKey))
+	require.NoError(t, store0.AddUnconfirmed(nil, felt0, "0x0", []starknetrpc.FunctionCall{call}, publicKey))
+	require.NoError(t, store1.AddUnconfirmed(nil, felt1, "0x1", []starknetrpc.FunctionCall{call}, publicKey))
 	assert.Equal(t, c.GetTotalInflightCount(), 2)
 
 	// get unconfirmed
@@ -230,3 +285,36 @@ func TestAccountStore(t *testing.T) {
 	assert.Equal(t, len(hashes1), 1)
 	assert.Equal(t, hashes1[0].Hash, "0x1")
 }
+
+func TestAccountStore_RestoreTxStore(t *testing.T) {
+	t.Parallel()
+
+	c := NewAccountStore()
+	account := new(felt.Felt).SetUint64(1)
+	publicKey := new(felt.Felt).SetUint64(2)
+	call := starknetrpc.FunctionCall{
+		ContractAddress:    new(felt.Felt).SetUint64(0),
+		EntryPointSelector: new(felt.Felt).SetUint64(0),
+	}
+
+	// nonce 4 was confirmed before the restart, leaving a gap
+	restored := []*UnconfirmedTx{
+		{IDs: []string{"a"}, Hash: "0x3", Nonce: new(felt.Felt).SetUint64(3), PublicKey: publicKey, Calls: []starknetrpc.FunctionCall{call}},
+		{IDs: []string{"b"}, Hash: "0x5", Nonce: new(felt.Felt).SetUint64(5), PublicKey: publicKey, Calls: []starknetrpc.FunctionCall{call}},
+	}
+	s, err := c.RestoreTxStore(account, new(felt.Felt).SetUint64(0), restored)
+	require.NoError(t, err)
+	assert.Equal(t, s, c.GetTxStore(account))
+	assert.Equal(t, 2, s.InflightCount())
+	assert.Equal(t, 0, s.GetNextNonce().Cmp(new(felt.Felt).SetUint64(6)))
+
+	require.NoError(t, s.Confirm(new(felt.Felt).SetUint64(3), "0x3"))
+	require.NoError(t, s.AddUnconfirmed([]string{"c"}, new(felt.Felt).SetUint64(6), "0x6", []starknetrpc.FunctionCall{call}, publicKey))
+	unconfirmed := s.GetUnconfirmed()
+	require.Equal(t, 2, len(unconfirmed))
+	assert.Equal(t, "b", unconfirmed[0].IDs[0])
+	assert.Equal(t, "c", unconfirmed[1].IDs[0])
+
+	_, err = c.RestoreTxStore(account, new(felt.Felt).SetUint64(0), nil)
+	require.ErrorContains(t, err, "TxStore already exists")
+}
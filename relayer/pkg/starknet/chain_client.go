@@ -20,7 +20,8 @@ type BatchBuilder interface {
 	// RequestLatestBlockHashAndNumber() (BatchBuilder)
 	RequestLatestBlockHashAndNumber() BatchBuilder
 	RequestEventsByFilter(f starknetrpc.EventsInput) BatchBuilder
-	// RequestTxReceiptByHash(h *felt.Felt) (BatchBuilder)
+	RequestTxStatusByHash(h *felt.Felt) BatchBuilder
+	RequestTxReceiptByHash(h *felt.Felt) BatchBuilder
 	Build() []gethrpc.BatchElem
 }
 
@@ -85,6 +86,24 @@ func (b *batchBuilder) RequestLatestBlockHashAndNumber() BatchBuilder {
 	return b
 }
 
+func (b *batchBuilder) RequestTxStatusByHash(h *felt.Felt) BatchBuilder {
+	b.args = append(b.args, gethrpc.BatchElem{
+		Method: "starknet_getTransactionStatus",
+		Args:   []interface{}{h},
+		Result: &starknetrpc.TxnStatusResp{},
+	})
+	return b
+}
+
+func (b *batchBuilder) RequestTxReceiptByHash(h *felt.Felt) BatchBuilder {
+	b.args = append(b.args, gethrpc.BatchElem{
+		Method: "starknet_getTransactionReceipt",
+		Args:   []interface{}{h},
+		Result: &ReceiptResult{},
+	})
+	return b
+}
+
 func (b *batchBuilder) Build() []gethrpc.BatchElem {
 	return b.args
 }
@@ -134,6 +153,15 @@ func (c *Client) ChainID(ctx context.Context) (string, error) {
 	return *chainID, nil
 }
 
+// VerifyChainID checks that the node serves the chain ID the client was created with, see MatchChainID.
+func (c *Client) VerifyChainID(ctx context.Context) error {
+	chainID, err := c.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	return MatchChainID(c.chainID, chainID)
+}
+
 func (c *Client) BlockByHash(ctx context.Context, h *felt.Felt) (FinalizedBlock, error) {
 	if c.defaultTimeout != 0 {
 		var cancel context.CancelFunc
@@ -178,6 +178,23 @@ func TestChainClient(t *testing.T) {
 					}
 				]`, batchCall[0].ID, chainIDHex)
 				out = []byte(response)
+			} else if batchCall[0].Method == "starknet_getTransactionStatus" {
+				response := fmt.Sprintf(`
+				[
+					{ "jsonrpc": "2.0",
+						"id": %d,
+						"result": {"finality_status": "ACCEPTED_ON_L2", "execution_status": "SUCCEEDED"}
+					},
+					{ "jsonrpc": "2.0",
+						"id": %d,
+						"result": {"type": "INVOKE", "transaction_hash": "0x1", "actual_fee": {"amount": "0x2a", "unit": "FRI"}, "execution_status": "SUCCEEDED", "finality_status": "ACCEPTED_ON_L2", "block_hash": "%s", "block_number": %d, "messages_sent": [], "events": [], "execution_resources": {"steps": 1}}
+					},
+					{ "jsonrpc": "2.0",
+						"id": %d,
+						"error": {"code": 29, "message": "Transaction hash not found"}
+					}
+				]`, batchCall[0].ID, batchCall[1].ID, blockHash, blockNumber, batchCall[2].ID)
+				out = []byte(response)
 			} else {
 				response := fmt.Sprintf(`
 			[
@@ -265,6 +282,37 @@ func TestChainClient(t *testing.T) {
 		}
 	})
 
+	t.Run("get tx statuses and receipts in Batch", func(t *testing.T) {
+		hash := new(felt.Felt).SetUint64(1)
+		results, err := client.Batch(context.TODO(), NewBatchBuilder().
+			RequestTxStatusByHash(hash).
+			RequestTxReceiptByHash(hash).
+			RequestTxStatusByHash(new(felt.Felt).SetUint64(2)))
+		require.NoError(t, err)
+		require.Equal(t, 3, len(results))
+
+		assert.Equal(t, "starknet_getTransactionStatus", results[0].Method)
+		require.Nil(t, results[0].Error)
+		status, ok := results[0].Result.(*starknetrpc.TxnStatusResp)
+		require.True(t, ok)
+		assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L2, status.FinalityStatus)
+		assert.Equal(t, starknetrpc.TxnExecutionStatusSUCCEEDED, status.ExecutionStatus)
+
+		assert.Equal(t, "starknet_getTransactionReceipt", results[1].Method)
+		require.Nil(t, results[1].Error)
+		receipt, ok := results[1].Result.(*ReceiptResult)
+		require.True(t, ok)
+		require.NotNil(t, receipt.Receipt)
+		assert.Equal(t, blockHash, receipt.Receipt.BlockHash)
+		assert.Equal(t, uint(blockNumber), receipt.Receipt.BlockNumber)
+		invoke, ok := receipt.Receipt.TransactionReceipt.(starknetrpc.InvokeTransactionReceipt)
+		require.True(t, ok)
+		assert.Equal(t, "0x2a", invoke.ActualFee.Amount.String())
+
+		// errors are reported per request
+		assert.ErrorContains(t, results[2].Error, "Transaction hash not found")
+	})
+
 	t.Run("get Batch", func(t *testing.T) {
 		builder := NewBatchBuilder()
 		builder.
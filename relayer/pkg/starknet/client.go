@@ -2,12 +2,14 @@ package starknet
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/NethermindEth/juno/core/felt"
 	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/logger"
@@ -16,9 +18,17 @@ import (
 //go:generate mockery --name Reader --output ./mocks/
 
 type Reader interface {
+	// CallContract and AccountNonce read at the default block of the client, see SetDefaultBlock
 	CallContract(context.Context, CallOps) ([]*felt.Felt, error)
 	LatestBlockHeight(context.Context) (uint64, error)
 
+	// block-aware reads at a block tag, number or hash
+	CallContractAt(context.Context, CallOps, starknetrpc.BlockID) ([]*felt.Felt, error)
+	AccountNonceAt(context.Context, *felt.Felt, starknetrpc.BlockID) (*felt.Felt, error)
+	StorageAt(ctx context.Context, contractAddress, key *felt.Felt, blockID starknetrpc.BlockID) (*felt.Felt, error)
+	ClassHashAt(ctx context.Context, contractAddress *felt.Felt, blockID starknetrpc.BlockID) (*felt.Felt, error)
+	ClassAt(ctx context.Context, contractAddress *felt.Felt, blockID starknetrpc.BlockID) (starknetrpc.ClassOutput, error)
+
 	// provider interface
 	BlockWithTxHashes(ctx context.Context, blockID starknetrpc.BlockID) (*starknetrpc.Block, error)
 	Call(context.Context, starknetrpc.FunctionCall, starknetrpc.BlockID) ([]*felt.Felt, error)
@@ -43,15 +53,16 @@ var _ ReaderWriter = (*Client)(nil)
 type Client struct {
 	Provider       starknetrpc.RpcProvider
 	EthClient      *ethrpc.Client
+	chainID        string
+	defaultBlock   starknetrpc.BlockID
 	lggr           logger.Logger
 	defaultTimeout time.Duration
 }
 
 // pass nil or 0 to timeout to not use built in default timeout
-func NewClient(chainID string, baseURL string, apiKey string, lggr logger.Logger, timeout *time.Duration) (*Client, error) {
-	// TODO: chainID now unused
-
-	options := []ethrpc.ClientOption{}
+// opts are applied to both the Provider and the EthClient, e.g. a custom HTTP client
+func NewClient(chainID string, baseURL string, apiKey string, lggr logger.Logger, timeout *time.Duration, opts ...ethrpc.ClientOption) (*Client, error) {
+	options := append([]ethrpc.ClientOption{}, opts...)
 	if strings.TrimSpace(apiKey) != "" {
 		options = append(options, ethrpc.WithHeader("x-apikey", apiKey))
 	}
@@ -61,7 +72,7 @@ func NewClient(chainID string, baseURL string, apiKey string, lggr logger.Logger
 		return nil, err
 	}
 
-	c, err := ethrpc.DialContext(context.Background(), baseURL)
+	c, err := ethrpc.DialOptions(context.Background(), baseURL, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +80,10 @@ func NewClient(chainID string, baseURL string, apiKey string, lggr logger.Logger
 	client := &Client{
 		Provider:  provider,
 		EthClient: c,
+		chainID:   chainID,
 		lggr:      lggr,
+		// pending state includes the txs that are not in a block yet
+		defaultBlock: starknetrpc.WithBlockTag(BlockTagPending),
 	}
 
 	// make copy to preserve value
@@ -83,16 +97,26 @@ func NewClient(chainID string, baseURL string, apiKey string, lggr logger.Logger
 	return client, nil
 }
 
+// SetDefaultBlock sets the block read by CallContract and AccountNonce, the pending block by default. It must be
+// called before the client is used.
+func (c *Client) SetDefaultBlock(blockID starknetrpc.BlockID) {
+	c.defaultBlock = blockID
+}
+
 // -- Custom Wrapped Func --
 
 func (c *Client) CallContract(ctx context.Context, ops CallOps) (data []*felt.Felt, err error) {
+	return c.CallContractAt(ctx, ops, c.defaultBlock)
+}
+
+func (c *Client) CallContractAt(ctx context.Context, ops CallOps, blockID starknetrpc.BlockID) (data []*felt.Felt, err error) {
 	tx := starknetrpc.FunctionCall{
 		ContractAddress:    ops.ContractAddress,
 		EntryPointSelector: ops.Selector,
 		Calldata:           ops.Calldata,
 	}
 
-	res, err := c.Call(ctx, tx, starknetrpc.WithBlockTag("pending"))
+	res, err := c.Call(ctx, tx, blockID)
 	if err != nil {
 		return nil, fmt.Errorf("error in client.CallContract: %w", err)
 	}
@@ -172,14 +196,45 @@ func (c *Client) TransactionReceipt(ctx context.Context, hash *felt.Felt) (stark
 		defer cancel()
 	}
 
-	out, err := c.Provider.TransactionReceipt(ctx, hash)
-	if err != nil {
-		return out, fmt.Errorf("error in client.TransactionReceipt: %w", err)
+	var result ReceiptResult
+	if err := c.EthClient.CallContext(ctx, &result, "starknet_getTransactionReceipt", hash); err != nil {
+		return nil, fmt.Errorf("error in client.TransactionReceipt: %w", err)
 	}
-	if out == nil {
-		return out, NilResultError("client.TransactionReceipt")
+	if result.Receipt == nil {
+		return nil, NilResultError("client.TransactionReceipt")
 	}
-	return out, nil
+	return result.Receipt, nil
+}
+
+// ReceiptResult decodes the result of starknet_getTransactionReceipt. Provider.TransactionReceipt drops the receipt
+// itself: TransactionReceiptWithBlockInfo embeds the receipt interface without decoding it, so the receipt is
+// decoded through UnknownTransactionReceipt instead. Receipt is nil for a null result.
+type ReceiptResult struct {
+	Receipt *starknetrpc.TransactionReceiptWithBlockInfo
+}
+
+func (r *ReceiptResult) UnmarshalJSON(raw []byte) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		r.Receipt = nil
+		return nil
+	}
+	var receipt starknetrpc.UnknownTransactionReceipt
+	if err := json.Unmarshal(raw, &receipt); err != nil {
+		return fmt.Errorf("error decoding receipt: %w", err)
+	}
+	var blockInfo struct {
+		BlockHash   *felt.Felt `json:"block_hash,omitempty"`
+		BlockNumber uint       `json:"block_number,omitempty"`
+	}
+	if err := json.Unmarshal(raw, &blockInfo); err != nil {
+		return fmt.Errorf("error decoding receipt block: %w", err)
+	}
+	r.Receipt = &starknetrpc.TransactionReceiptWithBlockInfo{
+		TransactionReceipt: receipt.TransactionReceipt,
+		BlockHash:          blockInfo.BlockHash,
+		BlockNumber:        blockInfo.BlockNumber,
+	}
+	return nil
 }
 
 func (c *Client) Events(ctx context.Context, input starknetrpc.EventsInput) (*starknetrpc.EventChunk, error) {
@@ -200,11 +255,67 @@ func (c *Client) Events(ctx context.Context, input starknetrpc.EventsInput) (*st
 }
 
 func (c *Client) AccountNonce(ctx context.Context, accountAddress *felt.Felt) (*felt.Felt, error) {
+	return c.AccountNonceAt(ctx, accountAddress, c.defaultBlock)
+}
+
+func (c *Client) AccountNonceAt(ctx context.Context, accountAddress *felt.Felt, blockID starknetrpc.BlockID) (*felt.Felt, error) {
+	if c.defaultTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultTimeout)
+		defer cancel()
+	}
+
+	return c.Provider.Nonce(ctx, blockID, accountAddress)
+}
+
+func (c *Client) StorageAt(ctx context.Context, contractAddress, key *felt.Felt, blockID starknetrpc.BlockID) (*felt.Felt, error) {
+	if c.defaultTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultTimeout)
+		defer cancel()
+	}
+
+	out, err := c.Provider.StorageAt(ctx, contractAddress, key.String(), blockID)
+	if err != nil {
+		return nil, fmt.Errorf("error in client.StorageAt: %w", err)
+	}
+	value, err := starknetutils.HexToFelt(out)
+	if err != nil {
+		return nil, fmt.Errorf("error in client.StorageAt: invalid value %q: %w", out, err)
+	}
+	return value, nil
+}
+
+func (c *Client) ClassHashAt(ctx context.Context, contractAddress *felt.Felt, blockID starknetrpc.BlockID) (*felt.Felt, error) {
 	if c.defaultTimeout != 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, c.defaultTimeout)
 		defer cancel()
 	}
 
-	return c.Provider.Nonce(ctx, starknetrpc.BlockID{Tag: "pending"}, accountAddress)
+	out, err := c.Provider.ClassHashAt(ctx, blockID, contractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error in client.ClassHashAt: %w", err)
+	}
+	if out == nil {
+		return nil, NilResultError("client.ClassHashAt")
+	}
+	return out, nil
+}
+
+func (c *Client) ClassAt(ctx context.Context, contractAddress *felt.Felt, blockID starknetrpc.BlockID) (starknetrpc.ClassOutput, error) {
+	if c.defaultTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultTimeout)
+		defer cancel()
+	}
+
+	out, err := c.Provider.ClassAt(ctx, blockID, contractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error in client.ClassAt: %w", err)
+	}
+	if out == nil {
+		return nil, NilResultError("client.ClassAt")
+	}
+	return out, nil
 }
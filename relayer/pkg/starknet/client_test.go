@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
 	starknetutils "github.com/NethermindEth/starknet.go/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,6 +25,8 @@ var (
 )
 
 func TestRPCClient(t *testing.T) {
+	// blockIDs records the block id param of the block-aware reads
+	blockIDs := make(chan string, 1)
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		req, _ := io.ReadAll(r.Body)
 		fmt.Println(r.RequestURI, r.URL, string(req))
@@ -43,6 +47,18 @@ func TestRPCClient(t *testing.T) {
 			out = []byte(fmt.Sprintf(`{"result": "%s"}`, id))
 		case "starknet_blockNumber":
 			out = []byte(`{"result": 1}`)
+		case "starknet_getTransactionReceipt":
+			out = []byte(`{"result": {"type": "INVOKE", "transaction_hash": "0x1", "execution_status": "REVERTED", "finality_status": "ACCEPTED_ON_L2",` +
+				`"revert_reason": "stale report", "actual_fee": {"amount": "0x2", "unit": "FRI"}, "block_hash": "0x3", "block_number": 4}}`)
+		case "starknet_call", "starknet_getStorageAt":
+			blockIDs <- string(call.Params[len(call.Params)-1])
+			out = []byte(`{"result": ["0x5"]}`)
+			if call.Method == "starknet_getStorageAt" {
+				out = []byte(`{"result": "0x5"}`)
+			}
+		case "starknet_getNonce", "starknet_getClassHashAt":
+			blockIDs <- string(call.Params[0])
+			out = []byte(`{"result": "0x5"}`)
 		default:
 			require.False(t, true, "unsupported RPC method %s", call.Method)
 		}
@@ -68,4 +84,61 @@ func TestRPCClient(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(1), blockNum)
 	})
+
+	t.Run("get transaction receipt", func(t *testing.T) {
+		out, err := client.TransactionReceipt(context.Background(), new(felt.Felt).SetUint64(1))
+		require.NoError(t, err)
+		receipt, ok := out.(*starknetrpc.TransactionReceiptWithBlockInfo)
+		require.True(t, ok)
+		assert.Equal(t, "0x3", receipt.BlockHash.String())
+		assert.Equal(t, uint(4), receipt.BlockNumber)
+		invoke, ok := receipt.TransactionReceipt.(starknetrpc.InvokeTransactionReceipt)
+		require.True(t, ok)
+		assert.Equal(t, starknetrpc.TxnExecutionStatusREVERTED, invoke.ExecutionStatus)
+		assert.Equal(t, "stale report", invoke.RevertReason)
+		assert.Equal(t, "0x2", invoke.ActualFee.Amount.String())
+	})
+
+	t.Run("block-aware reads", func(t *testing.T) {
+		ctx := context.Background()
+		five := new(felt.Felt).SetUint64(5)
+		address := new(felt.Felt).SetUint64(1)
+
+		res, err := client.CallContractAt(ctx, CallOps{ContractAddress: address, Selector: five}, starknetrpc.WithBlockNumber(7))
+		require.NoError(t, err)
+		assert.Equal(t, []*felt.Felt{five}, res)
+		assert.JSONEq(t, `{"block_number": 7}`, <-blockIDs)
+
+		nonce, err := client.AccountNonceAt(ctx, address, starknetrpc.WithBlockHash(five))
+		require.NoError(t, err)
+		assert.Equal(t, five, nonce)
+		assert.JSONEq(t, `{"block_hash": "0x5"}`, <-blockIDs)
+
+		value, err := client.StorageAt(ctx, address, five, starknetrpc.WithBlockTag(BlockTagLatest))
+		require.NoError(t, err)
+		assert.Equal(t, five, value)
+		assert.JSONEq(t, `"latest"`, <-blockIDs)
+
+		classHash, err := client.ClassHashAt(ctx, address, starknetrpc.WithBlockTag(BlockTagPending))
+		require.NoError(t, err)
+		assert.Equal(t, five, classHash)
+		assert.JSONEq(t, `"pending"`, <-blockIDs)
+	})
+
+	t.Run("default block", func(t *testing.T) {
+		ctx := context.Background()
+		address := new(felt.Felt).SetUint64(1)
+
+		_, err := client.CallContract(ctx, CallOps{ContractAddress: address, Selector: address})
+		require.NoError(t, err)
+		assert.JSONEq(t, `"pending"`, <-blockIDs)
+
+		client.SetDefaultBlock(starknetrpc.WithBlockTag(BlockTagLatest))
+		_, err = client.CallContract(ctx, CallOps{ContractAddress: address, Selector: address})
+		require.NoError(t, err)
+		assert.JSONEq(t, `"latest"`, <-blockIDs)
+		_, err = client.AccountNonce(ctx, address)
+		require.NoError(t, err)
+		assert.JSONEq(t, `"latest"`, <-blockIDs)
+	})
 }
@@ -48,6 +48,36 @@ func (_m *Reader) AccountNonce(_a0 context.Context, _a1 *felt.Felt) (*felt.Felt,
 	return r0, r1
 }
 
+// AccountNonceAt provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Reader) AccountNonceAt(_a0 context.Context, _a1 *felt.Felt, _a2 rpc.BlockID) (*felt.Felt, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccountNonceAt")
+	}
+
+	var r0 *felt.Felt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, rpc.BlockID) (*felt.Felt, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, rpc.BlockID) *felt.Felt); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *felt.Felt, rpc.BlockID) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BlockWithTxHashes provides a mock function with given fields: ctx, blockID
 func (_m *Reader) BlockWithTxHashes(ctx context.Context, blockID rpc.BlockID) (*rpc.Block, error) {
 	ret := _m.Called(ctx, blockID)
@@ -138,6 +168,96 @@ func (_m *Reader) CallContract(_a0 context.Context, _a1 starknet.CallOps) ([]*fe
 	return r0, r1
 }
 
+// CallContractAt provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Reader) CallContractAt(_a0 context.Context, _a1 starknet.CallOps, _a2 rpc.BlockID) ([]*felt.Felt, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CallContractAt")
+	}
+
+	var r0 []*felt.Felt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, starknet.CallOps, rpc.BlockID) ([]*felt.Felt, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, starknet.CallOps, rpc.BlockID) []*felt.Felt); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*felt.Felt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, starknet.CallOps, rpc.BlockID) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClassAt provides a mock function with given fields: ctx, contractAddress, blockID
+func (_m *Reader) ClassAt(ctx context.Context, contractAddress *felt.Felt, blockID rpc.BlockID) (rpc.ClassOutput, error) {
+	ret := _m.Called(ctx, contractAddress, blockID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClassAt")
+	}
+
+	var r0 rpc.ClassOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, rpc.BlockID) (rpc.ClassOutput, error)); ok {
+		return rf(ctx, contractAddress, blockID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, rpc.BlockID) rpc.ClassOutput); ok {
+		r0 = rf(ctx, contractAddress, blockID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rpc.ClassOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *felt.Felt, rpc.BlockID) error); ok {
+		r1 = rf(ctx, contractAddress, blockID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClassHashAt provides a mock function with given fields: ctx, contractAddress, blockID
+func (_m *Reader) ClassHashAt(ctx context.Context, contractAddress *felt.Felt, blockID rpc.BlockID) (*felt.Felt, error) {
+	ret := _m.Called(ctx, contractAddress, blockID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClassHashAt")
+	}
+
+	var r0 *felt.Felt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, rpc.BlockID) (*felt.Felt, error)); ok {
+		return rf(ctx, contractAddress, blockID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, rpc.BlockID) *felt.Felt); ok {
+		r0 = rf(ctx, contractAddress, blockID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *felt.Felt, rpc.BlockID) error); ok {
+		r1 = rf(ctx, contractAddress, blockID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Events provides a mock function with given fields: ctx, input
 func (_m *Reader) Events(ctx context.Context, input rpc.EventsInput) (*rpc.EventChunk, error) {
 	ret := _m.Called(ctx, input)
@@ -196,6 +316,36 @@ func (_m *Reader) LatestBlockHeight(_a0 context.Context) (uint64, error) {
 	return r0, r1
 }
 
+// StorageAt provides a mock function with given fields: ctx, contractAddress, key, blockID
+func (_m *Reader) StorageAt(ctx context.Context, contractAddress *felt.Felt, key *felt.Felt, blockID rpc.BlockID) (*felt.Felt, error) {
+	ret := _m.Called(ctx, contractAddress, key, blockID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StorageAt")
+	}
+
+	var r0 *felt.Felt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, *felt.Felt, rpc.BlockID) (*felt.Felt, error)); ok {
+		return rf(ctx, contractAddress, key, blockID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *felt.Felt, *felt.Felt, rpc.BlockID) *felt.Felt); ok {
+		r0 = rf(ctx, contractAddress, key, blockID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*felt.Felt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *felt.Felt, *felt.Felt, rpc.BlockID) error); ok {
+		r1 = rf(ctx, contractAddress, key, blockID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // TransactionByHash provides a mock function with given fields: _a0, _a1
 func (_m *Reader) TransactionByHash(_a0 context.Context, _a1 *felt.Felt) (rpc.Transaction, error) {
 	ret := _m.Called(_a0, _a1)
@@ -0,0 +1,240 @@
+package starknettest
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// AggregatorConfig is the OCR2 config set on an Aggregator.
+type AggregatorConfig struct {
+	ConfigDigest [32]byte
+	// Signers are the onchain public keys of the oracles, each at most a felt
+	Signers      [][]byte
+	Transmitters []*felt.Felt
+	F            uint8
+	// OnchainConfig holds the felts of the median onchain config: version, min and max
+	OnchainConfig         []*felt.Felt
+	OffchainConfigVersion uint64
+	OffchainConfig        []byte
+}
+
+// AggregatorRound is the latest round of an Aggregator.
+type AggregatorRound struct {
+	RoundID       uint32
+	Answer        *big.Int
+	EpochAndRound *big.Int
+	// ObservationTimestamp is the timestamp of the report, BlockTimestamp the timestamp of its transmission
+	ObservationTimestamp uint64
+	BlockNumber          uint64
+	BlockTimestamp       uint64
+}
+
+// Aggregator is a scriptable OCR2 aggregator contract. It accepts transmit invokes of reports of its latest config,
+// without verifying their signatures, and serves the getters that the relayer reads.
+type Aggregator struct {
+	server  *Server
+	lock    *sync.Mutex
+	address *felt.Felt
+
+	config            AggregatorConfig
+	configCount       uint64
+	configBlockNumber uint64
+	round             AggregatorRound
+	// billing holds the observation payment, transmission payment, gas base and gas per signature
+	billing       [4]*felt.Felt
+	linkAvailable *big.Int
+}
+
+var (
+	_ Contract    = (*Aggregator)(nil)
+	_ Snapshotter = (*Aggregator)(nil)
+)
+
+var (
+	// NewTransmissionEventKey and ConfigSetEventKey are the selectors of the aggregator events.
+	NewTransmissionEventKey = starknetutils.GetSelectorFromNameFelt("NewTransmission")
+	ConfigSetEventKey       = starknetutils.GetSelectorFromNameFelt("ConfigSet")
+)
+
+// DeployAggregator deploys an aggregator without a config at address.
+func (s *Server) DeployAggregator(address *felt.Felt) *Aggregator {
+	a := &Aggregator{
+		server:        s,
+		lock:          &s.lock,
+		address:       address,
+		round:         AggregatorRound{Answer: new(big.Int), EpochAndRound: new(big.Int)},
+		billing:       [4]*felt.Felt{new(felt.Felt), new(felt.Felt), new(felt.Felt), new(felt.Felt)},
+		linkAvailable: new(big.Int),
+	}
+	s.Deploy(address, a)
+	return a
+}
+
+// SetConfig sets the config and mines a block, along with any pending txs, with its ConfigSet event. The round of
+// the previous config is kept.
+func (a *Aggregator) SetConfig(config AggregatorConfig) error {
+	if len(config.Signers) != len(config.Transmitters) {
+		return errors.New("signers and transmitters must have the same length")
+	}
+	offchainConfig := starknet.EncodeFelts(config.OffchainConfig)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	b := a.server.mine()
+	previousBlockNumber := a.configBlockNumber
+	a.config, a.configBlockNumber = config, b.number
+	a.configCount++
+
+	data := []*felt.Felt{new(felt.Felt).SetUint64(a.configCount), new(felt.Felt).SetUint64(uint64(len(config.Signers)))}
+	for i, signer := range config.Signers {
+		data = append(data, new(felt.Felt).SetBytes(signer), config.Transmitters[i])
+	}
+	data = append(data, new(felt.Felt).SetUint64(uint64(config.F)), new(felt.Felt).SetUint64(uint64(len(config.OnchainConfig))))
+	data = append(data, config.OnchainConfig...)
+	data = append(data, new(felt.Felt).SetUint64(config.OffchainConfigVersion), new(felt.Felt).SetUint64(uint64(len(offchainConfig))))
+	for _, v := range offchainConfig {
+		data = append(data, starknetutils.BigIntToFelt(v))
+	}
+
+	// the event is attributed to a config tx that only exists in the block
+	exec := &Execution{address: a.address, BlockNumber: b.number, BlockTimestamp: b.timestamp}
+	exec.Emit([]*felt.Felt{ConfigSetEventKey, new(felt.Felt).SetUint64(previousBlockNumber), new(felt.Felt).SetBytes(config.ConfigDigest[:])}, data)
+	tx := &transaction{
+		hash:   new(felt.Felt).SetBytes(config.ConfigDigest[:]),
+		fee:    new(big.Int),
+		unit:   starknetrpc.UnitStrk,
+		status: starknetrpc.TxnStatus_Accepted_On_L2,
+		events: exec.events,
+		block:  b,
+	}
+	b.txs = append(b.txs, tx)
+	a.server.txs[tx.hash.String()] = tx
+	return nil
+}
+
+// SetBilling sets the billing config returned by the billing getter.
+func (a *Aggregator) SetBilling(observationPayment, transmissionPayment, gasBase, gasPerSignature uint64) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for i, v := range []uint64{observationPayment, transmissionPayment, gasBase, gasPerSignature} {
+		a.billing[i] = new(felt.Felt).SetUint64(v)
+	}
+}
+
+// SetLinkAvailableForPayment sets the LINK available for payment, which may be negative.
+func (a *Aggregator) SetLinkAvailableForPayment(amount *big.Int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.linkAvailable = new(big.Int).Set(amount)
+}
+
+// LatestRound returns the round of the latest transmission.
+func (a *Aggregator) LatestRound() AggregatorRound {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	round := a.round
+	round.Answer = new(big.Int).Set(a.round.Answer)
+	round.EpochAndRound = new(big.Int).Set(a.round.EpochAndRound)
+	return round
+}
+
+func (a *Aggregator) Call(exec *Execution, selector *felt.Felt, calldata []*felt.Felt) ([]*felt.Felt, error) {
+	return Functions{
+		"transmit":                    a.transmit,
+		"latest_config_details":       a.latestConfigDetails,
+		"latest_transmission_details": a.latestTransmissionDetails,
+		"latest_round_data":           a.latestRoundData,
+		"link_available_for_payment":  a.linkAvailableForPayment,
+		"billing": func(*Execution, []*felt.Felt) ([]*felt.Felt, error) {
+			return a.billing[:], nil
+		},
+	}.Call(exec, selector, calldata)
+}
+
+func (a *Aggregator) Snapshot() func() {
+	round := a.round
+	return func() { a.round = round }
+}
+
+func (a *Aggregator) latestConfigDetails(*Execution, []*felt.Felt) ([]*felt.Felt, error) {
+	return []*felt.Felt{
+		new(felt.Felt).SetUint64(a.configCount),
+		new(felt.Felt).SetUint64(a.configBlockNumber),
+		new(felt.Felt).SetBytes(a.config.ConfigDigest[:]),
+	}, nil
+}
+
+func (a *Aggregator) latestTransmissionDetails(*Execution, []*felt.Felt) ([]*felt.Felt, error) {
+	return []*felt.Felt{
+		new(felt.Felt).SetBytes(a.config.ConfigDigest[:]),
+		starknetutils.BigIntToFelt(a.round.EpochAndRound),
+		starknetutils.BigIntToFelt(a.round.Answer),
+		new(felt.Felt).SetUint64(a.round.ObservationTimestamp),
+	}, nil
+}
+
+func (a *Aggregator) latestRoundData(*Execution, []*felt.Felt) ([]*felt.Felt, error) {
+	return []*felt.Felt{
+		new(felt.Felt).SetUint64(uint64(a.round.RoundID)),
+		starknetutils.BigIntToFelt(a.round.Answer),
+		new(felt.Felt).SetUint64(a.round.BlockNumber),
+		new(felt.Felt).SetUint64(a.round.BlockTimestamp),
+		new(felt.Felt).SetUint64(a.round.BlockTimestamp),
+	}, nil
+}
+
+func (a *Aggregator) linkAvailableForPayment(*Execution, []*felt.Felt) ([]*felt.Felt, error) {
+	isNegative := new(felt.Felt)
+	if a.linkAvailable.Sign() < 0 {
+		isNegative.SetUint64(1)
+	}
+	return []*felt.Felt{isNegative, starknetutils.BigIntToFelt(new(big.Int).Abs(a.linkAvailable))}, nil
+}
+
+// transmit accepts a report with the calldata layout [config_digest, epoch_and_round, extra_hash,
+// observation_timestamp, observers, observations_len, observations..., juels_per_fee_coin, gas_price,
+// signatures_len, (r, s, public_key)*]. The answer is the median observation.
+func (a *Aggregator) transmit(exec *Execution, calldata []*felt.Felt) ([]*felt.Felt, error) {
+	const observationsLenIndex = 5
+	observationsLen, ok := feltLen(calldata, observationsLenIndex)
+	if !ok || observationsLen == 0 {
+		return nil, errors.New("transmit: invalid observations")
+	}
+	signaturesIndex := observationsLenIndex + 1 + observationsLen + 2
+	signaturesLen, ok := feltLen(calldata, signaturesIndex)
+	if !ok || signaturesIndex+1+3*signaturesLen != len(calldata) {
+		return nil, errors.New("transmit: invalid signatures")
+	}
+
+	if !calldata[0].Equal(new(felt.Felt).SetBytes(a.config.ConfigDigest[:])) {
+		return nil, errors.New("config digest mismatch")
+	}
+	epochAndRound := calldata[1].BigInt(new(big.Int))
+	if epochAndRound.Cmp(a.round.EpochAndRound) <= 0 {
+		return nil, errors.New("stale report")
+	}
+
+	observations := calldata[observationsLenIndex+1 : observationsLenIndex+1+observationsLen]
+	juelsPerFeeCoin, gasPrice := calldata[signaturesIndex-2], calldata[signaturesIndex-1]
+	a.round = AggregatorRound{
+		RoundID:              a.round.RoundID + 1,
+		Answer:               observations[len(observations)/2].BigInt(new(big.Int)),
+		EpochAndRound:        epochAndRound,
+		ObservationTimestamp: calldata[3].BigInt(new(big.Int)).Uint64(),
+		BlockNumber:          exec.BlockNumber,
+		BlockTimestamp:       exec.BlockTimestamp,
+	}
+
+	data := []*felt.Felt{observations[len(observations)/2], calldata[3], calldata[4], calldata[observationsLenIndex]}
+	data = append(data, observations...)
+	data = append(data, juelsPerFeeCoin, gasPrice, calldata[0], calldata[1], new(felt.Felt))
+	exec.Emit([]*felt.Felt{NewTransmissionEventKey, new(felt.Felt).SetUint64(uint64(a.round.RoundID)), exec.Caller}, data)
+	return nil, nil
+}
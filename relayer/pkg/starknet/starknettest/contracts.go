@@ -0,0 +1,245 @@
+package starknettest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+)
+
+// Contract is a contract implemented in Go. It is called for starknet_call requests and for the calls of mined
+// invokes, with the server lock held, so implementations must not call back into the Server.
+type Contract interface {
+	// Call runs the function of selector, a returned error reverts the call with its message as the reason.
+	Call(exec *Execution, selector *felt.Felt, calldata []*felt.Felt) ([]*felt.Felt, error)
+}
+
+// Snapshotter is implemented by contracts whose state changes are rolled back when an invoke reverts, and
+// discarded after a simulation.
+type Snapshotter interface {
+	// Snapshot saves the state of the contract and returns a function that restores it.
+	Snapshot() (restore func())
+}
+
+// Execution is the context of a contract call.
+type Execution struct {
+	// Caller is the account that sent the invoke, nil for starknet_call
+	Caller         *felt.Felt
+	BlockNumber    uint64
+	BlockTimestamp uint64
+
+	address *felt.Felt
+	events  []starknetrpc.Event
+}
+
+// Address returns the address of the called contract.
+func (e *Execution) Address() *felt.Felt {
+	return e.address
+}
+
+// Emit emits an event from the called contract, events of calls outside invokes are dropped.
+func (e *Execution) Emit(keys, data []*felt.Felt) {
+	e.events = append(e.events, starknetrpc.Event{FromAddress: e.address, Keys: keys, Data: data})
+}
+
+// Function is a contract function, see Functions.
+type Function func(exec *Execution, calldata []*felt.Felt) ([]*felt.Felt, error)
+
+// Functions is a Contract that dispatches calls to its functions by name.
+type Functions map[string]Function
+
+var _ Contract = Functions(nil)
+
+func (f Functions) Call(exec *Execution, selector *felt.Felt, calldata []*felt.Felt) ([]*felt.Felt, error) {
+	for name, fn := range f {
+		if starknetutils.GetSelectorFromNameFelt(name).Equal(selector) {
+			return fn(exec, calldata)
+		}
+	}
+	return nil, fmt.Errorf("entry point %s not found in contract", selector)
+}
+
+// ERC20 is a token with balance_of and transfer. Fee tokens are ERC20s that are charged the fees of mined invokes.
+type ERC20 struct {
+	lock     *sync.Mutex
+	balances map[string]*big.Int
+}
+
+var (
+	_ Contract    = (*ERC20)(nil)
+	_ Snapshotter = (*ERC20)(nil)
+)
+
+// DeployERC20 deploys an ERC20 without balances at address.
+func (s *Server) DeployERC20(address *felt.Felt) *ERC20 {
+	token := &ERC20{lock: &s.lock, balances: map[string]*big.Int{}}
+	s.Deploy(address, token)
+	return token
+}
+
+// Mint adds amount to the balance of account.
+func (t *ERC20) Mint(account *felt.Felt, amount *big.Int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.add(account, amount)
+}
+
+// Balance returns the balance of account.
+func (t *ERC20) Balance(account *felt.Felt) *big.Int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.balance(account)
+}
+
+func (t *ERC20) Call(exec *Execution, selector *felt.Felt, calldata []*felt.Felt) ([]*felt.Felt, error) {
+	return Functions{
+		"balance_of": t.balanceOf,
+		"balanceOf":  t.balanceOf,
+		"transfer":   t.transfer,
+	}.Call(exec, selector, calldata)
+}
+
+func (t *ERC20) Snapshot() func() {
+	balances := map[string]*big.Int{}
+	for k, v := range t.balances {
+		balances[k] = new(big.Int).Set(v)
+	}
+	return func() { t.balances = balances }
+}
+
+// balanceOf returns the balance of an account as a u256.
+func (t *ERC20) balanceOf(_ *Execution, calldata []*felt.Felt) ([]*felt.Felt, error) {
+	if len(calldata) != 1 {
+		return nil, errors.New("balance_of: expected 1 argument")
+	}
+	return toU256(t.balance(calldata[0])), nil
+}
+
+// transfer moves a u256 amount from the caller to the recipient.
+func (t *ERC20) transfer(exec *Execution, calldata []*felt.Felt) ([]*felt.Felt, error) {
+	if len(calldata) != 3 {
+		return nil, errors.New("transfer: expected 3 arguments")
+	}
+	if exec.Caller == nil {
+		return nil, errors.New("transfer: no caller")
+	}
+	amount := fromU256(calldata[1], calldata[2])
+	if t.balance(exec.Caller).Cmp(amount) < 0 {
+		return nil, errors.New("ERC20: insufficient balance")
+	}
+	t.add(exec.Caller, new(big.Int).Neg(amount))
+	t.add(calldata[0], amount)
+	exec.Emit([]*felt.Felt{starknetutils.GetSelectorFromNameFelt("Transfer")}, []*felt.Felt{exec.Caller, calldata[0], calldata[1], calldata[2]})
+	return []*felt.Felt{new(felt.Felt).SetUint64(1)}, nil
+}
+
+func (t *ERC20) balance(account *felt.Felt) *big.Int {
+	if balance, ok := t.balances[account.String()]; ok {
+		return new(big.Int).Set(balance)
+	}
+	return new(big.Int)
+}
+
+func (t *ERC20) add(account *felt.Felt, amount *big.Int) {
+	t.balances[account.String()] = t.balance(account).Add(t.balance(account), amount)
+}
+
+// charge takes the fee of an invoke, the balance is checked when the invoke is received and may not cover the
+// fees of several pending invokes, in which case it is emptied.
+func (t *ERC20) charge(account *felt.Felt, fee *big.Int) {
+	if t.balance(account).Cmp(fee) < 0 {
+		fee = t.balance(account)
+	}
+	t.add(account, new(big.Int).Neg(fee))
+}
+
+var u128 = new(big.Int).Lsh(big.NewInt(1), 128)
+
+func toU256(v *big.Int) []*felt.Felt {
+	high, low := new(big.Int).DivMod(v, u128, new(big.Int))
+	return []*felt.Felt{starknetutils.BigIntToFelt(low), starknetutils.BigIntToFelt(high)}
+}
+
+func fromU256(low, high *felt.Felt) *big.Int {
+	v := high.BigInt(new(big.Int))
+	return v.Mul(v, u128).Add(v, low.BigInt(new(big.Int)))
+}
+
+// decodeMulticall decodes the calls of the __execute__ calldata of an account, in the Cairo 1 layout
+// [n, (to, selector, calldata_len, calldata...)*] or else in the Cairo 0 layout
+// [n, (to, selector, data_offset, data_len)*, calldata_len, calldata...].
+func decodeMulticall(calldata []*felt.Felt) ([]starknetrpc.FunctionCall, error) {
+	if calls, ok := decodeCairo1Multicall(calldata); ok {
+		return calls, nil
+	}
+	if calls, ok := decodeCairo0Multicall(calldata); ok {
+		return calls, nil
+	}
+	return nil, errors.New("invalid multicall calldata")
+}
+
+func decodeCairo1Multicall(calldata []*felt.Felt) ([]starknetrpc.FunctionCall, bool) {
+	n, ok := feltLen(calldata, 0)
+	if !ok {
+		return nil, false
+	}
+	calls := make([]starknetrpc.FunctionCall, 0, n)
+	i := 1
+	for j := 0; j < n; j++ {
+		dataLen, ok := feltLen(calldata, i+2)
+		if !ok || i+3+dataLen > len(calldata) {
+			return nil, false
+		}
+		calls = append(calls, starknetrpc.FunctionCall{
+			ContractAddress:    calldata[i],
+			EntryPointSelector: calldata[i+1],
+			Calldata:           calldata[i+3 : i+3+dataLen],
+		})
+		i += 3 + dataLen
+	}
+	return calls, i == len(calldata)
+}
+
+func decodeCairo0Multicall(calldata []*felt.Felt) ([]starknetrpc.FunctionCall, bool) {
+	n, ok := feltLen(calldata, 0)
+	if !ok {
+		return nil, false
+	}
+	start := 1 + 4*n
+	dataLen, ok := feltLen(calldata, start)
+	if !ok || start+1+dataLen != len(calldata) {
+		return nil, false
+	}
+	data := calldata[start+1:]
+	calls := make([]starknetrpc.FunctionCall, 0, n)
+	for j := 0; j < n; j++ {
+		i := 1 + 4*j
+		offset, ok1 := feltLen(calldata, i+2)
+		length, ok2 := feltLen(calldata, i+3)
+		if !ok1 || !ok2 || offset+length > len(data) {
+			return nil, false
+		}
+		calls = append(calls, starknetrpc.FunctionCall{
+			ContractAddress:    calldata[i],
+			EntryPointSelector: calldata[i+1],
+			Calldata:           data[offset : offset+length],
+		})
+	}
+	return calls, true
+}
+
+// feltLen reads a length at index i of calldata, which must not exceed the calldata.
+func feltLen(calldata []*felt.Felt, i int) (int, bool) {
+	if i >= len(calldata) {
+		return 0, false
+	}
+	v := calldata[i].BigInt(new(big.Int))
+	if !v.IsInt64() || v.Int64() > int64(len(calldata)) {
+		return 0, false
+	}
+	return int(v.Int64()), true
+}
@@ -0,0 +1,616 @@
+package starknettest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/NethermindEth/juno/core/crypto"
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+)
+
+type handler func(s *Server, params []json.RawMessage) (any, *rpcError)
+
+// handlers implements the JSON-RPC methods, they are called with the lock held.
+var handlers = map[string]handler{
+	"starknet_chainId":                  (*Server).chainIDMethod,
+	"starknet_specVersion":              func(*Server, []json.RawMessage) (any, *rpcError) { return SpecVersion, nil },
+	"starknet_syncing":                  func(*Server, []json.RawMessage) (any, *rpcError) { return false, nil },
+	"starknet_blockNumber":              func(s *Server, _ []json.RawMessage) (any, *rpcError) { return s.latest().number, nil },
+	"starknet_blockHashAndNumber":       (*Server).blockHashAndNumber,
+	"starknet_getBlockWithTxHashes":     (*Server).getBlockWithTxHashes,
+	"starknet_getBlockWithTxs":          (*Server).getBlockWithTxs,
+	"starknet_getNonce":                 (*Server).getNonce,
+	"starknet_getClassHashAt":           (*Server).getClassHashAt,
+	"starknet_call":                     (*Server).callMethod,
+	"starknet_estimateFee":              (*Server).estimateFee,
+	"starknet_simulateTransactions":     (*Server).simulateTransactions,
+	"starknet_addInvokeTransaction":     (*Server).addInvokeTransaction,
+	"starknet_getTransactionStatus":     (*Server).getTransactionStatus,
+	"starknet_getTransactionReceipt":    (*Server).getTransactionReceipt,
+	"starknet_getTransactionByHash":     (*Server).getTransactionByHash,
+	"starknet_getEvents":                (*Server).getEvents,
+	"starknet_getBlockTransactionCount": (*Server).getBlockTransactionCount,
+}
+
+// invokeTxn holds the fields of V1 and V3 invokes that the server checks.
+type invokeTxn struct {
+	Type           starknetrpc.TransactionType        `json:"type"`
+	SenderAddress  *felt.Felt                         `json:"sender_address"`
+	Calldata       []*felt.Felt                       `json:"calldata"`
+	Version        *felt.Felt                         `json:"version"`
+	Signature      []*felt.Felt                       `json:"signature"`
+	Nonce          *felt.Felt                         `json:"nonce"`
+	MaxFee         *felt.Felt                         `json:"max_fee,omitempty"`
+	ResourceBounds *starknetrpc.ResourceBoundsMapping `json:"resource_bounds,omitempty"`
+}
+
+// version returns the tx version without the query bit of estimates and simulations.
+func (tx invokeTxn) version() uint64 {
+	if tx.Version == nil {
+		return 0
+	}
+	b := tx.Version.Bytes()
+	return new(big.Int).SetBytes(b[24:]).Uint64()
+}
+
+func (tx invokeTxn) unit() starknetrpc.FeePaymentUnit {
+	if tx.version() == 3 {
+		return starknetrpc.UnitStrk
+	}
+	return starknetrpc.UnitWei
+}
+
+// maxFee is the most the sender is willing to pay: the max fee of V1 txs, the L1 gas bounds of V3 txs.
+func (tx invokeTxn) maxFee() (*big.Int, error) {
+	if tx.version() != 3 {
+		if tx.MaxFee == nil {
+			return nil, errors.New("missing max_fee")
+		}
+		return tx.MaxFee.BigInt(new(big.Int)), nil
+	}
+	if tx.ResourceBounds == nil {
+		return nil, errors.New("missing resource_bounds")
+	}
+	amount, ok := new(big.Int).SetString(string(tx.ResourceBounds.L1Gas.MaxAmount), 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid max_amount %q", tx.ResourceBounds.L1Gas.MaxAmount)
+	}
+	price, ok := new(big.Int).SetString(string(tx.ResourceBounds.L1Gas.MaxPricePerUnit), 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid max_price_per_unit %q", tx.ResourceBounds.L1Gas.MaxPricePerUnit)
+	}
+	return amount.Mul(amount, price), nil
+}
+
+// hash is a stand-in for the tx hash: it commits to every field that the server knows of, but is not the hash
+// that the account signs.
+func (tx invokeTxn) hash() *felt.Felt {
+	fields := []*felt.Felt{tx.SenderAddress, tx.Nonce, new(felt.Felt).SetUint64(tx.version()), crypto.PoseidonArray(tx.Calldata...), crypto.PoseidonArray(tx.Signature...)}
+	if tx.MaxFee != nil {
+		fields = append(fields, tx.MaxFee)
+	}
+	if tx.ResourceBounds != nil {
+		for _, v := range []string{string(tx.ResourceBounds.L1Gas.MaxAmount), string(tx.ResourceBounds.L1Gas.MaxPricePerUnit)} {
+			b, _ := new(big.Int).SetString(v, 0)
+			fields = append(fields, starknetutils.BigIntToFelt(b))
+		}
+	}
+	return crypto.PoseidonArray(fields...)
+}
+
+func param(params []json.RawMessage, i int, v any) *rpcError {
+	if i >= len(params) {
+		return invalidParams(fmt.Errorf("missing param %d", i))
+	}
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return invalidParams(fmt.Errorf("param %d: %w", i, err))
+	}
+	return nil
+}
+
+// blockParam resolves a block id, pending is set for the pending tag in which case b is the latest block.
+func (s *Server) blockParam(params []json.RawMessage, i int) (b *block, pending bool, rpcErr *rpcError) {
+	var tag string
+	if i < len(params) && json.Unmarshal(params[i], &tag) == nil {
+		switch tag {
+		case "latest":
+			return s.latest(), false, nil
+		case "pending":
+			return s.latest(), true, nil
+		default:
+			return nil, false, invalidParams(fmt.Errorf("invalid block tag %q", tag))
+		}
+	}
+	var id struct {
+		Number *uint64    `json:"block_number"`
+		Hash   *felt.Felt `json:"block_hash"`
+	}
+	if rpcErr = param(params, i, &id); rpcErr != nil {
+		return nil, false, rpcErr
+	}
+	switch {
+	case id.Number != nil && *id.Number < uint64(len(s.blocks)):
+		return s.blocks[*id.Number], false, nil
+	case id.Hash != nil:
+		for _, b := range s.blocks {
+			if b.hash.Equal(id.Hash) {
+				return b, false, nil
+			}
+		}
+	}
+	return nil, false, newRPCError(starknetrpc.ErrBlockNotFound, nil)
+}
+
+func (s *Server) chainIDMethod(_ []json.RawMessage) (any, *rpcError) {
+	return "0x" + hex.EncodeToString([]byte(s.chainID)), nil
+}
+
+func (s *Server) blockHashAndNumber(_ []json.RawMessage) (any, *rpcError) {
+	latest := s.latest()
+	return starknetrpc.BlockHashAndNumberOutput{BlockNumber: latest.number, BlockHash: latest.hash}, nil
+}
+
+func (s *Server) blockHeader(b *block, pending bool) map[string]any {
+	fri, wei := s.gasPriceFRI, s.gasPriceWei
+	header := map[string]any{
+		"parent_hash":       b.hash,
+		"timestamp":         uint64(time.Now().Unix()),
+		"sequencer_address": new(felt.Felt),
+		"l1_da_mode":        starknetrpc.L1DAModeBlob,
+		"starknet_version":  SpecVersion,
+	}
+	if !pending {
+		fri, wei = b.gasPriceFRI, b.gasPriceWei
+		header["block_hash"] = b.hash
+		header["parent_hash"] = b.parentHash
+		header["block_number"] = b.number
+		header["new_root"] = b.hash
+		header["timestamp"] = b.timestamp
+		header["status"] = starknetrpc.BlockStatus_AcceptedOnL2
+		if b.number < uint64(s.l1Blocks) {
+			header["status"] = starknetrpc.BlockStatus_AcceptedOnL1
+		}
+	}
+	prices := starknetrpc.ResourcePrice{PriceInFRI: starknetutils.BigIntToFelt(fri), PriceInWei: starknetutils.BigIntToFelt(wei)}
+	header["l1_gas_price"] = prices
+	header["l1_data_gas_price"] = prices
+	return header
+}
+
+func (s *Server) blockTxs(b *block, pending bool) []*transaction {
+	if pending {
+		return s.pending
+	}
+	return b.txs
+}
+
+func (s *Server) getBlockWithTxHashes(params []json.RawMessage) (any, *rpcError) {
+	b, pending, rpcErr := s.blockParam(params, 0)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	result := s.blockHeader(b, pending)
+	hashes := []*felt.Felt{}
+	for _, tx := range s.blockTxs(b, pending) {
+		hashes = append(hashes, tx.hash)
+	}
+	result["transactions"] = hashes
+	return result, nil
+}
+
+func (s *Server) getBlockWithTxs(params []json.RawMessage) (any, *rpcError) {
+	b, pending, rpcErr := s.blockParam(params, 0)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	result := s.blockHeader(b, pending)
+	txs := []map[string]any{}
+	for _, tx := range s.blockTxs(b, pending) {
+		txs = append(txs, tx.withHash())
+	}
+	result["transactions"] = txs
+	return result, nil
+}
+
+func (s *Server) getBlockTransactionCount(params []json.RawMessage) (any, *rpcError) {
+	b, pending, rpcErr := s.blockParam(params, 0)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return len(s.blockTxs(b, pending)), nil
+}
+
+func (s *Server) getNonce(params []json.RawMessage) (any, *rpcError) {
+	_, pending, rpcErr := s.blockParam(params, 0)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var address felt.Felt
+	if rpcErr = param(params, 1, &address); rpcErr != nil {
+		return nil, rpcErr
+	}
+	nonce, ok := s.accounts[address.String()]
+	if !ok {
+		return nil, newRPCError(starknetrpc.ErrContractNotFound, nil)
+	}
+	if pending {
+		return s.pendingNonce(&address), nil
+	}
+	// nonces are not versioned, every block sees the latest state
+	return nonce, nil
+}
+
+// pendingNonce is the nonce of the next tx of an account, which accounts for its pending txs.
+func (s *Server) pendingNonce(address *felt.Felt) *felt.Felt {
+	nonce := new(felt.Felt).Set(s.accounts[address.String()])
+	for _, tx := range s.pending {
+		if tx.invoke.SenderAddress.Equal(address) && tx.invoke.Nonce.Cmp(nonce) >= 0 {
+			nonce.Add(tx.invoke.Nonce, new(felt.Felt).SetUint64(1))
+		}
+	}
+	return nonce
+}
+
+// pendingAt reports whether an account has a pending tx at nonce.
+func (s *Server) pendingAt(address, nonce *felt.Felt) bool {
+	for _, tx := range s.pending {
+		if tx.invoke.SenderAddress.Equal(address) && tx.invoke.Nonce.Equal(nonce) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) getClassHashAt(params []json.RawMessage) (any, *rpcError) {
+	if _, _, rpcErr := s.blockParam(params, 0); rpcErr != nil {
+		return nil, rpcErr
+	}
+	var address felt.Felt
+	if rpcErr := param(params, 1, &address); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if _, ok := s.accounts[address.String()]; ok {
+		return AccountClassHash, nil
+	}
+	if _, ok := s.contracts[address.String()]; ok {
+		return contractClassHash, nil
+	}
+	return nil, newRPCError(starknetrpc.ErrContractNotFound, nil)
+}
+
+// callMethod runs a call against the state of the latest block, whatever the block id.
+func (s *Server) callMethod(params []json.RawMessage) (any, *rpcError) {
+	var call starknetrpc.FunctionCall
+	if rpcErr := param(params, 0, &call); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if _, _, rpcErr := s.blockParam(params, 1); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if _, ok := s.contracts[call.ContractAddress.String()]; !ok {
+		return nil, newRPCError(starknetrpc.ErrContractNotFound, nil)
+	}
+	latest := s.latest()
+	result, err := s.call(&Execution{BlockNumber: latest.number, BlockTimestamp: latest.timestamp}, call)
+	if err != nil {
+		return nil, newRPCError(starknetrpc.ErrContractError, map[string]any{"revert_error": err.Error()})
+	}
+	return result, nil
+}
+
+// checkInvoke validates an invoke against the pending state of its sender. An invoke may reuse the nonce of a
+// pending tx of its sender, like a fee bump: both stay pending, and the one mined first rejects the other.
+func (s *Server) checkInvoke(tx invokeTxn) *rpcError {
+	if tx.SenderAddress == nil || tx.Nonce == nil {
+		return invalidParams(errors.New("missing sender_address or nonce"))
+	}
+	if _, ok := s.accounts[tx.SenderAddress.String()]; !ok {
+		return newRPCError(starknetrpc.ErrContractNotFound, nil)
+	}
+	if nonce := s.pendingNonce(tx.SenderAddress); !nonce.Equal(tx.Nonce) && !s.pendingAt(tx.SenderAddress, tx.Nonce) {
+		return newRPCError(starknetrpc.ErrInvalidTransactionNonce, fmt.Sprintf("Invalid transaction nonce of contract at address %s. Account nonce: %s; got: %s.", tx.SenderAddress, nonce, tx.Nonce))
+	}
+	return nil
+}
+
+// fee is the actual fee of an invoke, in its fee unit.
+func (s *Server) fee(tx invokeTxn) (gasPrice, overall *big.Int) {
+	gasPrice = s.gasPriceWei
+	if tx.unit() == starknetrpc.UnitStrk {
+		gasPrice = s.gasPriceFRI
+	}
+	return gasPrice, new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(s.gasConsumed))
+}
+
+func (s *Server) feeEstimate(tx invokeTxn) starknetrpc.FeeEstimate {
+	gasPrice, overall := s.fee(tx)
+	return starknetrpc.FeeEstimate{
+		GasConsumed:     new(felt.Felt).SetUint64(s.gasConsumed),
+		GasPrice:        starknetutils.BigIntToFelt(gasPrice),
+		DataGasConsumed: new(felt.Felt),
+		DataGasPrice:    starknetutils.BigIntToFelt(gasPrice),
+		OverallFee:      starknetutils.BigIntToFelt(overall),
+		FeeUnit:         tx.unit(),
+	}
+}
+
+func (s *Server) estimateFee(params []json.RawMessage) (any, *rpcError) {
+	var txs []invokeTxn
+	if rpcErr := param(params, 0, &txs); rpcErr != nil {
+		return nil, rpcErr
+	}
+	estimates := make([]starknetrpc.FeeEstimate, len(txs))
+	for i, tx := range txs {
+		if rpcErr := s.checkInvoke(tx); rpcErr != nil {
+			// nodes report validation failures of estimates as an execution error of the tx
+			return nil, newRPCError(starknetrpc.ErrTxnExec, map[string]any{"transaction_index": i, "execution_error": rpcErr.dataString()})
+		}
+		estimates[i] = s.feeEstimate(tx)
+	}
+	return estimates, nil
+}
+
+// simulateTransactions runs the calls of each invoke without committing its state changes. Only the revert reason
+// and the fee estimate of the trace are filled in.
+func (s *Server) simulateTransactions(params []json.RawMessage) (any, *rpcError) {
+	if _, _, rpcErr := s.blockParam(params, 0); rpcErr != nil {
+		return nil, rpcErr
+	}
+	var txs []invokeTxn
+	if rpcErr := param(params, 1, &txs); rpcErr != nil {
+		return nil, rpcErr
+	}
+	results := make([]map[string]any, len(txs))
+	for i, tx := range txs {
+		if rpcErr := s.checkInvoke(tx); rpcErr != nil {
+			return nil, newRPCError(starknetrpc.ErrTxnExec, map[string]any{"transaction_index": i, "execution_error": rpcErr.dataString()})
+		}
+		execution := map[string]any{}
+		if reason := s.dryRun(tx); reason != "" {
+			execution["revert_reason"] = reason
+		}
+		results[i] = map[string]any{
+			"transaction_trace": map[string]any{"type": starknetrpc.TransactionType_Invoke, "execute_invocation": execution},
+			"fee_estimation":    s.feeEstimate(tx),
+		}
+	}
+	return results, nil
+}
+
+// dryRun runs the calls of tx against snapshots of the contracts that support them, and returns the revert
+// reason if one of the calls fails.
+func (s *Server) dryRun(tx invokeTxn) string {
+	calls, err := decodeMulticall(tx.Calldata)
+	if err != nil {
+		return err.Error()
+	}
+	restore := s.snapshot()
+	defer restore()
+	latest := s.latest()
+	exec := &Execution{Caller: tx.SenderAddress, BlockNumber: latest.number + 1, BlockTimestamp: uint64(time.Now().Unix())}
+	for _, call := range calls {
+		if _, err := s.call(exec, call); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func (s *Server) addInvokeTransaction(params []json.RawMessage) (any, *rpcError) {
+	var raw map[string]any
+	if rpcErr := param(params, 0, &raw); rpcErr != nil {
+		return nil, rpcErr
+	}
+	var tx invokeTxn
+	if rpcErr := param(params, 0, &tx); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if tx.Type != starknetrpc.TransactionType_Invoke {
+		return nil, invalidParams(fmt.Errorf("unsupported tx type %q", tx.Type))
+	}
+	if rpcErr := s.checkInvoke(tx); rpcErr != nil {
+		return nil, rpcErr
+	}
+	maxFee, err := tx.maxFee()
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	_, fee := s.fee(tx)
+	if maxFee.Cmp(fee) < 0 {
+		return nil, newRPCError(starknetrpc.ErrInsufficientMaxFee, nil)
+	}
+	if s.feeTokens[tx.unit()].balance(tx.SenderAddress).Cmp(maxFee) < 0 {
+		return nil, newRPCError(starknetrpc.ErrInsufficientAccountBalance, nil)
+	}
+	hash := tx.hash()
+	if _, ok := s.txs[hash.String()]; ok {
+		return nil, newRPCError(starknetrpc.ErrDuplicateTx, nil)
+	}
+
+	s.pending = append(s.pending, &transaction{
+		hash:   hash,
+		raw:    raw,
+		invoke: tx,
+		fee:    fee,
+		unit:   tx.unit(),
+		status: starknetrpc.TxnStatus_Received,
+	})
+	s.txs[hash.String()] = s.pending[len(s.pending)-1]
+	if s.autoMine {
+		s.mine()
+	}
+	return map[string]any{"transaction_hash": hash}, nil
+}
+
+func (s *Server) txParam(params []json.RawMessage) (*transaction, *rpcError) {
+	var hash felt.Felt
+	if rpcErr := param(params, 0, &hash); rpcErr != nil {
+		return nil, rpcErr
+	}
+	tx, ok := s.txs[hash.String()]
+	if !ok {
+		return nil, newRPCError(starknetrpc.ErrHashNotFound, nil)
+	}
+	return tx, nil
+}
+
+func (s *Server) getTransactionStatus(params []json.RawMessage) (any, *rpcError) {
+	tx, rpcErr := s.txParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return s.txStatus(tx), nil
+}
+
+func (s *Server) getTransactionReceipt(params []json.RawMessage) (any, *rpcError) {
+	tx, rpcErr := s.txParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if tx.block == nil || tx.status == starknetrpc.TxnStatus_Rejected {
+		// received and rejected txs have no receipt
+		return nil, newRPCError(starknetrpc.ErrHashNotFound, nil)
+	}
+	status := s.txStatus(tx)
+	events := tx.events
+	if events == nil {
+		events = []starknetrpc.Event{}
+	}
+	receipt := map[string]any{
+		"type":             starknetrpc.TransactionType_Invoke,
+		"transaction_hash": tx.hash,
+		"actual_fee":       starknetrpc.FeePayment{Amount: starknetutils.BigIntToFelt(tx.fee), Unit: tx.unit},
+		"execution_status": status.ExecutionStatus,
+		"finality_status":  status.FinalityStatus,
+		"block_hash":       tx.block.hash,
+		"block_number":     tx.block.number,
+		"messages_sent":    []any{},
+		"events":           events,
+		"execution_resources": starknetrpc.ExecutionResources{
+			ComputationResources: starknetrpc.ComputationResources{Steps: 1},
+		},
+	}
+	if tx.reverts {
+		receipt["revert_reason"] = tx.reason
+	}
+	return receipt, nil
+}
+
+func (s *Server) getTransactionByHash(params []json.RawMessage) (any, *rpcError) {
+	tx, rpcErr := s.txParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return tx.withHash(), nil
+}
+
+func (tx *transaction) withHash() map[string]any {
+	result := map[string]any{"transaction_hash": tx.hash}
+	for k, v := range tx.raw {
+		result[k] = v
+	}
+	return result
+}
+
+// getEvents returns the events of successful txs in the range of blocks, the continuation token is the offset of
+// the next page.
+func (s *Server) getEvents(params []json.RawMessage) (any, *rpcError) {
+	var filter struct {
+		FromBlock         json.RawMessage `json:"from_block"`
+		ToBlock           json.RawMessage `json:"to_block"`
+		Address           *felt.Felt      `json:"address"`
+		Keys              [][]*felt.Felt  `json:"keys"`
+		ContinuationToken string          `json:"continuation_token"`
+		ChunkSize         int             `json:"chunk_size"`
+	}
+	if rpcErr := param(params, 0, &filter); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if filter.ChunkSize <= 0 {
+		return nil, invalidParams(errors.New("chunk_size must be positive"))
+	}
+	from, to := uint64(0), s.latest().number
+	if filter.FromBlock != nil {
+		b, _, rpcErr := s.blockParam([]json.RawMessage{filter.FromBlock}, 0)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		from = b.number
+	}
+	if filter.ToBlock != nil {
+		b, _, rpcErr := s.blockParam([]json.RawMessage{filter.ToBlock}, 0)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		to = b.number
+	}
+	offset := 0
+	if filter.ContinuationToken != "" {
+		var err error
+		if offset, err = strconv.Atoi(filter.ContinuationToken); err != nil || offset < 0 {
+			return nil, newRPCError(starknetrpc.ErrInvalidContinuationToken, nil)
+		}
+	}
+
+	var matched []starknetrpc.EmittedEvent
+	for n := from; n <= to && n < uint64(len(s.blocks)); n++ {
+		b := s.blocks[n]
+		for _, tx := range b.txs {
+			for _, event := range tx.events {
+				if matchEvent(event, filter.Address, filter.Keys) {
+					matched = append(matched, starknetrpc.EmittedEvent{Event: event, BlockHash: b.hash, BlockNumber: b.number, TransactionHash: tx.hash})
+				}
+			}
+		}
+	}
+
+	chunk := starknetrpc.EventChunk{Events: []starknetrpc.EmittedEvent{}}
+	if offset < len(matched) {
+		end := min(offset+filter.ChunkSize, len(matched))
+		chunk.Events = matched[offset:end]
+		if end < len(matched) {
+			chunk.ContinuationToken = strconv.Itoa(end)
+		}
+	}
+	return chunk, nil
+}
+
+// matchEvent applies an event filter, every position of keys matches any of its values, or any key if empty.
+func matchEvent(event starknetrpc.Event, address *felt.Felt, keys [][]*felt.Felt) bool {
+	if address != nil && !address.Equal(event.FromAddress) {
+		return false
+	}
+	for i, values := range keys {
+		if len(values) == 0 {
+			continue
+		}
+		if i >= len(event.Keys) {
+			return false
+		}
+		found := false
+		for _, v := range values {
+			if v.Equal(event.Keys[i]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *rpcError) dataString() string {
+	if s, ok := e.Data.(string); ok {
+		return s
+	}
+	return e.Message
+}
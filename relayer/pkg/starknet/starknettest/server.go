@@ -0,0 +1,408 @@
+// Package starknettest provides an in-process Starknet JSON-RPC node for hermetic tests.
+package starknettest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/crypto"
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+)
+
+// Well-known fee token addresses, an ERC20 is deployed at each of them by NewServer.
+var (
+	STRKFeeToken = mustHexToFelt("0x04718f5a0fc34cc1af16a1cdee98ffb20c31f5cd61d6ab07201858f4287c938d")
+	ETHFeeToken  = mustHexToFelt("0x049d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7")
+)
+
+// AccountClassHash is the class hash of the accounts added with AddAccount.
+var AccountClassHash = new(felt.Felt).SetBytes([]byte("starknettest account"))
+
+// contractClassHash is the class hash of the contracts deployed with Deploy.
+var contractClassHash = new(felt.Felt).SetBytes([]byte("starknettest contract"))
+
+const (
+	// DefaultGasConsumed is the L1 gas consumed by every invoke.
+	DefaultGasConsumed = 1000
+	// SpecVersion is the JSON-RPC spec version reported by the server.
+	SpecVersion = "0.7.1"
+)
+
+// Server is a stand-in for a Starknet node that serves the JSON-RPC methods used by the relayer. It keeps a chain
+// of blocks, the nonces of accounts, and contracts implemented in Go (see Contract). Invokes are checked for their
+// nonce, max fee and fee token balance but not for their signature, and are executed when they are mined:
+// immediately by default, or by Mine once SetAutoMine(false) was called.
+type Server struct {
+	// URL of the JSON-RPC endpoint
+	URL    string
+	server *httptest.Server
+
+	lock        sync.Mutex
+	chainID     string
+	autoMine    bool
+	gasConsumed uint64
+	gasPriceFRI *big.Int
+	gasPriceWei *big.Int
+	blocks      []*block
+	// l1Blocks is the number of blocks that are accepted on L1
+	l1Blocks  int
+	pending   []*transaction
+	txs       map[string]*transaction
+	accounts  map[string]*felt.Felt // nonces
+	contracts map[string]Contract
+	feeTokens map[starknetrpc.FeePaymentUnit]*ERC20
+	requests  map[string]int
+}
+
+type block struct {
+	number     uint64
+	hash       *felt.Felt
+	parentHash *felt.Felt
+	timestamp  uint64
+	// gas prices of the block in FRI and WEI
+	gasPriceFRI *big.Int
+	gasPriceWei *big.Int
+	txs         []*transaction
+}
+
+type transaction struct {
+	hash    *felt.Felt
+	raw     map[string]any
+	invoke  invokeTxn
+	fee     *big.Int
+	unit    starknetrpc.FeePaymentUnit
+	status  starknetrpc.TxnStatus
+	reason  string
+	events  []starknetrpc.Event
+	block   *block
+	reverts bool
+}
+
+// NewServer starts a server for chainID with a genesis block, which is closed with the test.
+func NewServer(t testing.TB, chainID string) *Server {
+	s := &Server{
+		chainID:     chainID,
+		autoMine:    true,
+		gasConsumed: DefaultGasConsumed,
+		gasPriceFRI: big.NewInt(100),
+		gasPriceWei: big.NewInt(10),
+		txs:         map[string]*transaction{},
+		accounts:    map[string]*felt.Felt{},
+		contracts:   map[string]Contract{},
+		requests:    map[string]int{},
+	}
+	s.feeTokens = map[starknetrpc.FeePaymentUnit]*ERC20{
+		starknetrpc.UnitStrk: s.DeployERC20(STRKFeeToken),
+		starknetrpc.UnitWei:  s.DeployERC20(ETHFeeToken),
+	}
+	s.mine()
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// SetAutoMine sets whether invokes are mined in a block of their own as soon as they are received. Otherwise they
+// stay RECEIVED until the next call of Mine.
+func (s *Server) SetAutoMine(autoMine bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.autoMine = autoMine
+}
+
+// SetGasPrice sets the L1 gas prices of the next blocks.
+func (s *Server) SetGasPrice(fri, wei *big.Int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gasPriceFRI, s.gasPriceWei = fri, wei
+}
+
+// SetGasConsumed sets the L1 gas consumed by every invoke.
+func (s *Server) SetGasConsumed(gas uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gasConsumed = gas
+}
+
+// AddAccount adds an account with nonce 0, see ERC20.Mint to fund it.
+func (s *Server) AddAccount(address *felt.Felt) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.accounts[address.String()] = new(felt.Felt)
+}
+
+// Nonce returns the nonce of an account, excluding pending txs.
+func (s *Server) Nonce(address *felt.Felt) *felt.Felt {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if nonce, ok := s.accounts[address.String()]; ok {
+		return new(felt.Felt).Set(nonce)
+	}
+	return nil
+}
+
+// Deploy deploys a contract at address.
+func (s *Server) Deploy(address *felt.Felt, contract Contract) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.contracts[address.String()] = contract
+}
+
+// FeeToken returns the ERC20 that pays fees in unit.
+func (s *Server) FeeToken(unit starknetrpc.FeePaymentUnit) *ERC20 {
+	return s.feeTokens[unit]
+}
+
+// Mine seals the pending txs into a new block and returns its number.
+func (s *Server) Mine() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.mine().number
+}
+
+// AcceptOnL1 moves all blocks mined so far to ACCEPTED_ON_L1.
+func (s *Server) AcceptOnL1() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.l1Blocks = len(s.blocks)
+}
+
+// LatestBlock returns the number of the latest block.
+func (s *Server) LatestBlock() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.latest().number
+}
+
+// Requests returns the number of requests of a JSON-RPC method that the server received, batched requests are
+// counted individually.
+func (s *Server) Requests(method string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[method]
+}
+
+// TxStatus returns the finality and execution status of a tx, and the revert reason of a reverted tx.
+func (s *Server) TxStatus(hash *felt.Felt) (status starknetrpc.TxnStatusResp, revertReason string, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tx, ok := s.txs[hash.String()]
+	if !ok {
+		return status, "", false
+	}
+	return s.txStatus(tx), tx.reason, true
+}
+
+// Transaction returns the fields of a tx as they were received.
+func (s *Server) Transaction(hash *felt.Felt) (map[string]any, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tx, ok := s.txs[hash.String()]
+	if !ok {
+		return nil, false
+	}
+	return tx.raw, true
+}
+
+func (s *Server) latest() *block {
+	return s.blocks[len(s.blocks)-1]
+}
+
+// mine executes the pending txs in a new block.
+func (s *Server) mine() *block {
+	b := &block{
+		number:      uint64(len(s.blocks)),
+		parentHash:  new(felt.Felt),
+		timestamp:   uint64(time.Now().Unix()),
+		gasPriceFRI: s.gasPriceFRI,
+		gasPriceWei: s.gasPriceWei,
+	}
+	if len(s.blocks) > 0 {
+		parent := s.latest()
+		b.parentHash = parent.hash
+		b.timestamp = max(b.timestamp, parent.timestamp+1)
+	}
+	b.hash = crypto.PoseidonArray(new(felt.Felt).SetUint64(b.number), b.parentHash, new(felt.Felt).SetUint64(b.timestamp))
+	s.blocks = append(s.blocks, b)
+
+	pending := s.pending
+	s.pending = nil
+	for _, tx := range pending {
+		tx.block = b
+		b.txs = append(b.txs, tx)
+		s.execute(b, tx)
+	}
+	return b
+}
+
+// execute runs an invoke in block b: the nonce of its sender is incremented and the fee is charged, even if one of
+// its calls reverts. The state changes of a reverted invoke are rolled back, see Snapshotter.
+func (s *Server) execute(b *block, tx *transaction) {
+	sender := tx.invoke.SenderAddress
+	nonce := s.accounts[sender.String()]
+	if !nonce.Equal(tx.invoke.Nonce) {
+		tx.status = starknetrpc.TxnStatus_Rejected
+		tx.reason = fmt.Sprintf("invalid nonce %s, expected %s", tx.invoke.Nonce, nonce)
+		return
+	}
+	nonce.Add(nonce, new(felt.Felt).SetUint64(1))
+	tx.status = starknetrpc.TxnStatus_Accepted_On_L2
+	s.feeTokens[tx.unit].charge(sender, tx.fee)
+
+	calls, err := decodeMulticall(tx.invoke.Calldata)
+	if err != nil {
+		tx.reverts, tx.reason = true, err.Error()
+		return
+	}
+	restore := s.snapshot()
+	exec := &Execution{Caller: sender, BlockNumber: b.number, BlockTimestamp: b.timestamp}
+	for _, call := range calls {
+		if _, err := s.call(exec, call); err != nil {
+			restore()
+			tx.reverts, tx.reason = true, err.Error()
+			return
+		}
+	}
+	tx.events = exec.events
+}
+
+// snapshot saves the state of the contracts that implement Snapshotter, and returns a function that restores it.
+func (s *Server) snapshot() (restore func()) {
+	var restores []func()
+	for _, contract := range s.contracts {
+		if snapshotter, ok := contract.(Snapshotter); ok {
+			restores = append(restores, snapshotter.Snapshot())
+		}
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
+
+func (s *Server) call(exec *Execution, call starknetrpc.FunctionCall) ([]*felt.Felt, error) {
+	contract, ok := s.contracts[call.ContractAddress.String()]
+	if !ok {
+		return nil, fmt.Errorf("contract %s is not deployed", call.ContractAddress)
+	}
+	exec.address = call.ContractAddress
+	return contract.Call(exec, call.EntryPointSelector, call.Calldata)
+}
+
+func (s *Server) txStatus(tx *transaction) starknetrpc.TxnStatusResp {
+	status := starknetrpc.TxnStatusResp{FinalityStatus: tx.status}
+	if tx.block == nil || tx.status == starknetrpc.TxnStatus_Rejected {
+		return status
+	}
+	if tx.block.number < uint64(s.l1Blocks) {
+		status.FinalityStatus = starknetrpc.TxnStatus_Accepted_On_L1
+	}
+	status.ExecutionStatus = starknetrpc.TxnExecutionStatusSUCCEEDED
+	if tx.reverts {
+		status.ExecutionStatus = starknetrpc.TxnExecutionStatusREVERTED
+	}
+	return status
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// newRPCError returns the error of the Starknet spec, with optional data.
+func newRPCError(err *starknetrpc.RPCError, data any) *rpcError {
+	return &rpcError{Code: err.Code, Message: err.Message, Data: data}
+}
+
+func invalidParams(err error) *rpcError {
+	return &rpcError{Code: starknetrpc.InvalidParams, Message: "Invalid params", Data: err.Error()}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result any
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []rpcRequest
+		if err = json.Unmarshal(body, &reqs); err == nil {
+			resps := make([]rpcResponse, len(reqs))
+			for i, req := range reqs {
+				resps[i] = s.handle(req)
+			}
+			result = resps
+		}
+	} else {
+		var req rpcRequest
+		if err = json.Unmarshal(body, &req); err == nil {
+			result = s.handle(req)
+		}
+	}
+	if err != nil {
+		result = rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: starknetrpc.InvalidJSON, Message: "Parse error", Data: err.Error()}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handle(req rpcRequest) rpcResponse {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.requests[req.Method]++
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	handler, ok := handlers[req.Method]
+	if !ok {
+		resp.Error = &rpcError{Code: starknetrpc.MethodNotFound, Message: "Method not found", Data: req.Method}
+		return resp
+	}
+	result, err := handler(s, req.Params)
+	if err != nil {
+		resp.Error = err
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func mustHexToFelt(hex string) *felt.Felt {
+	f, err := starknetutils.HexToFelt(hex)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,214 @@
+package starknettest
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	starknetutils "github.com/NethermindEth/starknet.go/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+
+	"github.com/smartcontractkit/chainlink-starknet/relayer/pkg/starknet"
+)
+
+// transferInvoke is a V3 invoke of a STRK transfer with the Cairo 1 multicall layout.
+func transferInvoke(sender, recipient *felt.Felt, nonce uint64, amount uint64, maxAmount string) starknetrpc.InvokeTxnV3 {
+	return starknetrpc.InvokeTxnV3{
+		Type:          starknetrpc.TransactionType_Invoke,
+		SenderAddress: sender,
+		Version:       starknetrpc.TransactionV3,
+		Signature:     []*felt.Felt{},
+		Nonce:         new(felt.Felt).SetUint64(nonce),
+		Calldata: []*felt.Felt{
+			new(felt.Felt).SetUint64(1),
+			STRKFeeToken,
+			starknetutils.GetSelectorFromNameFelt("transfer"),
+			new(felt.Felt).SetUint64(3),
+			recipient,
+			new(felt.Felt).SetUint64(amount),
+			new(felt.Felt),
+		},
+		ResourceBounds: starknetrpc.ResourceBoundsMapping{
+			L1Gas: starknetrpc.ResourceBounds{MaxAmount: starknetrpc.U64(maxAmount), MaxPricePerUnit: "0x64"},
+			L2Gas: starknetrpc.ResourceBounds{MaxAmount: "0x0", MaxPricePerUnit: "0x0"},
+		},
+		Tip:                   "0x0",
+		PayMasterData:         []*felt.Felt{},
+		AccountDeploymentData: []*felt.Felt{},
+		NonceDataMode:         starknetrpc.DAModeL1,
+		FeeMode:               starknetrpc.DAModeL1,
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer(t, "SN_SEPOLIA")
+	client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+	require.NoError(t, err)
+	ctx := tests.Context(t)
+
+	account := new(felt.Felt).SetUint64(1)
+	recipient := new(felt.Felt).SetUint64(2)
+	server.AddAccount(account)
+	server.FeeToken(starknetrpc.UnitStrk).Mint(account, big.NewInt(1_000_000))
+
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0x534e5f5345504f4c4941", chainID) // hex encoded like on a real node
+
+	t.Run("invoke", func(t *testing.T) {
+		resp, err := client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, 0, 500, "0x3e8"))
+		require.NoError(t, err)
+
+		status, err := client.Provider.GetTransactionStatus(ctx, resp.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L2, status.FinalityStatus)
+		assert.Equal(t, starknetrpc.TxnExecutionStatusSUCCEEDED, status.ExecutionStatus)
+
+		nonce, err := client.AccountNonce(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, new(felt.Felt).SetUint64(1), nonce)
+
+		// the fee is gas consumed * gas price, on top of the transfer
+		assert.Equal(t, big.NewInt(1_000_000-500-DefaultGasConsumed*100), server.FeeToken(starknetrpc.UnitStrk).Balance(account))
+		balance, err := client.CallContract(ctx, starknet.CallOps{
+			ContractAddress: STRKFeeToken,
+			Selector:        starknetutils.GetSelectorFromNameFelt("balance_of"),
+			Calldata:        []*felt.Felt{recipient},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(500), new(felt.Felt)}, balance)
+
+		receipt, err := client.TransactionReceipt(ctx, resp.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnExecutionStatusSUCCEEDED, receipt.GetExecutionStatus())
+
+		events, err := client.Events(ctx, starknetrpc.EventsInput{
+			EventFilter:       starknetrpc.EventFilter{FromBlock: starknetrpc.WithBlockNumber(0), ToBlock: starknetrpc.WithBlockTag("latest"), Address: STRKFeeToken},
+			ResultPageRequest: starknetrpc.ResultPageRequest{ChunkSize: 10},
+		})
+		require.NoError(t, err)
+		require.Len(t, events.Events, 1)
+		assert.Equal(t, resp.TransactionHash, events.Events[0].TransactionHash)
+		assert.Equal(t, []*felt.Felt{account, recipient, new(felt.Felt).SetUint64(500), new(felt.Felt)}, events.Events[0].Data)
+
+		server.AcceptOnL1()
+		status, err = client.Provider.GetTransactionStatus(ctx, resp.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L1, status.FinalityStatus)
+
+		// a reverted transfer still pays its fee
+		resp, err = client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, 1, 10_000_000, "0x3e8"))
+		require.NoError(t, err)
+		status, err = client.Provider.GetTransactionStatus(ctx, resp.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnExecutionStatusREVERTED, status.ExecutionStatus)
+		assert.Equal(t, big.NewInt(1_000_000-500-2*DefaultGasConsumed*100), server.FeeToken(starknetrpc.UnitStrk).Balance(account))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, 0, 1, "0x3e8"))
+		require.ErrorContains(t, err, starknetrpc.ErrInvalidTransactionNonce.Message)
+
+		nonce := server.Nonce(account).BigInt(new(big.Int)).Uint64()
+		_, err = client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, nonce, 1, "0x1"))
+		require.ErrorContains(t, err, starknetrpc.ErrInsufficientMaxFee.Message)
+
+		_, err = client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, nonce, 1, "0xffffffff"))
+		require.ErrorContains(t, err, starknetrpc.ErrInsufficientAccountBalance.Message)
+
+		_, err = client.Provider.GetTransactionStatus(ctx, new(felt.Felt).SetUint64(42))
+		require.ErrorContains(t, err, starknetrpc.ErrHashNotFound.Message)
+
+		_, err = client.AccountNonce(ctx, new(felt.Felt).SetUint64(42))
+		require.ErrorContains(t, err, starknetrpc.ErrContractNotFound.Message)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		server.SetAutoMine(false)
+		defer server.SetAutoMine(true)
+
+		nonce := server.Nonce(account).BigInt(new(big.Int)).Uint64()
+		resp, err := client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, nonce, 1, "0x3e8"))
+		require.NoError(t, err)
+		status, err := client.Provider.GetTransactionStatus(ctx, resp.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnStatus_Received, status.FinalityStatus)
+
+		// the pending nonce accounts for the received tx
+		pendingNonce, err := client.AccountNonce(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, nonce+1, pendingNonce.BigInt(new(big.Int)).Uint64())
+		assert.Equal(t, nonce, server.Nonce(account).BigInt(new(big.Int)).Uint64())
+
+		// a fee bump reuses the nonce of the pending tx, the first tx mined rejects the other
+		bumped, err := client.Provider.AddInvokeTransaction(ctx, transferInvoke(account, recipient, nonce, 1, "0x7d0"))
+		require.NoError(t, err)
+		pendingNonce, err = client.AccountNonce(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, nonce+1, pendingNonce.BigInt(new(big.Int)).Uint64())
+
+		block := server.Mine()
+		status, err = client.Provider.GetTransactionStatus(ctx, resp.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L2, status.FinalityStatus)
+		status, err = client.Provider.GetTransactionStatus(ctx, bumped.TransactionHash)
+		require.NoError(t, err)
+		assert.Equal(t, starknetrpc.TxnStatus_Rejected, status.FinalityStatus)
+		latest, err := client.LatestBlockHeight(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, block, latest)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		requests := server.Requests("starknet_getTransactionStatus")
+		_, err := client.Batch(ctx, starknet.NewBatchBuilder().RequestTxReceiptByHash(new(felt.Felt).SetUint64(42)))
+		require.NoError(t, err)
+		assert.Equal(t, requests, server.Requests("starknet_getTransactionStatus"))
+		assert.Positive(t, server.Requests("starknet_getTransactionReceipt"))
+	})
+}
+
+func TestServer_Aggregator(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer(t, "SN_SEPOLIA")
+	client, err := starknet.NewClient("SN_SEPOLIA", server.URL, "", logger.Test(t), nil)
+	require.NoError(t, err)
+	ctx := tests.Context(t)
+
+	address := new(felt.Felt).SetUint64(100)
+	aggregator := server.DeployAggregator(address)
+	digest := [32]byte{0, 4, 1}
+	require.NoError(t, aggregator.SetConfig(AggregatorConfig{
+		ConfigDigest:  digest,
+		Signers:       [][]byte{{1}},
+		Transmitters:  []*felt.Felt{new(felt.Felt).SetUint64(2)},
+		F:             1,
+		OnchainConfig: []*felt.Felt{new(felt.Felt).SetUint64(1), new(felt.Felt), new(felt.Felt).SetUint64(1000)},
+	}))
+
+	details, err := client.CallContract(ctx, starknet.CallOps{ContractAddress: address, Selector: starknetutils.GetSelectorFromNameFelt("latest_config_details")})
+	require.NoError(t, err)
+	require.Len(t, details, 3)
+	assert.Equal(t, new(felt.Felt).SetUint64(1), details[0])
+	assert.Equal(t, new(felt.Felt).SetBytes(digest[:]), details[2])
+
+	events, err := client.Events(ctx, starknetrpc.EventsInput{
+		EventFilter: starknetrpc.EventFilter{
+			FromBlock: starknetrpc.WithBlockNumber(details[1].BigInt(new(big.Int)).Uint64()),
+			ToBlock:   starknetrpc.WithBlockNumber(details[1].BigInt(new(big.Int)).Uint64()),
+			Address:   address,
+			Keys:      [][]*felt.Felt{{ConfigSetEventKey}},
+		},
+		ResultPageRequest: starknetrpc.ResultPageRequest{ChunkSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+}
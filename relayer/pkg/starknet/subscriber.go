@@ -0,0 +1,679 @@
+package starknet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/gorilla/websocket"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils"
+)
+
+// subscriptionBuffer is the capacity of the channel of a Subscription, deliveries block while it is full. The
+// notifications received meanwhile are queued for the subscription up to the same number, further ones are dropped.
+const subscriptionBuffer = 100
+
+// TxStatus is a status update of a tx, see Subscriber.SubscribeTransactionStatus.
+type TxStatus struct {
+	TransactionHash *felt.Felt
+	starknetrpc.TxnStatusResp
+	FailureReason string
+}
+
+// Subscription delivers the notifications of a subscription on C until it is unsubscribed or its Subscriber is
+// closed. C is not closed.
+type Subscription[T any] struct {
+	C <-chan T
+
+	subscriber *Subscriber
+	sub        *subscription
+}
+
+// Unsubscribe stops the deliveries of the subscription.
+func (s *Subscription[T]) Unsubscribe() {
+	s.subscriber.unsubscribe(s.sub)
+}
+
+// subscription is the state of a subscription that is independent of its notification type.
+type subscription struct {
+	method string
+	// params returns the params of the subscribe request, which resume the subscription after a reconnect
+	params func() map[string]any
+	// notify decodes and delivers the result of a notification
+	notify func(ctx context.Context, result json.RawMessage) error
+	// poll delivers the notifications since the previous poll when the node has no WebSocket endpoint
+	poll func(ctx context.Context) error
+	// queue holds the results received by the read loop until they are passed to notify, see dispatch
+	queue chan json.RawMessage
+	done  chan struct{}
+	once  sync.Once
+
+	// guarded by the Subscriber lock: the connection the subscription was requested on, and its id there
+	conn *wsConn
+	id   string
+}
+
+func newSubscription(method string) *subscription {
+	return &subscription{method: method, queue: make(chan json.RawMessage, subscriptionBuffer), done: make(chan struct{})}
+}
+
+// Subscriber subscribes to new heads, events and tx statuses over the WebSocket API of a node. Subscriptions are
+// requested again after a reconnect. When the node has no WebSocket endpoint, or wsURL is empty, the notifications
+// are polled from client instead.
+type Subscriber struct {
+	starter      utils.StartStopOnce
+	lggr         logger.Logger
+	wsURL        string
+	header       http.Header
+	client       *Client
+	pollInterval time.Duration
+	backoff      Backoff
+	minWait      time.Duration
+	maxWait      time.Duration
+	done         sync.WaitGroup
+	stop         chan struct{}
+
+	lock    sync.Mutex
+	subs    map[*subscription]struct{}
+	conn    *wsConn
+	polling bool
+}
+
+func NewSubscriber(lggr logger.Logger, wsURL string, apiKey string, client *Client, pollInterval time.Duration) *Subscriber {
+	header := http.Header{}
+	if strings.TrimSpace(apiKey) != "" {
+		header.Set("x-apikey", apiKey)
+	}
+	return &Subscriber{
+		lggr:         logger.Named(lggr, "Subscriber"),
+		wsURL:        wsURL,
+		header:       header,
+		client:       client,
+		pollInterval: pollInterval,
+		backoff:      ExponentialBackoff,
+		minWait:      time.Second,
+		maxWait:      30 * time.Second,
+		stop:         make(chan struct{}),
+		subs:         map[*subscription]struct{}{},
+	}
+}
+
+func (s *Subscriber) Start(context.Context) error {
+	return s.starter.StartOnce("Subscriber", func() error {
+		s.done.Add(1)
+		go s.run()
+		return nil
+	})
+}
+
+func (s *Subscriber) Close() error {
+	return s.starter.StopOnce("Subscriber", func() error {
+		close(s.stop)
+		s.lock.Lock()
+		if s.conn != nil {
+			s.conn.close()
+		}
+		s.lock.Unlock()
+		s.done.Wait()
+		return nil
+	})
+}
+
+// SubscribeNewHeads delivers the header of every new block, without gaps across reconnects.
+func (s *Subscriber) SubscribeNewHeads(ctx context.Context) (*Subscription[starknetrpc.BlockHeader], error) {
+	ch := make(chan starknetrpc.BlockHeader, subscriptionBuffer)
+	var next atomic.Uint64 // the next block number + 1, 0 until a head is delivered
+	sub := newSubscription("starknet_subscribeNewHeads")
+	deliverHead := func(ctx context.Context, head starknetrpc.BlockHeader) error {
+		if err := deliver(ctx, sub, ch, head); err != nil {
+			return err
+		}
+		next.Store(head.BlockNumber + 2)
+		return nil
+	}
+	sub.params = func() map[string]any {
+		if n := next.Load(); n > 0 {
+			return map[string]any{"block_id": starknetrpc.WithBlockNumber(n - 1)}
+		}
+		return map[string]any{}
+	}
+	sub.notify = func(ctx context.Context, result json.RawMessage) error {
+		var head starknetrpc.BlockHeader
+		if err := json.Unmarshal(result, &head); err != nil {
+			return fmt.Errorf("invalid head: %w", err)
+		}
+		return deliverHead(ctx, head)
+	}
+	sub.poll = func(ctx context.Context) error {
+		latest, err := s.client.LatestBlockHeight(ctx)
+		if err != nil {
+			return err
+		}
+		from := latest
+		if n := next.Load(); n > 0 {
+			from = n - 1
+		}
+		for number := from; number <= latest; number++ {
+			out, err := s.client.Provider.BlockWithTxHashes(ctx, starknetrpc.WithBlockNumber(number))
+			if err != nil {
+				return err
+			}
+			block, ok := out.(*starknetrpc.BlockTxHashes)
+			if !ok {
+				return fmt.Errorf("unexpected block type: %T", out)
+			}
+			if err = deliverHead(ctx, block.BlockHeader); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := s.subscribe(ctx, sub); err != nil {
+		return nil, err
+	}
+	return &Subscription[starknetrpc.BlockHeader]{C: ch, subscriber: s, sub: sub}, nil
+}
+
+// SubscribeEvents delivers the events emitted by fromAddress, if set, that match keys, see
+// [starknetrpc.EventFilter]. After a reconnect, the events of the block of the last delivered event are delivered
+// again.
+func (s *Subscriber) SubscribeEvents(ctx context.Context, fromAddress *felt.Felt, keys [][]*felt.Felt) (*Subscription[starknetrpc.EmittedEvent], error) {
+	ch := make(chan starknetrpc.EmittedEvent, subscriptionBuffer)
+	var from atomic.Uint64 // the block to resume from + 1, 0 until an event is delivered or polled
+	sub := newSubscription("starknet_subscribeEvents")
+	sub.params = func() map[string]any {
+		params := map[string]any{}
+		if fromAddress != nil {
+			params["from_address"] = fromAddress
+		}
+		if len(keys) > 0 {
+			params["keys"] = keys
+		}
+		if n := from.Load(); n > 0 {
+			params["block_id"] = starknetrpc.WithBlockNumber(n - 1)
+		}
+		return params
+	}
+	sub.notify = func(ctx context.Context, result json.RawMessage) error {
+		var event starknetrpc.EmittedEvent
+		if err := json.Unmarshal(result, &event); err != nil {
+			return fmt.Errorf("invalid event: %w", err)
+		}
+		if err := deliver(ctx, sub, ch, event); err != nil {
+			return err
+		}
+		from.Store(event.BlockNumber + 1)
+		return nil
+	}
+	sub.poll = func(ctx context.Context) error {
+		latest, err := s.client.LatestBlockHeight(ctx)
+		if err != nil {
+			return err
+		}
+		start := latest
+		if n := from.Load(); n > 0 {
+			start = n - 1
+		}
+		if start > latest {
+			return nil
+		}
+		input := starknetrpc.EventsInput{
+			EventFilter:       starknetrpc.EventFilter{FromBlock: starknetrpc.WithBlockNumber(start), ToBlock: starknetrpc.WithBlockNumber(latest), Address: fromAddress, Keys: keys},
+			ResultPageRequest: starknetrpc.ResultPageRequest{ChunkSize: 100},
+		}
+		for {
+			chunk, err := s.client.Events(ctx, input)
+			if err != nil {
+				return err
+			}
+			for _, event := range chunk.Events {
+				if err = deliver(ctx, sub, ch, event); err != nil {
+					return err
+				}
+			}
+			if chunk.ContinuationToken == "" {
+				break
+			}
+			input.ContinuationToken = chunk.ContinuationToken
+		}
+		from.Store(latest + 2)
+		return nil
+	}
+	if err := s.subscribe(ctx, sub); err != nil {
+		return nil, err
+	}
+	return &Subscription[starknetrpc.EmittedEvent]{C: ch, subscriber: s, sub: sub}, nil
+}
+
+// SubscribeTransactionStatus delivers the status of the tx with hash whenever it changes.
+func (s *Subscriber) SubscribeTransactionStatus(ctx context.Context, hash *felt.Felt) (*Subscription[TxStatus], error) {
+	ch := make(chan TxStatus, subscriptionBuffer)
+	var lock sync.Mutex
+	var last TxStatus
+	deliverStatus := func(ctx context.Context, sub *subscription, status TxStatus) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if status.TxnStatusResp == last.TxnStatusResp && status.FailureReason == last.FailureReason {
+			return nil
+		}
+		if err := deliver(ctx, sub, ch, status); err != nil {
+			return err
+		}
+		last = status
+		return nil
+	}
+	sub := newSubscription("starknet_subscribeTransactionStatus")
+	sub.params = func() map[string]any {
+		return map[string]any{"transaction_hash": hash}
+	}
+	sub.notify = func(ctx context.Context, result json.RawMessage) error {
+		var notification struct {
+			TransactionHash *felt.Felt `json:"transaction_hash"`
+			Status          struct {
+				starknetrpc.TxnStatusResp
+				FailureReason string `json:"failure_reason,omitempty"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(result, &notification); err != nil {
+			return fmt.Errorf("invalid tx status: %w", err)
+		}
+		return deliverStatus(ctx, sub, TxStatus{TransactionHash: hash, TxnStatusResp: notification.Status.TxnStatusResp, FailureReason: notification.Status.FailureReason})
+	}
+	sub.poll = func(ctx context.Context) error {
+		status, err := s.client.Provider.GetTransactionStatus(ctx, hash)
+		var rpcErr *starknetrpc.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == starknetrpc.ErrHashNotFound.Code {
+			// not received yet
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return deliverStatus(ctx, sub, TxStatus{TransactionHash: hash, TxnStatusResp: *status})
+	}
+	if err := s.subscribe(ctx, sub); err != nil {
+		return nil, err
+	}
+	return &Subscription[TxStatus]{C: ch, subscriber: s, sub: sub}, nil
+}
+
+// deliver sends v on ch, it blocks until ch has room, the subscription is unsubscribed or ctx is done.
+func deliver[T any](ctx context.Context, sub *subscription, ch chan<- T, v T) error {
+	select {
+	case ch <- v:
+		return nil
+	case <-sub.done:
+		return errors.New("unsubscribed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// subscribe registers sub and requests it on the current connection, or starts polling it. While disconnected,
+// sub is requested once connected.
+func (s *Subscriber) subscribe(ctx context.Context, sub *subscription) error {
+	s.lock.Lock()
+	s.subs[sub] = struct{}{}
+	conn := s.conn
+	if s.polling {
+		s.done.Add(1)
+		go s.pollLoop(sub)
+	} else {
+		s.done.Add(1)
+		go s.dispatch(sub)
+	}
+	s.lock.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	if err := s.request(ctx, conn, sub); err != nil {
+		s.unsubscribe(sub)
+		return err
+	}
+	return nil
+}
+
+// request requests sub on conn, unless it already was.
+func (s *Subscriber) request(ctx context.Context, conn *wsConn, sub *subscription) error {
+	s.lock.Lock()
+	if sub.conn == conn {
+		s.lock.Unlock()
+		return nil
+	}
+	sub.conn, sub.id = conn, ""
+	s.lock.Unlock()
+
+	// the id is set by the read loop, before the notifications that follow the response are dispatched
+	_, err := conn.call(ctx, sub.method, sub.params(), func(result json.RawMessage) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if sub.conn == conn {
+			sub.id = string(result)
+		}
+	})
+	if err != nil {
+		s.lock.Lock()
+		if sub.conn == conn {
+			sub.conn = nil
+		}
+		s.lock.Unlock()
+		return fmt.Errorf("failed to %s: %w", sub.method, err)
+	}
+	return nil
+}
+
+func (s *Subscriber) unsubscribe(sub *subscription) {
+	sub.once.Do(func() { close(sub.done) })
+	s.lock.Lock()
+	delete(s.subs, sub)
+	conn, id := sub.conn, sub.id
+	sub.conn, sub.id = nil, ""
+	s.lock.Unlock()
+
+	if conn != nil && id != "" {
+		ctx, cancel := utils.ContextFromChan(s.stop)
+		defer cancel()
+		ctx, cancel = context.WithTimeout(ctx, s.minWait)
+		defer cancel()
+		if _, err := conn.call(ctx, "starknet_unsubscribe", map[string]any{"subscription_id": json.RawMessage(id)}, nil); err != nil {
+			s.lggr.Debugw("Failed to unsubscribe", "method", sub.method, "subscriptionID", id, "error", err)
+		}
+	}
+}
+
+func (s *Subscriber) run() {
+	defer s.done.Done()
+
+	ctx, cancel := utils.ContextFromChan(s.stop)
+	defer cancel()
+
+	if s.wsURL == "" {
+		s.startPolling()
+		return
+	}
+
+	wait := s.minWait
+	for {
+		ws, resp, err := websocket.DefaultDialer.DialContext(ctx, s.wsURL, s.header)
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil && noWebSocket(resp.StatusCode) {
+			s.lggr.Warnw("Node has no WebSocket endpoint, polling instead", "url", s.wsURL, "status", resp.Status, "pollInterval", s.pollInterval)
+			s.startPolling()
+			return
+		}
+		if err == nil {
+			wait = s.minWait
+			s.lggr.Debugw("Connected", "url", s.wsURL)
+			err = s.serve(ctx, newWSConn(ws))
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.lggr.Warnw("WebSocket connection failed, reconnecting", "url", s.wsURL, "wait", wait, "error", err)
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(wait):
+		}
+		wait = min(s.backoff(wait), s.maxWait)
+	}
+}
+
+// noWebSocket returns true if the handshake response status means that the node does not serve WebSockets at all,
+// other failed handshakes (e.g. rate limits or an unavailable node) are retried.
+func noWebSocket(status int) bool {
+	switch status {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusUpgradeRequired:
+		return true
+	default:
+		return false
+	}
+}
+
+// serve requests the subscriptions on conn and queues its notifications until the connection fails.
+func (s *Subscriber) serve(ctx context.Context, conn *wsConn) error {
+	s.lock.Lock()
+	select {
+	case <-s.stop:
+		s.lock.Unlock()
+		conn.close()
+		return nil
+	default:
+	}
+	s.conn = conn
+	subs := make([]*subscription, 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.lock.Unlock()
+
+	go func() {
+		for _, sub := range subs {
+			if err := s.request(ctx, conn, sub); err != nil {
+				s.lggr.Warnw("Failed to resubscribe", "method", sub.method, "error", err)
+			}
+		}
+	}()
+
+	err := conn.readLoop(func(method string, id string, result json.RawMessage) {
+		s.lock.Lock()
+		var sub *subscription
+		for candidate := range s.subs {
+			if candidate.conn == conn && candidate.id == id {
+				sub = candidate
+				break
+			}
+		}
+		s.lock.Unlock()
+		if sub == nil {
+			return
+		}
+		// the read loop must not wait for a slow consumer, it also reads the responses of every other request
+		select {
+		case sub.queue <- result:
+		default:
+			s.lggr.Warnw("Subscription lags behind, dropped notification", "method", method, "subscriptionID", id)
+		}
+	})
+
+	s.lock.Lock()
+	s.conn = nil
+	for sub := range s.subs {
+		if sub.conn == conn {
+			sub.conn, sub.id = nil, ""
+		}
+	}
+	s.lock.Unlock()
+	return err
+}
+
+// dispatch passes the notifications queued for sub to its notify, so that a slow consumer only holds up its own
+// subscription.
+func (s *Subscriber) dispatch(sub *subscription) {
+	defer s.done.Done()
+
+	ctx, cancel := utils.ContextFromChan(s.stop)
+	defer cancel()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-sub.done:
+			return
+		case result := <-sub.queue:
+			if err := sub.notify(ctx, result); err != nil && ctx.Err() == nil {
+				s.lggr.Debugw("Dropped notification", "method", sub.method, "error", err)
+			}
+		}
+	}
+}
+
+func (s *Subscriber) startPolling() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.polling = true
+	for sub := range s.subs {
+		s.done.Add(1)
+		go s.pollLoop(sub)
+	}
+}
+
+func (s *Subscriber) pollLoop(sub *subscription) {
+	defer s.done.Done()
+
+	ctx, cancel := utils.ContextFromChan(s.stop)
+	defer cancel()
+
+	tick := time.NewTicker(s.pollInterval)
+	defer tick.Stop()
+	for {
+		if err := sub.poll(ctx); err != nil && ctx.Err() == nil {
+			s.lggr.Warnw("Failed to poll", "method", sub.method, "error", err)
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-sub.done:
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// wsConn is a JSON-RPC connection over WebSocket.
+type wsConn struct {
+	ws        *websocket.Conn
+	writeLock sync.Mutex
+
+	lock    sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan<- wsResponse
+	// onResult of a pending call is run by the read loop
+	onResult map[uint64]func(json.RawMessage)
+	closed   chan struct{}
+}
+
+type wsResponse struct {
+	result json.RawMessage
+	err    error
+}
+
+type wsMessage struct {
+	ID     *uint64         `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data,omitempty"`
+	} `json:"error,omitempty"`
+}
+
+func newWSConn(ws *websocket.Conn) *wsConn {
+	return &wsConn{
+		ws:       ws,
+		pending:  map[uint64]chan<- wsResponse{},
+		onResult: map[uint64]func(json.RawMessage){},
+		closed:   make(chan struct{}),
+	}
+}
+
+// call sends a request and waits for its response. onResult, if set, is run by the read loop on success.
+func (c *wsConn) call(ctx context.Context, method string, params any, onResult func(json.RawMessage)) (json.RawMessage, error) {
+	respCh := make(chan wsResponse, 1)
+	c.lock.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = respCh
+	if onResult != nil {
+		c.onResult[id] = onResult
+	}
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, id)
+		delete(c.onResult, id)
+		c.lock.Unlock()
+	}()
+
+	req := map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+	c.writeLock.Lock()
+	err := c.ws.WriteJSON(req)
+	c.writeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-respCh:
+		return resp.result, resp.err
+	case <-c.closed:
+		return nil, errors.New("connection closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readLoop reads the responses and passes the notifications to notify until the connection fails or is closed.
+func (c *wsConn) readLoop(notify func(method string, subscriptionID string, result json.RawMessage)) error {
+	defer c.close()
+	for {
+		var msg wsMessage
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if msg.ID != nil {
+			c.lock.Lock()
+			respCh, onResult := c.pending[*msg.ID], c.onResult[*msg.ID]
+			c.lock.Unlock()
+			if respCh == nil {
+				continue
+			}
+			if msg.Error != nil {
+				respCh <- wsResponse{err: &starknetrpc.RPCError{Code: msg.Error.Code, Message: msg.Error.Message, Data: msg.Error.Data}}
+				continue
+			}
+			if onResult != nil {
+				onResult(msg.Result)
+			}
+			respCh <- wsResponse{result: msg.Result}
+			continue
+		}
+		if msg.Method == "" {
+			continue
+		}
+		var params struct {
+			SubscriptionID json.RawMessage `json:"subscription_id"`
+			Result         json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			continue
+		}
+		notify(msg.Method, string(params.SubscriptionID), params.Result)
+	}
+}
+
+func (c *wsConn) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+		_ = c.ws.Close()
+	}
+}
@@ -0,0 +1,365 @@
+package starknet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	starknetrpc "github.com/NethermindEth/starknet.go/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/utils/tests"
+)
+
+type wsRequest struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// fakeWSNode serves the subscribe and unsubscribe requests of the Starknet WebSocket API, and pushes the
+// notifications of its tests.
+type fakeWSNode struct {
+	t        *testing.T
+	upgrader websocket.Upgrader
+
+	lock     sync.Mutex
+	conn     *websocket.Conn
+	nextID   int
+	subs     map[int]string // subscription id to subscribe method, of the current connection
+	requests []wsRequest
+}
+
+func (n *fakeWSNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := n.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	n.lock.Lock()
+	n.conn, n.subs = conn, map[int]string{}
+	n.lock.Unlock()
+
+	for {
+		var req wsRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		n.lock.Lock()
+		n.requests = append(n.requests, req)
+		var result any = true
+		if strings.HasPrefix(req.Method, "starknet_subscribe") {
+			n.nextID++
+			n.subs[n.nextID] = req.Method
+			result = n.nextID
+		}
+		if req.Method == "starknet_unsubscribe" {
+			var params struct {
+				SubscriptionID int `json:"subscription_id"`
+			}
+			require.NoError(n.t, json.Unmarshal(req.Params, &params))
+			delete(n.subs, params.SubscriptionID)
+		}
+		err := conn.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		n.lock.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// notify sends result to the subscriptions of method on the current connection.
+func (n *fakeWSNode) notify(method string, result string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for id, m := range n.subs {
+		if m != method {
+			continue
+		}
+		msg := fmt.Sprintf(`{"jsonrpc": "2.0", "method": %q, "params": {"subscription_id": %d, "result": %s}}`,
+			strings.Replace(method, "subscribe", "subscription", 1), id, result)
+		require.NoError(n.t, n.conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+	}
+}
+
+func (n *fakeWSNode) drop() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	require.NoError(n.t, n.conn.Close())
+}
+
+// subscribed returns the number of subscriptions of method on the current connection.
+func (n *fakeWSNode) subscribed(method string) (count int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, m := range n.subs {
+		if m == method {
+			count++
+		}
+	}
+	return
+}
+
+// requested returns the params of the requests of method.
+func (n *fakeWSNode) requested(method string) (params []string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, req := range n.requests {
+		if req.Method == method {
+			params = append(params, string(req.Params))
+		}
+	}
+	return
+}
+
+func TestSubscriber_WebSocket(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	node := &fakeWSNode{t: t}
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+
+	s := NewSubscriber(logger.Test(t), "ws"+strings.TrimPrefix(server.URL, "http"), "", nil, time.Minute)
+	s.minWait = 10 * time.Millisecond
+	require.NoError(t, s.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	address := new(felt.Felt).SetUint64(0xa)
+	heads, err := s.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+	events, err := s.SubscribeEvents(ctx, address, [][]*felt.Felt{{new(felt.Felt).SetUint64(1)}})
+	require.NoError(t, err)
+	statuses, err := s.SubscribeTransactionStatus(ctx, new(felt.Felt).SetUint64(0xf))
+	require.NoError(t, err)
+
+	// subscriptions made before the connection is up are requested once connected
+	require.Eventually(t, func() bool {
+		return len(node.requested("starknet_subscribeTransactionStatus")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.JSONEq(t, `{"from_address": "0xa", "keys": [["0x1"]]}`, node.requested("starknet_subscribeEvents")[0])
+
+	node.notify("starknet_subscribeNewHeads", `{"block_number": 5, "block_hash": "0x5", "parent_hash": "0x4", "timestamp": 100}`)
+	head := receive(t, heads.C)
+	assert.Equal(t, uint64(5), head.BlockNumber)
+	assert.Equal(t, "0x5", head.BlockHash.String())
+
+	node.notify("starknet_subscribeEvents", `{"from_address": "0xa", "keys": ["0x1"], "data": ["0x2"], "block_number": 4, "block_hash": "0x4", "transaction_hash": "0x3"}`)
+	event := receive(t, events.C)
+	assert.Equal(t, address, event.FromAddress)
+	assert.Equal(t, uint64(4), event.BlockNumber)
+	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(2)}, event.Data)
+
+	node.notify("starknet_subscribeTransactionStatus", `{"transaction_hash": "0xf", "status": {"finality_status": "ACCEPTED_ON_L2", "execution_status": "REVERTED", "failure_reason": "stale report"}}`)
+	status := receive(t, statuses.C)
+	assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L2, status.FinalityStatus)
+	assert.Equal(t, starknetrpc.TxnExecutionStatusREVERTED, status.ExecutionStatus)
+	assert.Equal(t, "stale report", status.FailureReason)
+
+	// the subscriptions are resumed after a reconnect
+	node.drop()
+	require.Eventually(t, func() bool {
+		return len(node.requested("starknet_subscribeTransactionStatus")) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return len(node.requested("starknet_subscribeNewHeads")) == 2 && len(node.requested("starknet_subscribeEvents")) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.JSONEq(t, `{"block_id": {"block_number": 6}}`, node.requested("starknet_subscribeNewHeads")[1])
+	assert.JSONEq(t, `{"from_address": "0xa", "keys": [["0x1"]], "block_id": {"block_number": 4}}`, node.requested("starknet_subscribeEvents")[1])
+
+	node.notify("starknet_subscribeNewHeads", `{"block_number": 6, "block_hash": "0x6", "parent_hash": "0x5", "timestamp": 101}`)
+	head = receive(t, heads.C)
+	assert.Equal(t, uint64(6), head.BlockNumber)
+
+	heads.Unsubscribe()
+	assert.Len(t, node.requested("starknet_unsubscribe"), 1)
+	assert.Zero(t, node.subscribed("starknet_subscribeNewHeads"))
+	assert.Equal(t, 1, node.subscribed("starknet_subscribeEvents"))
+}
+
+func TestSubscriber_SlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	node := &fakeWSNode{t: t}
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+
+	s := NewSubscriber(logger.Test(t), "ws"+strings.TrimPrefix(server.URL, "http"), "", nil, time.Minute)
+	require.NoError(t, s.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	heads, err := s.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+	statuses, err := s.SubscribeTransactionStatus(ctx, new(felt.Felt).SetUint64(0xf))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return node.subscribed("starknet_subscribeNewHeads") == 1 && node.subscribed("starknet_subscribeTransactionStatus") == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the heads are never read, they fill the channel and the queue of their subscription
+	for n := 0; n < 3*subscriptionBuffer; n++ {
+		node.notify("starknet_subscribeNewHeads", fmt.Sprintf(`{"block_number": %d, "block_hash": "0x%x", "parent_hash": "0x0", "timestamp": 100}`, n, n+1))
+	}
+	node.notify("starknet_subscribeTransactionStatus", `{"transaction_hash": "0xf", "status": {"finality_status": "RECEIVED"}}`)
+	assert.Equal(t, starknetrpc.TxnStatus_Received, receive(t, statuses.C).FinalityStatus)
+
+	// responses are still read
+	heads.Unsubscribe()
+	assert.Len(t, node.requested("starknet_unsubscribe"), 1)
+	assert.Zero(t, node.subscribed("starknet_subscribeNewHeads"))
+	assert.Equal(t, uint64(0), receive(t, heads.C).BlockNumber)
+}
+
+func TestSubscriber_Handshake(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	var status, dials atomic.Int64
+	status.Store(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dials.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewSubscriber(logger.Test(t), "ws"+strings.TrimPrefix(server.URL, "http"), "", nil, time.Minute)
+	s.minWait, s.maxWait = 10*time.Millisecond, 10*time.Millisecond
+	require.NoError(t, s.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	polling := func() bool {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.polling
+	}
+	// an unavailable or rate limiting node is dialed again
+	require.Eventually(t, func() bool { return dials.Load() >= 3 }, 5*time.Second, 10*time.Millisecond)
+	status.Store(http.StatusTooManyRequests)
+	n := dials.Load()
+	require.Eventually(t, func() bool { return dials.Load() >= n+2 }, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, polling())
+
+	// a node without a WebSocket endpoint is polled
+	status.Store(http.StatusNotFound)
+	require.Eventually(t, polling, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSubscriber_Polling(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	var latest atomic.Uint64
+	latest.Store(10)
+	var statusRequests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req wsRequest
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+			// no WebSocket endpoint
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var params []json.RawMessage
+		if len(req.Params) > 0 {
+			require.NoError(t, json.Unmarshal(req.Params, &params))
+		}
+
+		var result string
+		switch req.Method {
+		case "starknet_blockNumber":
+			result = fmt.Sprint(latest.Load())
+		case "starknet_getBlockWithTxHashes":
+			var blockID struct {
+				BlockNumber uint64 `json:"block_number"`
+			}
+			require.NoError(t, json.Unmarshal(params[0], &blockID))
+			n := blockID.BlockNumber
+			result = fmt.Sprintf(`{"status": "ACCEPTED_ON_L2", "block_hash": "0x%x", "parent_hash": "0x%x", "block_number": %d, "new_root": "0x0",
+				"timestamp": %d, "sequencer_address": "0x0", "l1_gas_price": {"price_in_fri": "0x1", "price_in_wei": "0x1"},
+				"l1_data_gas_price": {"price_in_fri": "0x1", "price_in_wei": "0x1"}, "l1_da_mode": "BLOB", "starknet_version": "0.13.1", "transactions": []}`,
+				n, n-1, n, 100+n)
+		case "starknet_getEvents":
+			var filter struct {
+				FromBlock struct {
+					BlockNumber uint64 `json:"block_number"`
+				} `json:"from_block"`
+				ToBlock struct {
+					BlockNumber uint64 `json:"block_number"`
+				} `json:"to_block"`
+			}
+			require.NoError(t, json.Unmarshal(params[0], &filter))
+			var events []string
+			for n := filter.FromBlock.BlockNumber; n <= filter.ToBlock.BlockNumber; n++ {
+				events = append(events, fmt.Sprintf(`{"from_address": "0xa", "keys": [], "data": [], "block_number": %d, "block_hash": "0x%x", "transaction_hash": "0x1"}`, n, n))
+			}
+			result = fmt.Sprintf(`{"events": [%s]}`, strings.Join(events, ","))
+		case "starknet_getTransactionStatus":
+			switch statusRequests.Add(1) {
+			case 1:
+				_, err := fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %d, "error": {"code": %d, "message": %q}}`, req.ID, starknetrpc.ErrHashNotFound.Code, starknetrpc.ErrHashNotFound.Message)
+				require.NoError(t, err)
+				return
+			case 2, 3:
+				result = `{"finality_status": "RECEIVED"}`
+			default:
+				result = `{"finality_status": "ACCEPTED_ON_L2", "execution_status": "SUCCEEDED"}`
+			}
+		default:
+			require.Fail(t, "unsupported RPC method", req.Method)
+		}
+		_, err := fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %d, "result": %s}`, req.ID, result)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(chainID, server.URL, "", logger.Test(t), &timeout)
+	require.NoError(t, err)
+	s := NewSubscriber(logger.Test(t), "ws"+strings.TrimPrefix(server.URL, "http"), "", client, 20*time.Millisecond)
+	require.NoError(t, s.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	heads, err := s.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+	events, err := s.SubscribeEvents(ctx, new(felt.Felt).SetUint64(0xa), nil)
+	require.NoError(t, err)
+	statuses, err := s.SubscribeTransactionStatus(ctx, new(felt.Felt).SetUint64(0xf))
+	require.NoError(t, err)
+
+	// the subscriptions start at the latest block, and deliver every block after it
+	assert.Equal(t, uint64(10), receive(t, heads.C).BlockNumber)
+	assert.Equal(t, uint64(10), receive(t, events.C).BlockNumber)
+	latest.Store(13)
+	for n := uint64(11); n <= 13; n++ {
+		head := receive(t, heads.C)
+		assert.Equal(t, n, head.BlockNumber)
+		assert.Equal(t, uint64(100+n), head.Timestamp)
+		assert.Equal(t, n, receive(t, events.C).BlockNumber)
+	}
+
+	// only the changes of status are delivered
+	assert.Equal(t, starknetrpc.TxnStatus_Received, receive(t, statuses.C).FinalityStatus)
+	status := receive(t, statuses.C)
+	assert.Equal(t, starknetrpc.TxnStatus_Accepted_On_L2, status.FinalityStatus)
+	assert.Equal(t, starknetrpc.TxnExecutionStatusSUCCEEDED, status.ExecutionStatus)
+	assert.GreaterOrEqual(t, statusRequests.Load(), int64(4))
+}
+
+// receive returns the next value of c, and fails the test if none arrives in time.
+func receive[T any](t *testing.T, c <-chan T) T {
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(tests.WaitTimeout(t)):
+		require.FailNow(t, "timed out waiting for a notification")
+		panic("unreachable")
+	}
+}
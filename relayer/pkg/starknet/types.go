@@ -9,3 +9,9 @@ type CallOps struct {
 	Selector        *felt.Felt
 	Calldata        []*felt.Felt
 }
+
+// Block tags: latest is the last accepted block, pending includes the txs that are not in a block yet.
+const (
+	BlockTagLatest  = "latest"
+	BlockTagPending = "pending"
+)
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/NethermindEth/juno/core/felt"
 )
@@ -37,6 +38,31 @@ func Min[T cmp.Ordered](a, b T) T {
 	return b
 }
 
+// ErrChainIDMismatch is returned when a node serves another chain than the configured one.
+var ErrChainIDMismatch = errors.New("chain ID mismatch")
+
+// MatchChainID checks that the chain ID reported by a node is the expected one, each either a short string like
+// SN_SEPOLIA or hex encoded like the 0x534e5f5345504f4c4941 returned by starknet_chainId.
+func MatchChainID(expected, reported string) error {
+	if decodeChainID(expected) != decodeChainID(reported) {
+		return fmt.Errorf("%w: node serves %s, expected %s", ErrChainIDMismatch, decodeChainID(reported), decodeChainID(expected))
+	}
+	return nil
+}
+
+// decodeChainID returns the short string of a hex encoded chain ID, other chain IDs are returned as is.
+func decodeChainID(chainID string) string {
+	chainID = strings.TrimSpace(chainID)
+	if !strings.HasPrefix(chainID, "0x") {
+		return chainID
+	}
+	v, ok := new(big.Int).SetString(chainID[2:], 16)
+	if !ok {
+		return chainID
+	}
+	return string(v.Bytes())
+}
+
 // EncodeFelts takes a byte slice and splits as bunch of felts. First felt indicates the total byte size.
 func EncodeFelts(data []byte) (felts []*big.Int) {
 	// prefix with len
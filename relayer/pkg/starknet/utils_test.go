@@ -129,3 +129,24 @@ func TestDecodeFelts2(t *testing.T) {
 	_, err := DecodeFelts(array)
 	require.Error(t, err)
 }
+
+func TestMatchChainID(t *testing.T) {
+	for _, tc := range []struct {
+		expected, reported string
+		match              bool
+	}{
+		{"SN_SEPOLIA", "0x534e5f5345504f4c4941", true},
+		{"0x534e5f5345504f4c4941", "SN_SEPOLIA", true},
+		{"SN_SEPOLIA", "SN_SEPOLIA", true},
+		{"SN_SEPOLIA", "0x534e5f4d41494e", false},
+		{"SN_MAIN", "0x534e5f4d41494e", true},
+	} {
+		err := MatchChainID(tc.expected, tc.reported)
+		if tc.match {
+			assert.NoError(t, err, tc)
+		} else {
+			assert.ErrorIs(t, err, ErrChainIDMismatch, tc)
+		}
+	}
+	assert.EqualError(t, MatchChainID("SN_SEPOLIA", "0x534e5f4d41494e"), "chain ID mismatch: node serves SN_MAIN, expected SN_SEPOLIA")
+}